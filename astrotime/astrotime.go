@@ -27,12 +27,17 @@ const (
 
 // MJD2JulianDay return the Julian day for the given MJD
 func MJD2JulianDay(mjd float64) float64 {
-	return mjd + JulianCentury //JulianDayZero
+	return mjd + JulianDayZero
 }
 
-// ToMJD converts a time.Time to MJD.
+// ToMJD converts a time.Time to MJD, keeping the sub-second part.
 func ToMJD(t time.Time) float64 {
-	return float64(t.Unix())/SecondPerDay + UnixMJDoffsetDays
+	return JDFromTime(t).MJD()
+}
+
+// FromMJD converts an MJD to a UTC time.Time.
+func FromMJD(mjd float64) time.Time {
+	return JDFromMJD(mjd).Time()
 }
 
 // Turns the current MJD
@@ -77,6 +82,8 @@ func ParseLocalTimeToUTC(t string) (time.Time, error) {
 	return ti.UTC(), nil
 }
 
+// Schedule runs f every period p offset by o until ctx is cancelled. For
+// sidereal or event aligned times, or several jobs, see Scheduler.
 // see: https://stackoverflow.com/questions/19549199/golang-implementing-a-cron-executing-tasks-at-a-specific-time
 func Schedule(ctx context.Context, p time.Duration, o time.Duration, f func(time.Time)) {
 	// Position the first execution
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
 )
 
 func TestIsoNow(t *testing.T) {
@@ -44,6 +46,25 @@ func TestParseLocalTimeToUTC(t *testing.T) {
 	fmt.Println(ti)
 }
 
+func TestFromMJD(t *testing.T) {
+	ti := FromMJD(51544.5)
+	expected := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	if !ti.Equal(expected) {
+		fmt.Println("FromMJD Expected ", expected, " Got ", ti)
+		t.Fail()
+	}
+	if ToMJD(ti) != 51544.5 {
+		fmt.Println("ToMJD Expected 51544.5 Got ", ToMJD(ti))
+		t.Fail()
+	}
+}
+
+func TestMJDSubSecond(t *testing.T) {
+	ti := time.Date(2000, 1, 1, 12, 0, 0, 500000000, time.UTC)
+	th.CheckFT(t, ToMJD(ti), 51544.5+0.5/SecondPerDay, 1e-11, "ToMJD Error")
+	th.CheckF(t, MJD2JulianDay(51544.5), 2451545.0, "MJD2JulianDay Error")
+}
+
 func TestSchedule(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	f := func(t time.Time) { fmt.Println(t) }
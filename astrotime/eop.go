@@ -0,0 +1,487 @@
+// Earth orientation parameters from IERS finals2000A and Bulletin A files
+package astrotime
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EOP holds the Earth orientation parameters at an epoch.
+type EOP struct {
+	MJD    float64 `yaml:"mjd"`    // UTC
+	UT1UTC float64 `yaml:"ut1utc"` // seconds of time
+	Xp     float64 `yaml:"xp"`     // polar motion, arcsec
+	Yp     float64 `yaml:"yp"`     // polar motion, arcsec
+	DX     float64 `yaml:"dx"`     // celestial pole offset wrt IAU 2000A, mas
+	DY     float64 `yaml:"dy"`     // celestial pole offset wrt IAU 2000A, mas
+	// Predicted is true when any of the values used came from the IERS
+	// predictions rather than observations.
+	Predicted bool `yaml:"predicted"`
+	// Extrapolated is true when the epoch lies outside the table and the
+	// values of the nearest entry are being held.
+	Extrapolated bool `yaml:"extrapolated"`
+	// NoTable is true when no EOP table is loaded and every value is
+	// zero, so UT1 is taken as UTC.
+	NoTable bool `yaml:"noTable"`
+}
+
+// EOPTable is a daily table of Earth orientation parameters sorted by MJD.
+type EOPTable struct {
+	entries []EOP
+}
+
+const (
+	// number of points used in the Lagrange interpolation
+	eopInterpPoints = 4
+)
+
+var (
+	eopMu    sync.RWMutex
+	eopTable *EOPTable
+)
+
+// NewEOPTable returns a table built from the given entries.
+func NewEOPTable(entries []EOP) *EOPTable {
+	t := &EOPTable{entries: append([]EOP(nil), entries...)}
+	t.sort()
+	return t
+}
+
+// sort orders the entries by MJD and drops duplicate days, keeping the
+// last one read.
+func (t *EOPTable) sort() {
+	sort.SliceStable(t.entries, func(i, j int) bool {
+		return t.entries[i].MJD < t.entries[j].MJD
+	})
+	var out []EOP
+	for _, e := range t.entries {
+		if len(out) > 0 && out[len(out)-1].MJD == e.MJD {
+			out[len(out)-1] = e
+			continue
+		}
+		out = append(out, e)
+	}
+	t.entries = out
+}
+
+// Len returns the number of entries in the table.
+func (t *EOPTable) Len() int {
+	return len(t.entries)
+}
+
+// Span returns the first and last MJD in the table.
+func (t *EOPTable) Span() (float64, float64) {
+	if len(t.entries) == 0 {
+		return 0.0, 0.0
+	}
+	return t.entries[0].MJD, t.entries[len(t.entries)-1].MJD
+}
+
+// LastObserved returns the MJD of the last entry that is not a prediction.
+// Returns 0 if every entry is predicted.
+func (t *EOPTable) LastObserved() float64 {
+	for idx := len(t.entries) - 1; idx >= 0; idx-- {
+		if !t.entries[idx].Predicted {
+			return t.entries[idx].MJD
+		}
+	}
+	return 0.0
+}
+
+// Merge adds the entries of o to t. Entries of o replace entries of t
+// for the same day.
+func (t *EOPTable) Merge(o *EOPTable) {
+	t.entries = append(t.entries, o.entries...)
+	t.sort()
+}
+
+// At returns the interpolated Earth orientation parameters at mjd (UTC).
+// Outside the table the nearest entry is returned with Extrapolated set.
+func (t *EOPTable) At(mjd float64) (EOP, error) {
+	var eop EOP
+	n := len(t.entries)
+	if n == 0 {
+		return eop, errors.New("EOP table is empty")
+	}
+	if math.IsNaN(mjd) || math.IsInf(mjd, 0) {
+		return eop, errors.New("Invalid MJD for EOP lookup")
+	}
+	if mjd < t.entries[0].MJD || mjd > t.entries[n-1].MJD {
+		if mjd < t.entries[0].MJD {
+			eop = t.entries[0]
+		} else {
+			eop = t.entries[n-1]
+		}
+		eop.MJD = mjd
+		eop.Extrapolated = true
+		return eop, nil
+	}
+
+	// index of the entry at or before mjd
+	idx := sort.Search(n, func(i int) bool { return t.entries[i].MJD > mjd }) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if t.entries[idx].MJD == mjd {
+		return t.entries[idx], nil
+	}
+
+	// window of points centered on the interval containing mjd
+	lo := idx - eopInterpPoints/2 + 1
+	if lo < 0 {
+		lo = 0
+	}
+	hi := lo + eopInterpPoints
+	if hi > n {
+		hi = n
+		lo = hi - eopInterpPoints
+		if lo < 0 {
+			lo = 0
+		}
+	}
+	pts := t.entries[lo:hi]
+
+	x := make([]float64, len(pts))
+	ut1 := make([]float64, len(pts))
+	xp := make([]float64, len(pts))
+	yp := make([]float64, len(pts))
+	dx := make([]float64, len(pts))
+	dy := make([]float64, len(pts))
+	ref := t.entries[idx].UT1UTC
+	for jdx, p := range pts {
+		x[jdx] = p.MJD
+		// remove leap second jumps relative to the entry before mjd
+		ut1[jdx] = p.UT1UTC - math.Round(p.UT1UTC-ref)
+		xp[jdx] = p.Xp
+		yp[jdx] = p.Yp
+		dx[jdx] = p.DX
+		dy[jdx] = p.DY
+		eop.Predicted = eop.Predicted || p.Predicted
+	}
+	eop.MJD = mjd
+	eop.UT1UTC = lagrange(x, ut1, mjd)
+	eop.Xp = lagrange(x, xp, mjd)
+	eop.Yp = lagrange(x, yp, mjd)
+	eop.DX = lagrange(x, dx, mjd)
+	eop.DY = lagrange(x, dy, mjd)
+	return eop, nil
+}
+
+// AtTime returns the interpolated Earth orientation parameters at ti.
+func (t *EOPTable) AtTime(ti time.Time) (EOP, error) {
+	return t.At(ToMJD(ti))
+}
+
+// lagrange evaluates the Lagrange polynomial through (x,y) at xi.
+func lagrange(x, y []float64, xi float64) float64 {
+	var sum float64
+	for i := range x {
+		term := y[i]
+		for j := range x {
+			if i != j {
+				term *= (xi - x[j]) / (x[i] - x[j])
+			}
+		}
+		sum += term
+	}
+	return sum
+}
+
+// column returns the trimmed text between the 1-based inclusive columns
+// first and last of line, or "" when the line is too short.
+func column(line string, first, last int) string {
+	if len(line) < first {
+		return ""
+	}
+	if len(line) < last {
+		last = len(line)
+	}
+	return strings.TrimSpace(line[first-1 : last])
+}
+
+// columnFloat parses the column as a float. ok is false for blank
+// columns.
+func columnFloat(line string, first, last int) (float64, bool, error) {
+	s := column(line, first, last)
+	if s == "" {
+		return 0.0, false, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0.0, false, err
+	}
+	return v, true, nil
+}
+
+// ReadFinals2000A reads an IERS finals2000A.all (or finals2000A.data,
+// finals2000A.daily) file.
+func ReadFinals2000A(fn string) (*EOPTable, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseFinals2000A(f)
+}
+
+// ParseFinals2000A parses the fixed column IERS finals2000A format. Bulletin
+// B values are used when present, otherwise Bulletin A values. Lines
+// without a UT1-UTC value (the far future end of the file) are skipped.
+func ParseFinals2000A(r io.Reader) (*EOPTable, error) {
+	t := &EOPTable{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var eop EOP
+		mjd, ok, err := columnFloat(line, 8, 15)
+		if err != nil || !ok {
+			emsg := fmt.Sprintf("finals2000A line %d: invalid MJD", lineNum)
+			return nil, errors.New(emsg)
+		}
+		eop.MJD = mjd
+		ut1, ok, err := columnFloat(line, 59, 68)
+		if err != nil {
+			emsg := fmt.Sprintf("finals2000A line %d: invalid UT1-UTC: %v", lineNum, err)
+			return nil, errors.New(emsg)
+		}
+		if !ok {
+			continue
+		}
+		eop.UT1UTC = ut1
+		eop.Xp, _, err = columnFloat(line, 19, 27)
+		if err != nil {
+			emsg := fmt.Sprintf("finals2000A line %d: invalid PM-x: %v", lineNum, err)
+			return nil, errors.New(emsg)
+		}
+		eop.Yp, _, err = columnFloat(line, 38, 46)
+		if err != nil {
+			emsg := fmt.Sprintf("finals2000A line %d: invalid PM-y: %v", lineNum, err)
+			return nil, errors.New(emsg)
+		}
+		eop.DX, _, err = columnFloat(line, 98, 106)
+		if err != nil {
+			emsg := fmt.Sprintf("finals2000A line %d: invalid dX: %v", lineNum, err)
+			return nil, errors.New(emsg)
+		}
+		eop.DY, _, err = columnFloat(line, 117, 125)
+		if err != nil {
+			emsg := fmt.Sprintf("finals2000A line %d: invalid dY: %v", lineNum, err)
+			return nil, errors.New(emsg)
+		}
+		eop.Predicted = column(line, 17, 17) == "P" || column(line, 58, 58) == "P"
+
+		// Bulletin B values, when present, supersede Bulletin A
+		if v, ok, err := columnFloat(line, 135, 144); err == nil && ok {
+			eop.Xp = v
+		}
+		if v, ok, err := columnFloat(line, 145, 154); err == nil && ok {
+			eop.Yp = v
+		}
+		if v, ok, err := columnFloat(line, 155, 165); err == nil && ok {
+			eop.UT1UTC = v
+		}
+		if v, ok, err := columnFloat(line, 166, 175); err == nil && ok {
+			eop.DX = v
+		}
+		if v, ok, err := columnFloat(line, 176, 185); err == nil && ok {
+			eop.DY = v
+		}
+		t.entries = append(t.entries, eop)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(t.entries) == 0 {
+		return nil, errors.New("No EOP entries found in finals2000A data")
+	}
+	t.sort()
+	return t, nil
+}
+
+// ReadBulletinA reads an IERS Bulletin A (ser7) text file.
+func ReadBulletinA(fn string) (*EOPTable, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseBulletinA(f)
+}
+
+// parseFloats converts every field to a float, ok is false if any fails.
+func parseFloats(fields []string) ([]float64, bool) {
+	v := make([]float64, len(fields))
+	for idx, f := range fields {
+		fv, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, false
+		}
+		v[idx] = fv
+	}
+	return v, true
+}
+
+// ParseBulletinA parses the weekly IERS Bulletin A. The combined EOP
+// section supplies observed values, the predictions table supplies
+// predicted values and the IAU2000A celestial pole offset series
+// supplies dX, dY.
+func ParseBulletinA(r io.Reader) (*EOPTable, error) {
+	const (
+		none = iota
+		combined
+		predictions
+		poleOffsets
+	)
+	section := none
+	entries := make(map[float64]*EOP)
+	dxdy := make(map[float64][2]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		upper := strings.ToUpper(line)
+		switch {
+		case strings.Contains(upper, "COMBINED EARTH ORIENTATION PARAMETERS"):
+			section = combined
+			continue
+		case strings.Contains(upper, "PREDICTIONS:"):
+			section = predictions
+			continue
+		case strings.Contains(upper, "IAU2000A CELESTIAL POLE OFFSET"):
+			section = poleOffsets
+			continue
+		case strings.Contains(upper, "CELESTIAL POLE OFFSET SERIES"):
+			section = none
+			continue
+		}
+		fields := strings.Fields(line)
+		v, ok := parseFloats(fields)
+		if !ok {
+			continue
+		}
+		switch section {
+		case combined:
+			// YY MM DD MJD x err y err UT1-UTC err
+			if len(v) != 10 {
+				continue
+			}
+			entries[v[3]] = &EOP{MJD: v[3], Xp: v[4], Yp: v[6], UT1UTC: v[8]}
+		case predictions:
+			// YYYY MM DD MJD x y UT1-UTC
+			if len(v) != 7 {
+				continue
+			}
+			if _, ok := entries[v[3]]; ok {
+				continue
+			}
+			entries[v[3]] = &EOP{MJD: v[3], Xp: v[4], Yp: v[5], UT1UTC: v[6], Predicted: true}
+		case poleOffsets:
+			// MJD dX err dY err
+			if len(v) != 5 {
+				continue
+			}
+			dxdy[v[0]] = [2]float64{v[1], v[3]}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("No EOP entries found in Bulletin A data")
+	}
+	t := &EOPTable{}
+	for mjd, e := range entries {
+		if d, ok := dxdy[mjd]; ok {
+			e.DX = d[0]
+			e.DY = d[1]
+		}
+		t.entries = append(t.entries, *e)
+	}
+	t.sort()
+	return t, nil
+}
+
+// LoadEOP reads a finals2000A or Bulletin A file and makes it the table
+// used by GetEOP. Bulletin A files are recognized by their combined EOP
+// section.
+func LoadEOP(fn string) error {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	var t *EOPTable
+	if strings.Contains(strings.ToUpper(string(b)), "COMBINED EARTH ORIENTATION PARAMETERS") {
+		t, err = ParseBulletinA(strings.NewReader(string(b)))
+	} else {
+		t, err = ParseFinals2000A(strings.NewReader(string(b)))
+	}
+	if err != nil {
+		return err
+	}
+	SetEOPTable(t)
+	return nil
+}
+
+// SetEOPTable sets the table used by GetEOP. nil clears it.
+func SetEOPTable(t *EOPTable) {
+	eopMu.Lock()
+	defer eopMu.Unlock()
+	eopTable = t
+}
+
+// GetEOPTable returns the table used by GetEOP, nil if none is loaded.
+func GetEOPTable() *EOPTable {
+	eopMu.RLock()
+	defer eopMu.RUnlock()
+	return eopTable
+}
+
+// GetEOP returns the Earth orientation parameters at ti from the loaded
+// table. If no table is loaded, or it is empty, all values are zero and
+// Extrapolated and NoTable are set.
+func GetEOP(ti time.Time) EOP {
+	mjd := ToMJD(ti)
+	t := GetEOPTable()
+	if t == nil {
+		return EOP{MJD: mjd, Extrapolated: true, NoTable: true}
+	}
+	eop, err := t.At(mjd)
+	if err != nil {
+		return EOP{MJD: mjd, Extrapolated: true, NoTable: true}
+	}
+	return eop
+}
+
+// PoleOffsets returns the corrections to the nutation in longitude and
+// obliquity, in radians, at the TT date tt that move the modelled
+// celestial pole by DX and DY to the observed one, as NOVAS cel_pole does.
+func (e EOP) PoleOffsets(tt JD) (float64, float64) {
+	if e.DX == 0.0 && e.DY == 0.0 {
+		return 0.0, 0.0
+	}
+	// a trivial model of the path of the pole in the GCRS gives dz
+	x := 2004.190 * julianCenturies(tt) * ArcsecondToRadian
+	dz := -(x + 0.5*x*x*x) * e.DX
+	dp := [3]float64{e.DX, e.DY, dz}
+	m := BiasPrecessionMatrix(tt)
+	var p [3]float64
+	for i := range p {
+		for j := range dp {
+			p[i] += m[i][j] * dp[j] * 1e-3 * ArcsecondToRadian
+		}
+	}
+	return p[0] / math.Sin(MeanObliquity(tt)), p[1]
+}
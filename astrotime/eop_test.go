@@ -0,0 +1,150 @@
+package astrotime
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestReadFinals2000A(t *testing.T) {
+	tbl, err := ReadFinals2000A("testdata/finals2000A.sample")
+	if err != nil {
+		fmt.Println("ReadFinals2000A error: ", err)
+		t.Fail()
+		return
+	}
+	// last line has no UT1-UTC and is skipped
+	th.CheckI(t, tbl.Len(), 14, "Length Error")
+	first, last := tbl.Span()
+	th.CheckF(t, first, 60762.0, "Span Error")
+	th.CheckF(t, last, 60775.0, "Span Error")
+	th.CheckF(t, tbl.LastObserved(), 60769.0, "LastObserved Error")
+
+	eop, err := tbl.At(60763.0)
+	if err != nil {
+		fmt.Println("At error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, eop.UT1UTC, 0.0303350, 1e-12, "UT1UTC Error")
+	th.CheckFT(t, eop.Xp, 0.120450, 1e-12, "Xp Error")
+	th.CheckFT(t, eop.Yp, 0.402710, 1e-12, "Yp Error")
+	th.CheckFT(t, eop.DX, 0.340, 1e-12, "DX Error")
+	th.CheckFT(t, eop.DY, -0.095, 1e-12, "DY Error")
+
+	// values in the fixture are linear in MJD so interpolation is exact
+	eop, _ = tbl.At(60763.25)
+	th.CheckFT(t, eop.UT1UTC, 0.0303350-0.25*0.00045, 1e-12, "UT1UTC Interp Error")
+	th.CheckFT(t, eop.Xp, 0.120450+0.25*0.0012, 1e-12, "Xp Interp Error")
+	if eop.Predicted || eop.Extrapolated {
+		fmt.Println("Unexpected flags: ", eop)
+		t.Fail()
+	}
+	eop, _ = tbl.At(60772.5)
+	if !eop.Predicted {
+		fmt.Println("Expected Predicted flag: ", eop)
+		t.Fail()
+	}
+	eop, _ = tbl.At(60800.0)
+	if !eop.Extrapolated {
+		fmt.Println("Expected Extrapolated flag: ", eop)
+		t.Fail()
+	}
+	th.CheckFT(t, eop.UT1UTC, 0.030785-13*0.00045, 1e-12, "Extrapolated Error")
+}
+
+func TestReadBulletinA(t *testing.T) {
+	tbl, err := ReadBulletinA("testdata/bulletinA.sample")
+	if err != nil {
+		fmt.Println("ReadBulletinA error: ", err)
+		t.Fail()
+		return
+	}
+	th.CheckI(t, tbl.Len(), 11, "Length Error")
+	eop, _ := tbl.At(60762.0)
+	th.CheckFT(t, eop.UT1UTC, 0.030785, 1e-12, "UT1UTC Error")
+	th.CheckFT(t, eop.DX, 0.33, 1e-12, "DX Error")
+	th.CheckFT(t, eop.DY, -0.10, 1e-12, "DY Error")
+	eop, _ = tbl.At(60770.0)
+	th.CheckFT(t, eop.Xp, 0.1289, 1e-12, "Xp Error")
+	if !eop.Predicted {
+		fmt.Println("Expected Predicted flag: ", eop)
+		t.Fail()
+	}
+	th.CheckF(t, tbl.LastObserved(), 60768.0, "LastObserved Error")
+}
+
+func TestEOPLeapSecond(t *testing.T) {
+	// UT1-UTC jumps by +1s at the 2017-01-01 leap second
+	tbl := NewEOPTable([]EOP{
+		{MJD: 57752, UT1UTC: -0.4076},
+		{MJD: 57753, UT1UTC: -0.4083},
+		{MJD: 57754, UT1UTC: 0.5910},
+		{MJD: 57755, UT1UTC: 0.5903},
+	})
+	eop, _ := tbl.At(57753.5)
+	th.CheckFT(t, eop.UT1UTC, -0.40865, 1e-4, "Before leap Error")
+	eop, _ = tbl.At(57754.5)
+	th.CheckFT(t, eop.UT1UTC, 0.59065, 1e-4, "After leap Error")
+}
+
+func TestGetEOP(t *testing.T) {
+	SetEOPTable(nil)
+	ti := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	eop := GetEOP(ti)
+	if !eop.Extrapolated || !eop.NoTable || eop.UT1UTC != 0.0 {
+		fmt.Println("Expected zero extrapolated EOP: ", eop)
+		t.Fail()
+	}
+	err := LoadEOP("testdata/bulletinA.sample")
+	if err != nil {
+		fmt.Println("LoadEOP error: ", err)
+		t.Fail()
+	}
+	eop = GetEOP(ti)
+	th.CheckFT(t, eop.UT1UTC, 0.028985, 1e-12, "UT1UTC Error")
+	if eop.NoTable {
+		fmt.Println("Unexpected NoTable with a table loaded")
+		t.Fail()
+	}
+	err = LoadEOP("testdata/finals2000A.sample")
+	if err != nil {
+		fmt.Println("LoadEOP error: ", err)
+		t.Fail()
+	}
+	eop = GetEOP(ti)
+	th.CheckFT(t, eop.DX, 0.37, 1e-12, "DX Error")
+	SetEOPTable(nil)
+}
+
+func TestPoleOffsets(t *testing.T) {
+	mas := ArcsecondToRadian / 1000.0
+	j := JDFromMJD(51544.5)
+	ddpsi, ddeps := EOP{}.PoleOffsets(j)
+	th.CheckF(t, ddpsi, 0.0, "Zero dpsi Error")
+	th.CheckF(t, ddeps, 0.0, "Zero deps Error")
+	// at J2000 dX is along the equinox and dY along the pole of the
+	// ecliptic, to the 17 mas of the frame bias
+	eop := EOP{DX: 0.3, DY: -0.2}
+	ddpsi, ddeps = eop.PoleOffsets(j)
+	eps := MeanObliquity(j)
+	th.CheckFT(t, ddpsi/mas, 0.3/math.Sin(eps), 1e-6, "J2000 dpsi Error")
+	th.CheckFT(t, ddeps/mas, -0.2, 1e-6, "J2000 deps Error")
+
+	// the offsets enter the apparent sidereal time as the equation of the
+	// equinoxes
+	SetEOPTable(NewEOPTable([]EOP{
+		{MJD: 60765, DX: 0.3, DY: -0.2},
+		{MJD: 60766, DX: 0.3, DY: -0.2},
+		{MJD: 60767, DX: 0.3, DY: -0.2},
+	}))
+	defer SetEOPTable(nil)
+	utc := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	eop = GetEOP(utc)
+	ut1, tt := ut1tt(utc, eop)
+	ddpsi, _ = eop.PoleOffsets(tt)
+	th.CheckFT(t, (GAST(utc)-GAST06(ut1, tt)*RadianToHour)/RadianToHour, ddpsi*math.Cos(MeanObliquity(tt)), 1e-15, "GAST pole offset Error")
+	th.CheckFT(t, ddpsi/mas, 0.3/math.Sin(MeanObliquity(tt)), 0.01, "2025 dpsi Error")
+}
@@ -0,0 +1,232 @@
+// Two-part Julian dates
+package astrotime
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Julian date of the Unix epoch noon, 1970-01-01T12:00:00
+	unixNoonJD      = int64(2440588)
+	unixNoonSeconds = int64(43200)
+	nanoPerDay      = int64(86400e9)
+
+	// JulianYear is the length of the Julian year in days
+	JulianYear = float64(365.25)
+	// BesselianYear is the length of the tropical year in days used for
+	// Besselian epochs
+	BesselianYear = float64(365.242198781)
+	// B1900 is the Julian date of the B1900.0 epoch
+	B1900 = float64(2415020.31352)
+
+	// Layouts for JD.Format and prefixes recognized by ParseJD
+	JDStr             = "JD"
+	MJDStr            = "MJD"
+	JulianEpochStr    = "J"
+	BesselianEpochStr = "B"
+)
+
+// JD is a Julian date held in two parts, SOFA style, so that nanosecond
+// resolution is kept over the full range of time.Time. Day is the whole
+// Julian day number (the preceding noon) and Frac the fraction of the day
+// in [0, 1). A JD carries no time scale; it is read in whatever scale the
+// time it came from was in.
+type JD struct {
+	Day  float64
+	Frac float64
+}
+
+// NewJD returns the JD day+frac normalized so Day is whole and Frac is in
+// [0, 1).
+func NewJD(day, frac float64) JD {
+	d := math.Floor(day)
+	f := (day - d) + frac
+	fd := math.Floor(f)
+	return JD{Day: d + fd, Frac: f - fd}
+}
+
+// JDFromTime converts a time.Time to a JD without loss of resolution.
+func JDFromTime(t time.Time) JD {
+	s := t.Unix() - unixNoonSeconds
+	days := s / 86400
+	rem := s % 86400
+	if rem < 0 {
+		rem += 86400
+		days--
+	}
+	ns := rem*int64(time.Second) + int64(t.Nanosecond())
+	return JD{Day: float64(unixNoonJD + days), Frac: float64(ns) / float64(nanoPerDay)}
+}
+
+// JDFromMJD returns the JD for the given MJD.
+func JDFromMJD(mjd float64) JD {
+	d := math.Floor(mjd)
+	return NewJD(d+JulianDayZero-0.5, (mjd-d)+0.5)
+}
+
+// JulianEpochJD returns the JD of the Julian epoch e, i.e. 2000.0 for J2000.0.
+func JulianEpochJD(e float64) JD {
+	days := (e - 2000.0) * JulianYear
+	d := math.Floor(days)
+	return NewJD(J2000+d, days-d)
+}
+
+// BesselianEpochJD returns the JD of the Besselian epoch e, i.e. 1950.0 for
+// B1950.0.
+func BesselianEpochJD(e float64) JD {
+	days := (e - 1900.0) * BesselianYear
+	d := math.Floor(days)
+	return NewJD(math.Floor(B1900)+d, (B1900-math.Floor(B1900))+(days-d))
+}
+
+// Float returns the JD as a single float64, with about 20 microseconds of
+// resolution.
+func (j JD) Float() float64 {
+	return j.Day + j.Frac
+}
+
+// MJD returns the modified Julian date.
+func (j JD) MJD() float64 {
+	return (j.Day - (JulianDayZero + 0.5)) + (j.Frac + 0.5)
+}
+
+// Time returns the time.Time, in UTC, for the JD.
+func (j JD) Time() time.Time {
+	j = NewJD(j.Day, j.Frac)
+	days := int64(j.Day) - unixNoonJD
+	ns := int64(math.Round(j.Frac * float64(nanoPerDay)))
+	return time.Unix(days*86400+unixNoonSeconds, ns).UTC()
+}
+
+// Add returns the JD plus the duration d.
+func (j JD) Add(d time.Duration) JD {
+	days := int64(d) / nanoPerDay
+	rem := int64(d) % nanoPerDay
+	return NewJD(j.Day+float64(days), j.Frac+float64(rem)/float64(nanoPerDay))
+}
+
+// Sub returns the duration j-o.
+func (j JD) Sub(o JD) time.Duration {
+	days := time.Duration(j.Day-o.Day) * 24 * time.Hour
+	return days + time.Duration(math.Round((j.Frac-o.Frac)*float64(nanoPerDay)))
+}
+
+// Before reports whether j is before o.
+func (j JD) Before(o JD) bool {
+	return j.Sub(o) < 0
+}
+
+// After reports whether j is after o.
+func (j JD) After(o JD) bool {
+	return j.Sub(o) > 0
+}
+
+// JulianEpoch returns the Julian epoch, i.e. 2000.0 at J2000.0.
+func (j JD) JulianEpoch() float64 {
+	return 2000.0 + ((j.Day-J2000)+j.Frac)/JulianYear
+}
+
+// BesselianEpoch returns the Besselian epoch, i.e. 1950.0 at B1950.0.
+func (j JD) BesselianEpoch() float64 {
+	return 1900.0 + ((j.Day-B1900)+j.Frac)/BesselianYear
+}
+
+// formatSplit formats day+frac with prec decimals without going through a
+// single float64.
+func formatSplit(day, frac float64, prec int) string {
+	j := NewJD(day, frac)
+	f := strconv.FormatFloat(j.Frac, 'f', prec, 64)
+	if strings.HasPrefix(f, "1") {
+		// the fraction rounded up to the next day
+		j.Day++
+		f = strconv.FormatFloat(0.0, 'f', prec, 64)
+	}
+	return strconv.FormatFloat(j.Day, 'f', 0, 64) + f[1:]
+}
+
+// Format returns the JD as a string in the given layout with prec decimals:
+//
+//	JDStr             "JD 2451545.000"
+//	MJDStr            "MJD 51544.500"
+//	JulianEpochStr    "J2000.000"
+//	BesselianEpochStr "B1950.000"
+func (j JD) Format(layout string, prec int) string {
+	var s string
+	switch layout {
+	case JDStr:
+		s = JDStr + " " + formatSplit(j.Day, j.Frac, prec)
+	case MJDStr:
+		s = MJDStr + " " + formatSplit(j.Day-(JulianDayZero+0.5), j.Frac+0.5, prec)
+	case JulianEpochStr:
+		s = JulianEpochStr + strconv.FormatFloat(j.JulianEpoch(), 'f', prec, 64)
+	case BesselianEpochStr:
+		s = BesselianEpochStr + strconv.FormatFloat(j.BesselianEpoch(), 'f', prec, 64)
+	}
+	return s
+}
+
+// String returns the JD with nanosecond resolution.
+func (j JD) String() string {
+	return j.Format(JDStr, 15)
+}
+
+// parseSplit parses a decimal number into whole and fractional parts so no
+// precision is lost.
+func parseSplit(s string) (float64, float64, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	neg := strings.HasPrefix(whole, "-")
+	w, err := strconv.ParseFloat(whole, 64)
+	if err != nil {
+		return 0.0, 0.0, err
+	}
+	f := 0.0
+	if frac != "" {
+		f, err = strconv.ParseFloat("0."+frac, 64)
+		if err != nil {
+			return 0.0, 0.0, err
+		}
+	}
+	if neg {
+		f = -f
+	}
+	return w, f, nil
+}
+
+// ParseJD parses a Julian date, MJD or epoch. Accepted forms are
+// "2451545.0" or "JD 2451545.0", "MJD 51544.5", "J2000.0" and "B1950.0".
+func ParseJD(s string) (JD, error) {
+	str := strings.TrimSpace(s)
+	var layout string
+	for _, l := range []string{MJDStr, JDStr, JulianEpochStr, BesselianEpochStr} {
+		if strings.HasPrefix(strings.ToUpper(str), l) {
+			layout = l
+			str = strings.TrimSpace(str[len(l):])
+			break
+		}
+	}
+	if layout == JulianEpochStr || layout == BesselianEpochStr {
+		e, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			emsg := fmt.Sprintf("Cannot parse epoch %s: %v", s, err)
+			return JD{}, errors.New(emsg)
+		}
+		if layout == JulianEpochStr {
+			return JulianEpochJD(e), nil
+		}
+		return BesselianEpochJD(e), nil
+	}
+	w, f, err := parseSplit(str)
+	if err != nil {
+		emsg := fmt.Sprintf("Cannot parse Julian date %s: %v", s, err)
+		return JD{}, errors.New(emsg)
+	}
+	if layout == MJDStr {
+		return NewJD(w+JulianDayZero-0.5, f+0.5), nil
+	}
+	return NewJD(w, f), nil
+}
@@ -0,0 +1,100 @@
+package astrotime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestJDFromTime(t *testing.T) {
+	ti := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	j := JDFromTime(ti)
+	th.CheckF(t, j.Day, 2451545.0, "JD Day Error")
+	th.CheckF(t, j.Frac, 0.0, "JD Frac Error")
+	th.CheckF(t, j.MJD(), 51544.5, "JD MJD Error")
+
+	// nanoseconds survive the round trip over a wide range of dates
+	for _, ti := range []time.Time{
+		time.Date(1858, 11, 17, 0, 0, 0, 1, time.UTC),
+		time.Date(1969, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		time.Date(2025, 4, 1, 6, 30, 15, 123456789, time.UTC),
+		time.Date(2262, 1, 1, 0, 0, 0, 987654321, time.UTC),
+	} {
+		back := JDFromTime(ti).Time()
+		if !back.Equal(ti) {
+			fmt.Println("JD round trip Expected ", ti, " Got ", back)
+			t.Fail()
+		}
+	}
+}
+
+func TestJDArithmetic(t *testing.T) {
+	ti := time.Date(2025, 4, 1, 6, 30, 15, 123456789, time.UTC)
+	j := JDFromTime(ti)
+	for _, d := range []time.Duration{time.Nanosecond, -time.Nanosecond, 36 * time.Hour, -1000 * time.Hour} {
+		j2 := j.Add(d)
+		if !j2.Time().Equal(ti.Add(d)) {
+			fmt.Println("JD Add Expected ", ti.Add(d), " Got ", j2.Time())
+			t.Fail()
+		}
+		if j2.Sub(j) != d {
+			fmt.Println("JD Sub Expected ", d, " Got ", j2.Sub(j))
+			t.Fail()
+		}
+	}
+	if !j.Before(j.Add(time.Nanosecond)) || !j.After(j.Add(-time.Nanosecond)) {
+		fmt.Println("JD Before/After Error")
+		t.Fail()
+	}
+	n := NewJD(2451544.5, 1.25)
+	th.CheckF(t, n.Day, 2451545.0, "NewJD Day Error")
+	th.CheckF(t, n.Frac, 0.75, "NewJD Frac Error")
+}
+
+func TestJDEpochs(t *testing.T) {
+	th.CheckF(t, JulianEpochJD(2000.0).Float(), J2000, "J2000 Error")
+	th.CheckFT(t, BesselianEpochJD(1950.0).Float(), 2433282.4235, 1e-4, "B1950 Error")
+	th.CheckFT(t, BesselianEpochJD(1900.0).Float(), B1900, 1e-9, "B1900 Error")
+	j := JDFromTime(time.Date(2025, 7, 2, 12, 0, 0, 0, time.UTC))
+	th.CheckFT(t, JulianEpochJD(j.JulianEpoch()).Float(), j.Float(), 1e-8, "Julian epoch Error")
+	th.CheckFT(t, BesselianEpochJD(j.BesselianEpoch()).Float(), j.Float(), 1e-8, "Besselian epoch Error")
+}
+
+func TestJDFormatParse(t *testing.T) {
+	j := JDFromTime(time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC))
+	th.CheckS(t, j.Format(JDStr, 3), "JD 2451545.000", "Format JD Error")
+	th.CheckS(t, j.Format(MJDStr, 1), "MJD 51544.5", "Format MJD Error")
+	th.CheckS(t, j.Format(JulianEpochStr, 1), "J2000.0", "Format J Error")
+	th.CheckS(t, JDFromTime(time.Date(2000, 1, 1, 11, 59, 59, 999999999, time.UTC)).Format(JDStr, 3),
+		"JD 2451545.000", "Format carry Error")
+
+	cases := map[string]float64{
+		"2451545.0":        2451545.0,
+		"JD 2451545.25":    2451545.25,
+		"MJD 51544.5":      2451545.0,
+		"mjd 60000":        2460000.5,
+		"J2000.0":          2451545.0,
+		"B1950.0":          2433282.42345905,
+		" JD 2460767.5   ": 2460767.5,
+	}
+	for s, ex := range cases {
+		j, err := ParseJD(s)
+		if err != nil {
+			fmt.Println("ParseJD error: ", err)
+			t.Fail()
+		}
+		th.CheckFT(t, j.Float(), ex, 1e-8, "ParseJD Error "+s)
+	}
+	_, err := ParseJD("JD two")
+	th.CheckErrorNil(t, err, "Expected ParseJD error")
+
+	// formatting with nanosecond resolution parses back exactly
+	ti := time.Date(2025, 4, 1, 6, 30, 15, 123456789, time.UTC)
+	j, _ = ParseJD(JDFromTime(ti).String())
+	if !j.Time().Equal(ti) {
+		fmt.Println("String round trip Expected ", ti, " Got ", j.Time())
+		t.Fail()
+	}
+}
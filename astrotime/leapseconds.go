@@ -0,0 +1,268 @@
+// Leap second (TAI-UTC) tables
+package astrotime
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// NTPUnixOffset is the number of seconds from the NTP epoch
+	// (1900-01-01) to the Unix epoch.
+	NTPUnixOffset = int64(2208988800)
+)
+
+// leapEntry is one row of the TAI-UTC table. Before 1972 UTC drifted
+// with respect to TAI, so TAI-UTC = Offset + (MJD - RefMJD) * Rate.
+type leapEntry struct {
+	Start  time.Time // UTC
+	Offset float64   // seconds
+	RefMJD float64
+	Rate   float64 // seconds per day
+}
+
+// LeapSecondTable holds TAI-UTC as a function of UTC.
+type LeapSecondTable struct {
+	entries []leapEntry
+	updated time.Time
+	expires time.Time
+}
+
+var (
+	numberRe = regexp.MustCompile(`[-+]?[0-9]+\.?[0-9]*`)
+
+	//go:embed leap-seconds.list
+	defaultLeapSeconds string
+
+	leapMu    sync.RWMutex
+	leapTable *LeapSecondTable
+)
+
+func init() {
+	t, err := ParseLeapSecondsList(strings.NewReader(defaultLeapSeconds))
+	if err != nil {
+		panic(err)
+	}
+	leapTable = t
+}
+
+// DefaultLeapSecondTable returns the table embedded in the module.
+func DefaultLeapSecondTable() *LeapSecondTable {
+	t, _ := ParseLeapSecondsList(strings.NewReader(defaultLeapSeconds))
+	return t
+}
+
+// ReadLeapSecondsList reads an IETF/IERS leap-seconds.list file.
+func ReadLeapSecondsList(fn string) (*LeapSecondTable, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseLeapSecondsList(f)
+}
+
+// ntpTime converts NTP seconds to a UTC time.
+func ntpTime(s string) (time.Time, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(v-NTPUnixOffset, 0).UTC(), nil
+}
+
+// ParseLeapSecondsList parses the leap-seconds.list format: data lines of
+// NTP seconds and TAI-UTC, '#' comments, '#$' last update and '#@'
+// expiration lines.
+func ParseLeapSecondsList(r io.Reader) (*LeapSecondTable, error) {
+	t := &LeapSecondTable{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#$") || strings.HasPrefix(line, "#@") {
+			ti, err := ntpTime(strings.TrimSpace(line[2:]))
+			if err != nil {
+				emsg := fmt.Sprintf("leap-seconds.list line %d: %v", lineNum, err)
+				return nil, errors.New(emsg)
+			}
+			if line[1] == '$' {
+				t.updated = ti
+			} else {
+				t.expires = ti
+			}
+			continue
+		}
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			emsg := fmt.Sprintf("leap-seconds.list line %d: expected 2 fields, got %d", lineNum, len(fields))
+			return nil, errors.New(emsg)
+		}
+		start, err := ntpTime(fields[0])
+		if err != nil {
+			emsg := fmt.Sprintf("leap-seconds.list line %d: %v", lineNum, err)
+			return nil, errors.New(emsg)
+		}
+		offset, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			emsg := fmt.Sprintf("leap-seconds.list line %d: %v", lineNum, err)
+			return nil, errors.New(emsg)
+		}
+		t.entries = append(t.entries, leapEntry{Start: start, Offset: offset})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t.finish()
+}
+
+// ReadTaiUtcDat reads a USNO tai-utc.dat file.
+func ReadTaiUtcDat(fn string) (*LeapSecondTable, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseTaiUtcDat(f)
+}
+
+// ParseTaiUtcDat parses the USNO tai-utc.dat format, including the pre-1972
+// drift terms. A line looks like
+//
+//	1966 JAN  1 =JD 2439126.5  TAI-UTC=   4.3131700 S + (MJD - 39126.) X 0.002592 S
+func ParseTaiUtcDat(r io.Reader) (*LeapSecondTable, error) {
+	t := &LeapSecondTable{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// JD, offset, reference MJD and rate follow the =JD marker
+		idx := strings.Index(line, "=JD")
+		var fields []string
+		if idx >= 0 {
+			fields = numberRe.FindAllString(line[idx:], -1)
+		}
+		v, ok := parseFloats(fields)
+		if !ok || len(v) != 4 {
+			emsg := fmt.Sprintf("tai-utc.dat line %d: unrecognized format", lineNum)
+			return nil, errors.New(emsg)
+		}
+		start := FromMJD(v[0] - JulianDayZero)
+		t.entries = append(t.entries, leapEntry{Start: start, Offset: v[1], RefMJD: v[2], Rate: v[3]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t.finish()
+}
+
+// finish sorts and checks a freshly parsed table.
+func (t *LeapSecondTable) finish() (*LeapSecondTable, error) {
+	if len(t.entries) == 0 {
+		return nil, errors.New("No leap second entries found")
+	}
+	sort.Slice(t.entries, func(i, j int) bool {
+		return t.entries[i].Start.Before(t.entries[j].Start)
+	})
+	return t, nil
+}
+
+// Updated returns the last update time of the table. Zero if unknown.
+func (t *LeapSecondTable) Updated() time.Time {
+	return t.updated
+}
+
+// Expires returns the time after which the table may be missing leap
+// seconds. Zero if unknown.
+func (t *LeapSecondTable) Expires() time.Time {
+	return t.expires
+}
+
+// Expired reports if the table is out of date at ti.
+func (t *LeapSecondTable) Expired(ti time.Time) bool {
+	return !t.expires.IsZero() && ti.After(t.expires)
+}
+
+// LastLeap returns the UTC time of the most recent entry in the table.
+func (t *LeapSecondTable) LastLeap() time.Time {
+	return t.entries[len(t.entries)-1].Start
+}
+
+// TAIminusUTC returns TAI-UTC in seconds at the UTC time utc. Before the
+// first entry the first entry is used and an error is returned.
+func (t *LeapSecondTable) TAIminusUTC(utc time.Time) (float64, error) {
+	idx := sort.Search(len(t.entries), func(i int) bool {
+		return t.entries[i].Start.After(utc)
+	}) - 1
+	var err error
+	if idx < 0 {
+		idx = 0
+		emsg := fmt.Sprintf("%v is before the start of the leap second table", utc)
+		err = errors.New(emsg)
+	}
+	e := t.entries[idx]
+	dat := e.Offset
+	if e.Rate != 0.0 {
+		dat += (ToMJD(utc) - e.RefMJD) * e.Rate
+	}
+	return dat, err
+}
+
+// LoadLeapSeconds reads a leap-seconds.list or tai-utc.dat file and makes
+// it the table used for all time scale conversions.
+func LoadLeapSeconds(fn string) error {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	var t *LeapSecondTable
+	if strings.Contains(string(b), "TAI-UTC=") {
+		t, err = ParseTaiUtcDat(strings.NewReader(string(b)))
+	} else {
+		t, err = ParseLeapSecondsList(strings.NewReader(string(b)))
+	}
+	if err != nil {
+		return err
+	}
+	SetLeapSecondTable(t)
+	return nil
+}
+
+// SetLeapSecondTable sets the table used for all time scale conversions.
+// nil restores the embedded default.
+func SetLeapSecondTable(t *LeapSecondTable) {
+	if t == nil {
+		t = DefaultLeapSecondTable()
+	}
+	leapMu.Lock()
+	defer leapMu.Unlock()
+	leapTable = t
+}
+
+// GetLeapSecondTable returns the table used for all time scale conversions.
+func GetLeapSecondTable() *LeapSecondTable {
+	leapMu.RLock()
+	defer leapMu.RUnlock()
+	return leapTable
+}
@@ -0,0 +1,73 @@
+package astrotime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestDefaultLeapSecondTable(t *testing.T) {
+	tbl := DefaultLeapSecondTable()
+	cases := map[time.Time]float64{
+		time.Date(1972, 1, 1, 0, 0, 0, 0, time.UTC):             10.0,
+		time.Date(1999, 6, 1, 0, 0, 0, 0, time.UTC):             32.0,
+		time.Date(2016, 12, 31, 23, 59, 59, 999e6, time.UTC):    36.0,
+		time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC):             37.0,
+		time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC):             37.0,
+		time.Date(2012, 6, 30, 23, 59, 59, 999999999, time.UTC): 34.0,
+	}
+	for ti, ex := range cases {
+		dat, err := tbl.TAIminusUTC(ti)
+		if err != nil {
+			fmt.Println("TAIminusUTC error: ", err)
+			t.Fail()
+		}
+		th.CheckF(t, dat, ex, "TAI-UTC Error at "+ti.String())
+	}
+	_, err := tbl.TAIminusUTC(time.Date(1965, 1, 1, 0, 0, 0, 0, time.UTC))
+	th.CheckErrorNil(t, err, "Expected error before table start")
+	if tbl.Expires().IsZero() || tbl.Updated().IsZero() {
+		fmt.Println("Expected expiration and update times")
+		t.Fail()
+	}
+	if !tbl.LastLeap().Equal(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		fmt.Println("LastLeap Error: ", tbl.LastLeap())
+		t.Fail()
+	}
+}
+
+func TestReadTaiUtcDat(t *testing.T) {
+	tbl, err := ReadTaiUtcDat("testdata/tai-utc.dat")
+	if err != nil {
+		fmt.Println("ReadTaiUtcDat error: ", err)
+		t.Fail()
+		return
+	}
+	dat, _ := tbl.TAIminusUTC(FromMJD(37300.0))
+	th.CheckFT(t, dat, 1.4228180, 1e-9, "1961 TAI-UTC Error")
+	// drift term
+	dat, _ = tbl.TAIminusUTC(FromMJD(39500.0))
+	th.CheckFT(t, dat, 4.3131700+(39500.0-39126.0)*0.002592, 1e-9, "1966 TAI-UTC Error")
+	dat, _ = tbl.TAIminusUTC(FromMJD(37700.0))
+	th.CheckFT(t, dat, 1.8458580+(37700.0-37665.0)*0.0011232, 1e-9, "1962 TAI-UTC Error")
+	dat, _ = tbl.TAIminusUTC(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	th.CheckF(t, dat, 37.0, "2020 TAI-UTC Error")
+}
+
+func TestLoadLeapSeconds(t *testing.T) {
+	defer SetLeapSecondTable(nil)
+	err := LoadLeapSeconds("testdata/tai-utc.dat")
+	if err != nil {
+		fmt.Println("LoadLeapSeconds error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, TAIminusUTC(FromMJD(37300.0)), 1.4228180, 1e-9, "TAI-UTC Error")
+	err = LoadLeapSeconds("leap-seconds.list")
+	if err != nil {
+		fmt.Println("LoadLeapSeconds error: ", err)
+		t.Fail()
+	}
+	th.CheckF(t, TAIminusUTC(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)), 37.0, "TAI-UTC Error")
+}
@@ -0,0 +1,62 @@
+// Frame bias and precession
+package astrotime
+
+import (
+	"math"
+)
+
+// rotX returns the matrix rotating the reference frame about the x axis by
+// a radians, as in SOFA.
+func rotX(a float64) [3][3]float64 {
+	s, c := math.Sincos(a)
+	return [3][3]float64{{1.0, 0.0, 0.0}, {0.0, c, s}, {0.0, -s, c}}
+}
+
+// rotZ returns the matrix rotating the reference frame about the z axis by
+// a radians, as in SOFA.
+func rotZ(a float64) [3][3]float64 {
+	s, c := math.Sincos(a)
+	return [3][3]float64{{c, s, 0.0}, {-s, c, 0.0}, {0.0, 0.0, 1.0}}
+}
+
+// MatMul returns the matrix product a b.
+func MatMul(a, b [3][3]float64) [3][3]float64 {
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+// PrecessionAngles returns the IAU 2006 Fukushima-Williams angles gamma_bar,
+// phi_bar, psi_bar and epsilon_A in radians at the TT date tt. They include
+// the frame bias between the ICRS and the mean equator and equinox of J2000.
+func PrecessionAngles(tt JD) (float64, float64, float64, float64) {
+	t := julianCenturies(tt)
+	gamb := -0.052928 + (10.556378+(0.4932044+(-0.00031238+
+		(-0.000002788+0.0000000260*t)*t)*t)*t)*t
+	phib := 84381.412819 + (-46.811016+(0.0511268+(0.00053289+
+		(-0.000000440+(-0.0000000176)*t)*t)*t)*t)*t
+	psib := -0.041775 + (5038.481484+(1.5584175+(-0.00018522+
+		(-0.000026452+(-0.0000000148)*t)*t)*t)*t)*t
+	return gamb * ArcsecondToRadian, phib * ArcsecondToRadian,
+		psib * ArcsecondToRadian, MeanObliquity(tt)
+}
+
+// EclipticMatrix returns the matrix rotating ICRS vectors onto the mean
+// ecliptic and equinox of the TT date tt.
+func EclipticMatrix(tt JD) [3][3]float64 {
+	gamb, phib, psib, _ := PrecessionAngles(tt)
+	return MatMul(rotZ(-psib), MatMul(rotX(phib), rotZ(gamb)))
+}
+
+// BiasPrecessionMatrix returns the matrix rotating ICRS vectors onto the
+// mean equator and equinox of the TT date tt. At J2000.0 it is the frame
+// bias alone.
+func BiasPrecessionMatrix(tt JD) [3][3]float64 {
+	return MatMul(rotX(-MeanObliquity(tt)), EclipticMatrix(tt))
+}
@@ -0,0 +1,42 @@
+package astrotime
+
+import (
+	"testing"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// Reference values from the SOFA test suite.
+
+func TestBiasPrecessionMatrix(t *testing.T) {
+	want := [3][3]float64{
+		{0.9999995505176007047, 0.8695404617348208406e-3, 0.3779735201865589104e-3},
+		{-0.8695404723772031414e-3, 0.9999996219496027161, -0.1361752497080270143e-6},
+		{-0.3779734957034089490e-3, -0.1924880847894457113e-6, 0.9999999285679971958},
+	}
+	m := BiasPrecessionMatrix(JDFromMJD(50123.9999))
+	for i := range want {
+		for j := range want[i] {
+			th.CheckFT(t, m[i][j], want[i][j], 1e-12, "Bias-precession matrix Error")
+		}
+	}
+
+	// frame bias alone at J2000.0
+	b := BiasPrecessionMatrix(NewJD(J2000, 0.0))
+	th.CheckFT(t, b[0][1], -0.7078368960971557145e-7, 1e-14, "Frame bias Error")
+	th.CheckFT(t, b[0][2], 0.8056213977613185606e-7, 1e-14, "Frame bias Error")
+}
+
+func TestEclipticMatrix(t *testing.T) {
+	want := [3][3]float64{
+		{0.9999952427708701137, -0.2829062057663042347e-2, -0.1229163741100017629e-2},
+		{0.3084546876908653562e-2, 0.9174891871550392514, 0.3977487611849338124},
+		{0.2488512951527405928e-5, -0.3977506604161195467, 0.9174935488232863071},
+	}
+	m := EclipticMatrix(NewJD(2456165.5, 0.401182685))
+	for i := range want {
+		for j := range want[i] {
+			th.CheckFT(t, m[i][j], want[i][j], 1e-12, "Ecliptic matrix Error")
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Scheduling on wall-clock, sidereal and computed event times
+package astrotime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Trigger returns the first time strictly after 'after' at which a job
+// should fire, or ErrNoMoreTimes once it has no more.
+type Trigger func(after time.Time) (time.Time, error)
+
+// ErrNoMoreTimes is returned by a Trigger that has no more fire times. The
+// scheduler removes the job as completed, without reporting an error.
+var ErrNoMoreTimes = errors.New("No more scheduled times")
+
+// job is a named Trigger and the function it runs.
+type job struct {
+	name    string
+	trigger Trigger
+	f       func(time.Time)
+	next    time.Time
+}
+
+// Scheduler runs any number of named jobs, each firing at the times given
+// by its Trigger. Jobs run one at a time on the goroutine calling Run.
+// Fire times that pass while another job is running by more than the
+// tolerance are skipped and reported as missed.
+type Scheduler struct {
+	mu        sync.Mutex
+	jobs      map[string]*job
+	tolerance time.Duration
+	missed    func(name string, scheduled, now time.Time)
+	errFn     func(name string, err error)
+	wake      chan struct{}
+}
+
+// NewScheduler returns an empty Scheduler with a 1 second missed-tick
+// tolerance.
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		jobs:      make(map[string]*job),
+		tolerance: time.Second,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// SetTolerance sets how late a job may fire before the tick is counted as
+// missed.
+func (s *Scheduler) SetTolerance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tolerance = d
+}
+
+// OnMissed sets the function called for each skipped fire time.
+func (s *Scheduler) OnMissed(f func(name string, scheduled, now time.Time)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.missed = f
+}
+
+// OnError sets the function called when a job's Trigger fails. The job is
+// removed from the scheduler. A Trigger returning ErrNoMoreTimes is not a
+// failure and is not reported.
+func (s *Scheduler) OnError(f func(name string, err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errFn = f
+}
+
+// AddJob adds the job 'name' which runs f at every time given by tr. Jobs
+// may be added while the scheduler is running.
+func (s *Scheduler) AddJob(name string, tr Trigger, f func(time.Time)) error {
+	next, err := tr(time.Now())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		emsg := fmt.Sprintf("Job %s already scheduled", name)
+		return errors.New(emsg)
+	}
+	s.jobs[name] = &job{name: name, trigger: tr, f: f, next: next}
+	s.poke()
+	return nil
+}
+
+// RemoveJob removes the job 'name'.
+func (s *Scheduler) RemoveJob(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, name)
+	s.poke()
+}
+
+// Jobs returns the names of the scheduled jobs in the order they next fire.
+func (s *Scheduler) Jobs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.jobs))
+	for n := range s.jobs {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return s.jobs[names[i]].next.Before(s.jobs[names[j]].next)
+	})
+	return names
+}
+
+// Next returns the next fire time of the job 'name'.
+func (s *Scheduler) Next(name string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	return j.next, true
+}
+
+// poke wakes Run to pick up changed jobs. Caller must hold s.mu.
+func (s *Scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// earliest returns the job which fires next. Caller must hold s.mu.
+func (s *Scheduler) earliest() *job {
+	var first *job
+	for _, j := range s.jobs {
+		if first == nil || j.next.Before(first.next) {
+			first = j
+		}
+	}
+	return first
+}
+
+// Run fires the jobs until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		j := s.earliest()
+		var name string
+		var at time.Time
+		if j != nil {
+			name, at = j.name, j.next
+		}
+		s.mu.Unlock()
+
+		// Receiving from a nil channel blocks forever
+		var timerC <-chan time.Time
+		var timer *time.Timer
+		if j != nil {
+			timer = time.NewTimer(time.Until(at))
+			timerC = timer.C
+		}
+		select {
+		case <-timerC:
+			s.fire(name, at)
+		case <-s.wake:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// fire runs the job 'name' due at 'at' and advances it past any fire
+// times missed while it, or a job before it, was running.
+func (s *Scheduler) fire(name string, at time.Time) {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	if !ok || !j.next.Equal(at) {
+		// removed or replaced while waiting
+		s.mu.Unlock()
+		return
+	}
+	tolerance, missed, errFn := s.tolerance, s.missed, s.errFn
+	s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(at) > tolerance {
+		if missed != nil {
+			missed(name, at, now)
+		}
+	} else {
+		j.f(at)
+		now = time.Now()
+	}
+
+	prev := at
+	next, err := j.trigger(prev)
+	for err == nil {
+		if !next.After(prev) {
+			emsg := fmt.Sprintf("Trigger returned %v, not after %v", next, prev)
+			err = errors.New(emsg)
+			break
+		}
+		if now.Sub(next) <= tolerance {
+			break
+		}
+		if missed != nil {
+			missed(name, next, now)
+		}
+		prev = next
+		next, err = j.trigger(prev)
+	}
+
+	s.mu.Lock()
+	if s.jobs[name] != j {
+		s.mu.Unlock()
+		return
+	}
+	if err == nil {
+		j.next = next
+		s.mu.Unlock()
+		return
+	}
+	delete(s.jobs, name)
+	s.mu.Unlock()
+	// outside the lock, so errFn may call back into the scheduler
+	if errFn != nil && !errors.Is(err, ErrNoMoreTimes) {
+		errFn(name, err)
+	}
+}
+
+// Every returns a Trigger firing on wall-clock period p boundaries plus an
+// offset o, as Schedule does.
+func Every(p, o time.Duration) Trigger {
+	return func(after time.Time) (time.Time, error) {
+		if p <= 0 {
+			return time.Time{}, errors.New("Period must be positive")
+		}
+		next := after.Truncate(p).Add(o)
+		for !next.After(after) {
+			next = next.Add(p)
+		}
+		return next, nil
+	}
+}
+
+// Times returns a Trigger firing at each of the given times, for example
+// precomputed rise or transit times. It returns ErrNoMoreTimes once the
+// times are used up.
+func Times(ts ...time.Time) Trigger {
+	sorted := append([]time.Time(nil), ts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	return func(after time.Time) (time.Time, error) {
+		for _, t := range sorted {
+			if t.After(after) {
+				return t, nil
+			}
+		}
+		return time.Time{}, ErrNoMoreTimes
+	}
+}
+
+// nextLST returns the first UTC time after 'after' at which the local
+// apparent sidereal time at east longitude lonDeg is lstHr hours.
+func nextLST(after time.Time, lonDeg, lstHr float64) time.Time {
+	dh := normHour(lstHr - LST(after, lonDeg))
+	if dh < 1e-12 {
+		dh += HourPerDay
+	}
+	next := after.Add(SiderealToSolar(time.Duration(dh * float64(time.Hour))))
+	// take up precession and nutation over the interval
+	res := normHour(lstHr-LST(next, lonDeg)+12.0) - 12.0
+	return next.Add(SiderealToSolar(time.Duration(res * float64(time.Hour))))
+}
+
+// AtLST returns a Trigger firing each sidereal day when the local apparent
+// sidereal time at east longitude lonDeg equals any of lstHr (hours).
+func AtLST(lonDeg float64, lstHr ...float64) Trigger {
+	return func(after time.Time) (time.Time, error) {
+		if len(lstHr) == 0 {
+			return time.Time{}, errors.New("No LST given")
+		}
+		var first time.Time
+		for _, l := range lstHr {
+			next := nextLST(after, lonDeg, l)
+			if !next.After(after) {
+				next = nextLST(next, lonDeg, l)
+			}
+			if first.IsZero() || next.Before(first) {
+				first = next
+			}
+		}
+		return first, nil
+	}
+}
+
+// AtHourAngle returns a Trigger firing each sidereal day when a source at
+// right ascension of date raHr (hours) is at any of the hour angles haHr
+// (hours) for east longitude lonDeg. AtHourAngle(lon, ra, 0) fires at
+// transit.
+func AtHourAngle(lonDeg, raHr float64, haHr ...float64) Trigger {
+	lst := make([]float64, len(haHr))
+	for idx, ha := range haHr {
+		lst[idx] = normHour(raHr + ha)
+	}
+	return AtLST(lonDeg, lst...)
+}
+
+// EveryLST returns a Trigger firing every sidereal interval p, aligned so
+// that one fire time falls on LST lstHr at east longitude lonDeg. p should
+// divide 24 hours.
+func EveryLST(lonDeg float64, p time.Duration, lstHr float64) Trigger {
+	return func(after time.Time) (time.Time, error) {
+		if p <= 0 {
+			return time.Time{}, errors.New("Period must be positive")
+		}
+		ph := p.Hours()
+		// d is in [0, p); the next aligned LST
+		d := math.Mod(normHour(lstHr-LST(after, lonDeg)), ph)
+		target := normHour(LST(after, lonDeg) + d)
+		if d < 1e-12 {
+			target = normHour(target + ph)
+		}
+		next := nextLST(after, lonDeg, target)
+		if !next.After(after) {
+			// 'after' was on an aligned LST to within rounding
+			next = nextLST(next, lonDeg, normHour(target+ph))
+		}
+		return next, nil
+	}
+}
+
+// AtElevation returns a Trigger firing each sidereal day when a fixed
+// source at right ascension of date raHr (hours) and declination decDeg
+// crosses elevation elDeg, rising or setting, at the site latDeg, lonDeg
+// (east). Refraction and the source's own motion are ignored. The Trigger
+// fails if the source never crosses elDeg.
+func AtElevation(latDeg, lonDeg, raHr, decDeg, elDeg float64, rising bool) Trigger {
+	rad := math.Pi / 180.0
+	cosH := (math.Sin(elDeg*rad) - math.Sin(latDeg*rad)*math.Sin(decDeg*rad)) /
+		(math.Cos(latDeg*rad) * math.Cos(decDeg*rad))
+	if math.IsNaN(cosH) || cosH < -1.0 || cosH > 1.0 {
+		return func(after time.Time) (time.Time, error) {
+			emsg := fmt.Sprintf("Source at dec %.4f never crosses elevation %.4f at latitude %.4f",
+				decDeg, elDeg, latDeg)
+			return time.Time{}, errors.New(emsg)
+		}
+	}
+	ha := math.Acos(cosH) / rad / degreePerHour
+	if rising {
+		ha = -ha
+	}
+	return AtHourAngle(lonDeg, raHr, ha)
+}
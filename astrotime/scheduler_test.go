@@ -0,0 +1,205 @@
+package astrotime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestTriggers(t *testing.T) {
+	after := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	lon := -118.282
+
+	next, err := Every(time.Hour, 15*time.Minute)(after)
+	if err != nil || !next.Equal(after.Add(15*time.Minute)) {
+		fmt.Println("Every Error: ", next, err)
+		t.Fail()
+	}
+
+	next, err = AtLST(lon, 12.0)(after)
+	if err != nil {
+		fmt.Println("AtLST error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, LST(next, lon), 12.0, 1e-9, "AtLST Error")
+	if !next.After(after) || next.Sub(after) > 24*time.Hour {
+		fmt.Println("AtLST out of range: ", next)
+		t.Fail()
+	}
+	// the following fire is a sidereal day later
+	next2, _ := AtLST(lon, 12.0)(next)
+	th.CheckFT(t, next2.Sub(next).Seconds(), 86164.09, 0.01, "Sidereal day Error")
+
+	// multiple LSTs fire in order
+	a, _ := AtLST(lon, 3.0, LST(after, lon)+0.5)(after)
+	th.CheckFT(t, a.Sub(after).Hours(), 0.5/SiderealRatio, 1e-6, "AtLST order Error")
+
+	ra := 14.261
+	next, _ = AtHourAngle(lon, ra, -2.0)(after)
+	th.CheckFT(t, HourAngle(next, lon, ra), -2.0, 1e-9, "AtHourAngle Error")
+
+	next, _ = EveryLST(lon, 2*time.Hour, 1.0)(after)
+	lst := LST(next, lon)
+	r := math.Mod(lst-1.0+24.0, 2.0)
+	th.CheckFT(t, math.Min(r, 2.0-r), 0.0, 1e-9, "EveryLST Error")
+	if next.Sub(after) > 2*time.Hour {
+		fmt.Println("EveryLST too late: ", next)
+		t.Fail()
+	}
+	// from a fire time, the next is a full period later
+	next2, _ = EveryLST(lon, 2*time.Hour, 1.0)(next)
+	th.CheckFT(t, next2.Sub(next).Seconds(), 7200.0/SiderealRatio, 0.01, "EveryLST period Error")
+	for _, d := range []time.Duration{-time.Nanosecond, time.Nanosecond} {
+		n, _ := EveryLST(lon, 2*time.Hour, 1.0)(next.Add(d))
+		if !n.After(next.Add(d)) {
+			fmt.Println("EveryLST not after: ", n, next.Add(d))
+			t.Fail()
+		}
+	}
+
+	lat := 37.2339
+	next, err = AtElevation(lat, lon, ra, 19.18, 10.0, true)(after)
+	if err != nil {
+		fmt.Println("AtElevation error: ", err)
+		t.Fail()
+	}
+	ha := HourAngle(next, lon, ra) * 15.0 * math.Pi / 180.0
+	sinEl := math.Sin(lat*math.Pi/180.0)*math.Sin(19.18*math.Pi/180.0) +
+		math.Cos(lat*math.Pi/180.0)*math.Cos(19.18*math.Pi/180.0)*math.Cos(ha)
+	th.CheckFT(t, math.Asin(sinEl)*180.0/math.Pi, 10.0, 1e-6, "AtElevation Error")
+	if ha > 0.0 {
+		fmt.Println("AtElevation should be rising: ", ha)
+		t.Fail()
+	}
+	_, err = AtElevation(lat, lon, ra, -70.0, 0.0, true)(after)
+	th.CheckErrorNil(t, err, "Expected never rises error")
+
+	tr := Times(after.Add(2*time.Hour), after.Add(time.Hour))
+	next, _ = tr(after)
+	th.CheckF(t, next.Sub(after).Hours(), 1.0, "Times Error")
+	_, err = tr(after.Add(3 * time.Hour))
+	if !errors.Is(err, ErrNoMoreTimes) {
+		fmt.Println("Expected ErrNoMoreTimes, got ", err)
+		t.Fail()
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler()
+	s.SetTolerance(50 * time.Millisecond)
+	var mu sync.Mutex
+	counts := map[string]int{}
+	missed := 0
+	errs := map[string]bool{}
+	s.OnMissed(func(name string, scheduled, now time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		missed++
+	})
+	s.OnError(func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs[name] = true
+	})
+	count := func(name string) func(time.Time) {
+		return func(time.Time) {
+			mu.Lock()
+			defer mu.Unlock()
+			counts[name]++
+		}
+	}
+	err := s.AddJob("fast", Every(100*time.Millisecond, 0), count("fast"))
+	if err != nil {
+		fmt.Println("AddJob error: ", err)
+		t.Fail()
+	}
+	err = s.AddJob("fast", Every(time.Second, 0), count("fast"))
+	th.CheckErrorNil(t, err, "Expected duplicate job error")
+	now := time.Now()
+	s.AddJob("once", Times(now.Add(150*time.Millisecond)), count("once"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(550 * time.Millisecond)
+	// a slow job makes the fast one miss ticks
+	s.AddJob("slow", Times(time.Now().Add(20*time.Millisecond)), func(time.Time) {
+		time.Sleep(350 * time.Millisecond)
+	})
+	time.Sleep(600 * time.Millisecond)
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if counts["fast"] < 5 {
+		fmt.Println("fast ran ", counts["fast"], " times")
+		t.Fail()
+	}
+	th.CheckI(t, counts["once"], 1, "once Error")
+	if missed < 2 {
+		fmt.Println("Expected missed ticks, got ", missed)
+		t.Fail()
+	}
+	// jobs running out of times complete without an error
+	if len(errs) != 0 {
+		fmt.Println("OnError Error: ", errs)
+		t.Fail()
+	}
+	if len(s.Jobs()) != 1 || s.Jobs()[0] != "fast" {
+		fmt.Println("Jobs Error: ", s.Jobs())
+		t.Fail()
+	}
+}
+
+func TestSchedulerErrorReentry(t *testing.T) {
+	s := NewScheduler()
+	var mu sync.Mutex
+	runs := 0
+	run := func(time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs++
+	}
+	// fires once, then fails
+	once := func(at time.Time) Trigger {
+		return func(after time.Time) (time.Time, error) {
+			if at.After(after) {
+				return at, nil
+			}
+			return time.Time{}, errors.New("Trigger failed")
+		}
+	}
+	// the error callback may use the scheduler, here to re-arm the job
+	rearmed := false
+	s.OnError(func(name string, err error) {
+		if !rearmed {
+			rearmed = true
+			s.AddJob(name, once(time.Now().Add(50*time.Millisecond)), run)
+		}
+	})
+	s.AddJob("once", once(time.Now().Add(50*time.Millisecond)), run)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	th.CheckI(t, runs, 2, "Re-armed job runs Error")
+}
@@ -0,0 +1,132 @@
+// Sidereal time and hour angle
+package astrotime
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// SiderealRatio is the ratio of a solar to a sidereal interval
+	SiderealRatio = float64(1.00273781191135448)
+	// RadianToHour converts radians to hours of angle
+	RadianToHour = 12.0 / math.Pi
+	// degrees of angle per hour
+	degreePerHour = float64(15.0)
+)
+
+// normRadian returns a in [0, 2*pi).
+func normRadian(a float64) float64 {
+	w := math.Mod(a, 2.0*math.Pi)
+	if w < 0.0 {
+		w += 2.0 * math.Pi
+	}
+	return w
+}
+
+// normHour returns h in [0, 24).
+func normHour(h float64) float64 {
+	w := math.Mod(h, HourPerDay)
+	if w < 0.0 {
+		w += HourPerDay
+	}
+	return w
+}
+
+// EarthRotationAngle returns the IAU 2000 Earth rotation angle in radians
+// at the UT1 date ut1.
+func EarthRotationAngle(ut1 JD) float64 {
+	// the whole days drop out modulo 2 pi
+	tu := (ut1.Day - J2000) + ut1.Frac
+	f := math.Mod(ut1.Day, 1.0) + math.Mod(ut1.Frac, 1.0)
+	return normRadian(2.0 * math.Pi * (f + 0.7790572732640 + 0.00273781191135448*tu))
+}
+
+// GMST06 returns the IAU 2006 Greenwich mean sidereal time in radians for
+// the UT1 date ut1 and the TT date tt of the same instant.
+func GMST06(ut1, tt JD) float64 {
+	t := julianCenturies(tt)
+	p := 0.014506 + (4612.156534+(1.3915817+(-0.00000044+
+		(-0.000029956+(-0.0000000368)*t)*t)*t)*t)*t
+	return normRadian(EarthRotationAngle(ut1) + p*ArcsecondToRadian)
+}
+
+// EquationOfEquinoxes returns GAST-GMST in radians at the TT date tt: the
+// nutation in longitude projected on the equator plus the leading
+// complementary terms of IERS Conventions 2003.
+func EquationOfEquinoxes(tt JD) float64 {
+	t := julianCenturies(tt)
+	_, _, f, d, om := fundamentalArgs(t)
+	dpsi, _ := Nutation(tt)
+	ct := 2640.96e-6*math.Sin(om) - 0.39e-6*math.Cos(om) +
+		63.52e-6*math.Sin(2.0*om) - 0.02e-6*math.Cos(2.0*om) +
+		11.75e-6*math.Sin(2.0*f-2.0*d+3.0*om) + 0.01e-6*math.Cos(2.0*f-2.0*d+3.0*om) +
+		11.21e-6*math.Sin(2.0*f-2.0*d+om) + 0.01e-6*math.Cos(2.0*f-2.0*d+om) -
+		4.55e-6*math.Sin(2.0*f-2.0*d+2.0*om) +
+		2.02e-6*math.Sin(2.0*f+3.0*om) +
+		1.98e-6*math.Sin(2.0*f+om) -
+		1.72e-6*math.Sin(3.0*om) -
+		0.87e-6*t*math.Sin(om)
+	return dpsi*math.Cos(MeanObliquity(tt)) + ct*ArcsecondToRadian
+}
+
+// GAST06 returns the Greenwich apparent sidereal time in radians for the
+// UT1 date ut1 and the TT date tt of the same instant.
+func GAST06(ut1, tt JD) float64 {
+	return normRadian(GMST06(ut1, tt) + EquationOfEquinoxes(tt))
+}
+
+// ut1tt returns the UT1 and TT dates for the UTC time utc using the loaded
+// leap second table and the EOP eop.
+func ut1tt(utc time.Time, eop EOP) (JD, JD) {
+	j := JDFromTime(utc)
+	ut1 := j.Add(secondsToDuration(eop.UT1UTC))
+	tt := j.Add(secondsToDuration(TTminusUTC(utc)))
+	return ut1, tt
+}
+
+// GMST returns the Greenwich mean sidereal time in hours at the UTC time
+// utc.
+func GMST(utc time.Time) float64 {
+	ut1, tt := ut1tt(utc, GetEOP(utc))
+	return GMST06(ut1, tt) * RadianToHour
+}
+
+// GAST returns the Greenwich apparent sidereal time in hours at the UTC
+// time utc, including the celestial pole offsets of the EOP table.
+func GAST(utc time.Time) float64 {
+	eop := GetEOP(utc)
+	ut1, tt := ut1tt(utc, eop)
+	ddpsi, _ := eop.PoleOffsets(tt)
+	g := GAST06(ut1, tt) + ddpsi*math.Cos(MeanObliquity(tt))
+	return normRadian(g) * RadianToHour
+}
+
+// LST returns the local apparent sidereal time in hours at the UTC time
+// utc for an east longitude lonDeg in degrees.
+func LST(utc time.Time, lonDeg float64) float64 {
+	return normHour(GAST(utc) + lonDeg/degreePerHour)
+}
+
+// LMST returns the local mean sidereal time in hours at the UTC time utc
+// for an east longitude lonDeg in degrees.
+func LMST(utc time.Time, lonDeg float64) float64 {
+	return normHour(GMST(utc) + lonDeg/degreePerHour)
+}
+
+// HourAngle returns the hour angle in hours, in [-12, 12), of a right
+// ascension raHr in hours at the UTC time utc and east longitude lonDeg in
+// degrees. The RA should be of date, as returned by NOVAS.
+func HourAngle(utc time.Time, lonDeg, raHr float64) float64 {
+	return normHour(LST(utc, lonDeg)-raHr+12.0) - 12.0
+}
+
+// SolarToSidereal converts a solar (UT1) interval to a sidereal interval.
+func SolarToSidereal(d time.Duration) time.Duration {
+	return time.Duration(math.Round(float64(d) * SiderealRatio))
+}
+
+// SiderealToSolar converts a sidereal interval to a solar (UT1) interval.
+func SiderealToSolar(d time.Duration) time.Duration {
+	return time.Duration(math.Round(float64(d) / SiderealRatio))
+}
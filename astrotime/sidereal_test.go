@@ -0,0 +1,56 @@
+package astrotime
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// Reference values from the SOFA test suite.
+
+func TestEarthRotationAngle(t *testing.T) {
+	j := JDFromMJD(54388.0)
+	th.CheckFT(t, EarthRotationAngle(j), 0.4022837240028158102, 1e-12, "ERA Error")
+}
+
+func TestGMST06(t *testing.T) {
+	j := JDFromMJD(53736.0)
+	th.CheckFT(t, GMST06(j, j), 1.754174971870091203, 1e-12, "GMST06 Error")
+	// IAU 2000B nutation, good to about a mas
+	th.CheckFT(t, GAST06(j, j), 1.754166136510680589, 1e-8, "GAST06 Error")
+}
+
+func TestNutation(t *testing.T) {
+	j := JDFromMJD(53736.0)
+	dpsi, deps := Nutation(j)
+	th.CheckFT(t, dpsi, -0.9632552291148362783e-5, 1e-8, "dpsi Error")
+	th.CheckFT(t, deps, 0.4063197106621159367e-4, 1e-8, "deps Error")
+	th.CheckFT(t, MeanObliquity(JDFromMJD(54388.0)), 0.4090749229387258204, 1e-14, "Obliquity Error")
+}
+
+func TestLSTHourAngle(t *testing.T) {
+	SetEOPTable(nil)
+	utc := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	gmst := GMST(utc)
+	lon := -118.2817
+	th.CheckFT(t, LMST(utc, lon), math.Mod(gmst+lon/15.0+24.0, 24.0), 1e-12, "LMST Error")
+	// equation of the equinoxes is about a second of time
+	ee := (GAST(utc) - gmst) * SecondPerHour
+	if math.Abs(ee) > 1.2 {
+		fmt.Println("Equation of the equinoxes out of range: ", ee)
+		t.Fail()
+	}
+	lst := LST(utc, lon)
+	th.CheckFT(t, HourAngle(utc, lon, lst), 0.0, 1e-12, "HourAngle Error")
+	th.CheckFT(t, HourAngle(utc, lon, lst+13.0), 11.0, 1e-12, "HourAngle wrap Error")
+	th.CheckFT(t, HourAngle(utc, lon, lst-1.5), 1.5, 1e-12, "HourAngle Error")
+
+	// a sidereal day later the LST is the same, less about 8 ms of
+	// precession
+	later := utc.Add(SiderealToSolar(24 * time.Hour))
+	th.CheckFT(t, math.Mod(LST(later, lon)-lst+36.0, 24.0)-12.0, 0.0, 1e-5, "Sidereal day Error")
+	th.CheckFT(t, SolarToSidereal(24*time.Hour).Seconds(), 86636.5469, 1e-3, "SolarToSidereal Error")
+}
@@ -0,0 +1,216 @@
+// Time scales: UTC, TAI, TT, TDB, UT1 and GPS
+package astrotime
+
+import (
+	"math"
+	"time"
+)
+
+type TimeScale int
+
+const (
+	// Enums to identify the time scale
+	_ TimeScale = iota
+	UTC
+	TAI
+	TT
+	TDB
+	UT1
+	GPS
+
+	// Time scale strings
+	UTCStr = "UTC"
+	TAIStr = "TAI"
+	TTStr  = "TT"
+	TDBStr = "TDB"
+	UT1Str = "UT1"
+	GPSStr = "GPS"
+
+	// TTminusTAI is the fixed offset TT - TAI in seconds
+	TTminusTAI = float64(32.184)
+	// TAIminusGPS is the fixed offset TAI - GPS in seconds
+	TAIminusGPS = float64(19.0)
+	// J2000 is the Julian date of the J2000.0 epoch
+	J2000 = float64(2451545.0)
+)
+
+// ScaleTime is a clock reading in a given time scale. Time holds the
+// reading as if it were a UTC wall clock, so for example the TT reading of
+// 2025-01-01T00:00:00 UTC is 2025-01-01T00:01:09.184.
+type ScaleTime struct {
+	Scale TimeScale
+	Time  time.Time
+}
+
+// String returns the name of the time scale.
+func (ts TimeScale) String() string {
+	var s string
+	switch ts {
+	case UTC:
+		s = UTCStr
+	case TAI:
+		s = TAIStr
+	case TT:
+		s = TTStr
+	case TDB:
+		s = TDBStr
+	case UT1:
+		s = UT1Str
+	case GPS:
+		s = GPSStr
+	}
+	return s
+}
+
+// NewScaleTime returns a clock reading t in the time scale ts.
+func NewScaleTime(ts TimeScale, t time.Time) ScaleTime {
+	return ScaleTime{Scale: ts, Time: t.UTC()}
+}
+
+// secondsToDuration converts seconds to a Duration rounded to the
+// nanosecond.
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// TAIminusUTC returns TAI-UTC in seconds at the UTC time utc using the
+// loaded leap second table.
+func TAIminusUTC(utc time.Time) float64 {
+	dat, _ := GetLeapSecondTable().TAIminusUTC(utc)
+	return dat
+}
+
+// TTminusUTC returns TT-UTC in seconds at the UTC time utc.
+func TTminusUTC(utc time.Time) float64 {
+	return TAIminusUTC(utc) + TTminusTAI
+}
+
+// DeltaT returns TT-UT1 in seconds at the UTC time utc, with UT1-UTC taken
+// from the loaded EOP table.
+func DeltaT(utc time.Time) float64 {
+	return TTminusUTC(utc) - GetEOP(utc).UT1UTC
+}
+
+// TDBminusTT returns TDB-TT in seconds at the given TT Julian date. This is
+// the series of Fairhead et al. (1990) as used by NOVAS, good to about
+// 10 microseconds.
+func TDBminusTT(jdTT float64) float64 {
+	t := (jdTT - J2000) / JulianCentury
+	return 0.001657*math.Sin(628.3076*t+6.2401) +
+		0.000022*math.Sin(575.3385*t+4.2970) +
+		0.000014*math.Sin(1256.6152*t+6.1969) +
+		0.000005*math.Sin(606.9777*t+4.0212) +
+		0.000005*math.Sin(52.9691*t+0.4444) +
+		0.000002*math.Sin(21.3299*t+5.5431) +
+		0.000010*t*math.Sin(628.3076*t+4.2490)
+}
+
+// toTAI converts the reading to TAI.
+func (st ScaleTime) toTAI() time.Time {
+	t := st.Time
+	switch st.Scale {
+	case UTC:
+		return t.Add(secondsToDuration(TAIminusUTC(t)))
+	case TAI:
+		return t
+	case TT:
+		return t.Add(-secondsToDuration(TTminusTAI))
+	case TDB:
+		tt := t.Add(-secondsToDuration(TDBminusTT(ToMJD(t) + JulianDayZero)))
+		return tt.Add(-secondsToDuration(TTminusTAI))
+	case UT1:
+		// UT1-UTC varies slowly, so evaluating it at the UT1 reading is
+		// well below a microsecond off.
+		utc := t.Add(-secondsToDuration(GetEOP(t).UT1UTC))
+		return utc.Add(secondsToDuration(TAIminusUTC(utc)))
+	case GPS:
+		return t.Add(secondsToDuration(TAIminusGPS))
+	}
+	return t
+}
+
+// fromTAI converts a TAI reading to the time scale ts.
+func fromTAI(tai time.Time, ts TimeScale) time.Time {
+	switch ts {
+	case UTC:
+		return taiToUTC(tai)
+	case TAI:
+		return tai
+	case TT:
+		return tai.Add(secondsToDuration(TTminusTAI))
+	case TDB:
+		tt := tai.Add(secondsToDuration(TTminusTAI))
+		return tt.Add(secondsToDuration(TDBminusTT(ToMJD(tt) + JulianDayZero)))
+	case UT1:
+		utc := taiToUTC(tai)
+		return utc.Add(secondsToDuration(GetEOP(utc).UT1UTC))
+	case GPS:
+		return tai.Add(-secondsToDuration(TAIminusGPS))
+	}
+	return tai
+}
+
+// taiToUTC inverts UTC = TAI - (TAI-UTC)(UTC). The offset is looked up at
+// the UTC estimate, which settles after two passes.
+func taiToUTC(tai time.Time) time.Time {
+	utc := tai.Add(-secondsToDuration(TAIminusUTC(tai)))
+	return tai.Add(-secondsToDuration(TAIminusUTC(utc)))
+}
+
+// To returns the same instant read in the time scale ts.
+func (st ScaleTime) To(ts TimeScale) ScaleTime {
+	if st.Scale == ts {
+		return st
+	}
+	return ScaleTime{Scale: ts, Time: fromTAI(st.toTAI(), ts)}
+}
+
+// UTC returns the instant in UTC.
+func (st ScaleTime) UTC() ScaleTime {
+	return st.To(UTC)
+}
+
+// TAI returns the instant in TAI.
+func (st ScaleTime) TAI() ScaleTime {
+	return st.To(TAI)
+}
+
+// TT returns the instant in TT.
+func (st ScaleTime) TT() ScaleTime {
+	return st.To(TT)
+}
+
+// TDB returns the instant in TDB.
+func (st ScaleTime) TDB() ScaleTime {
+	return st.To(TDB)
+}
+
+// UT1 returns the instant in UT1.
+func (st ScaleTime) UT1() ScaleTime {
+	return st.To(UT1)
+}
+
+// GPS returns the instant in GPS time.
+func (st ScaleTime) GPS() ScaleTime {
+	return st.To(GPS)
+}
+
+// MJD returns the modified Julian date of the reading in its own scale.
+func (st ScaleTime) MJD() float64 {
+	return ToMJD(st.Time)
+}
+
+// JulianDate returns the Julian date of the reading in its own scale.
+func (st ScaleTime) JulianDate() float64 {
+	return st.MJD() + JulianDayZero
+}
+
+// JD returns the two-part Julian date of the reading in its own scale.
+func (st ScaleTime) JD() JD {
+	return JDFromTime(st.Time)
+}
+
+// ToTT returns the TT reading of the UTC time utc.
+func ToTT(utc time.Time) time.Time {
+	return NewScaleTime(UTC, utc).TT().Time
+}
@@ -0,0 +1,74 @@
+package astrotime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestTimeScaleOffsets(t *testing.T) {
+	SetEOPTable(nil)
+	utc := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	st := NewScaleTime(UTC, utc)
+
+	tai := st.TAI()
+	th.CheckF(t, tai.Time.Sub(utc).Seconds(), 37.0, "TAI-UTC Error")
+	th.CheckS(t, tai.Scale.String(), TAIStr, "Scale Error")
+	tt := st.TT()
+	th.CheckF(t, tt.Time.Sub(utc).Seconds(), 69.184, "TT-UTC Error")
+	gps := st.GPS()
+	th.CheckF(t, gps.Time.Sub(utc).Seconds(), 18.0, "GPS-UTC Error")
+	tdb := st.TDB()
+	th.CheckFT(t, tdb.Time.Sub(tt.Time).Seconds(), TDBminusTT(tt.JulianDate()), 1e-9, "TDB-TT Error")
+	th.CheckF(t, TTminusUTC(utc), 69.184, "TTminusUTC Error")
+	th.CheckF(t, DeltaT(utc), 69.184, "DeltaT Error")
+	th.CheckF(t, ToTT(utc).Sub(utc).Seconds(), 69.184, "ToTT Error")
+
+	SetEOPTable(NewEOPTable([]EOP{{MJD: 60676, UT1UTC: 0.05}, {MJD: 60677, UT1UTC: 0.05}}))
+	defer SetEOPTable(nil)
+	ut1 := st.UT1()
+	th.CheckFT(t, ut1.Time.Sub(utc).Seconds(), 0.05, 1e-9, "UT1-UTC Error")
+	th.CheckFT(t, DeltaT(utc), 69.134, 1e-9, "DeltaT Error")
+}
+
+func TestTimeScaleRoundTrip(t *testing.T) {
+	scales := []TimeScale{UTC, TAI, TT, TDB, UT1, GPS}
+	utc := time.Date(2016, 12, 31, 23, 59, 30, 123456789, time.UTC)
+	for _, from := range scales {
+		for _, to := range scales {
+			st := NewScaleTime(UTC, utc).To(from)
+			back := st.To(to).To(UTC)
+			d := back.Time.Sub(utc)
+			if d > time.Microsecond || d < -time.Microsecond {
+				fmt.Println("Round trip error ", from, "->", to, ": ", d)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestTimeScaleLeapBoundary(t *testing.T) {
+	// one second of TAI spans the inserted leap second
+	before := NewScaleTime(UTC, time.Date(2016, 12, 31, 23, 59, 59, 0, time.UTC))
+	after := NewScaleTime(UTC, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	dTAI := after.TAI().Time.Sub(before.TAI().Time)
+	th.CheckF(t, dTAI.Seconds(), 2.0, "Leap second Error")
+	back := after.TAI().UTC()
+	if !back.Time.Equal(after.Time) {
+		fmt.Println("TAI to UTC Error: ", back.Time)
+		t.Fail()
+	}
+}
+
+func TestTDBminusTT(t *testing.T) {
+	// the annual term dominates with an amplitude of 1.657 ms
+	for mjd := 60000.0; mjd < 60400.0; mjd += 10.0 {
+		d := TDBminusTT(mjd + JulianDayZero)
+		if d > 0.0018 || d < -0.0018 {
+			fmt.Println("TDB-TT out of range: ", d)
+			t.Fail()
+		}
+	}
+}
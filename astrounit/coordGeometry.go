@@ -0,0 +1,161 @@
+// Spherical geometry on AngleCoord
+package astrounit
+
+import (
+	"errors"
+	"math"
+)
+
+// The functions in this file treat A1 as the longitude-like angle (RA or
+// Az) and A2 as the latitude-like angle (Dec or El), so they apply to
+// RA/Dec and Az/El pairs but not to Lat/Lon pairs, which are stored the
+// other way round. Results are returned in the units of the receiver.
+
+// toUnit returns a in the unit u.
+func (a Angle) toUnit(u AngleUnit) Angle {
+	switch u {
+	case MilliRadian:
+		return a.MilliRadian()
+	case Degree:
+		return a.Degree()
+	case Hour:
+		return a.Hour()
+	case ArcMinute:
+		return a.ArcMinute()
+	case ArcSecond:
+		return a.ArcSecond()
+	case MilliArcSecond:
+		return a.MilliArcSecond()
+	}
+	return a.Radian()
+}
+
+// lonLat returns A1 and A2 in radians.
+func (ac AngleCoord) lonLat() (float64, float64) {
+	return ac.A1.Radian().Value, ac.A2.Radian().Value
+}
+
+// withLonLat returns a coordinate from radians in the units of ac, with
+// the longitude in [0, 2 pi).
+func (ac AngleCoord) withLonLat(lon, lat float64) AngleCoord {
+	lon = math.Mod(lon, 2.0*math.Pi)
+	if lon < 0.0 {
+		lon += 2.0 * math.Pi
+	}
+	return AngleCoord{
+		A1: NewAngle(Radian, lon).toUnit(ac.A1.Unit),
+		A2: NewAngle(Radian, lat).toUnit(ac.A2.Unit),
+	}
+}
+
+// UnitVector returns the Cartesian unit vector of the coordinate, x toward
+// A1 = 0 and z toward A2 = 90 degrees.
+func (ac AngleCoord) UnitVector() [3]float64 {
+	lon, lat := ac.lonLat()
+	sl, cl := math.Sincos(lon)
+	sb, cb := math.Sincos(lat)
+	return [3]float64{cb * cl, cb * sl, sb}
+}
+
+// NewAngleCoordVector returns the coordinate, in units a1u and a2u, of the
+// direction of the vector v, which need not be normalized.
+func NewAngleCoordVector(v [3]float64, a1u, a2u AngleUnit) AngleCoord {
+	ac := AngleCoord{A1: NewAngle(a1u, 0.0), A2: NewAngle(a2u, 0.0)}
+	return ac.withLonLat(math.Atan2(v[1], v[0]), math.Atan2(v[2], math.Hypot(v[0], v[1])))
+}
+
+// Separation returns the great circle angle between ac and b using the
+// Vincenty formula, which is accurate at all separations.
+func (ac AngleCoord) Separation(b AngleCoord) Angle {
+	l1, p1 := ac.lonLat()
+	l2, p2 := b.lonLat()
+	sdl, cdl := math.Sincos(l2 - l1)
+	sp1, cp1 := math.Sincos(p1)
+	sp2, cp2 := math.Sincos(p2)
+	num := math.Hypot(cp2*sdl, cp1*sp2-sp1*cp2*cdl)
+	den := sp1*sp2 + cp1*cp2*cdl
+	return NewAngle(Radian, math.Atan2(num, den))
+}
+
+// PositionAngle returns the position angle of b as seen from ac, measured
+// from the direction of increasing A2 (north, or up) toward increasing A1
+// (east, or clockwise in azimuth), in [0, 360) degrees.
+func (ac AngleCoord) PositionAngle(b AngleCoord) Angle {
+	l1, p1 := ac.lonLat()
+	l2, p2 := b.lonLat()
+	sdl, cdl := math.Sincos(l2 - l1)
+	pa := math.Atan2(sdl*math.Cos(p2), math.Cos(p1)*math.Sin(p2)-math.Sin(p1)*math.Cos(p2)*cdl)
+	if pa < 0.0 {
+		pa += 2.0 * math.Pi
+	}
+	return NewAngle(Radian, pa)
+}
+
+// Offset returns the coordinate sep away from ac along the position angle
+// pa.
+func (ac AngleCoord) Offset(sep, pa Angle) AngleCoord {
+	l1, p1 := ac.lonLat()
+	sd, cd := math.Sincos(sep.Radian().Value)
+	st, ct := math.Sincos(pa.Radian().Value)
+	sp1, cp1 := math.Sincos(p1)
+	sp2 := sp1*cd + cp1*sd*ct
+	p2 := math.Asin(math.Max(-1.0, math.Min(1.0, sp2)))
+	l2 := l1 + math.Atan2(st*sd*cp1, cd-sp1*sp2)
+	return ac.withLonLat(l2, p2)
+}
+
+// Interpolate returns the point a fraction f of the way from ac to b along
+// the great circle joining them. f outside [0, 1] extrapolates. The path
+// between antipodal points is undefined.
+func (ac AngleCoord) Interpolate(b AngleCoord, f float64) (AngleCoord, error) {
+	va := ac.UnitVector()
+	vb := b.UnitVector()
+	omega := ac.Separation(b).Radian().Value
+	so := math.Sin(omega)
+	if so < 1e-12 {
+		if omega > math.Pi/2.0 {
+			return ac, errors.New("Great circle between antipodal points is undefined")
+		}
+		return ac, nil
+	}
+	fa := math.Sin((1.0-f)*omega) / so
+	fb := math.Sin(f*omega) / so
+	v := [3]float64{fa*va[0] + fb*vb[0], fa*va[1] + fb*vb[1], fa*va[2] + fb*vb[2]}
+	return NewAngleCoordVector(v, ac.A1.Unit, ac.A2.Unit), nil
+}
+
+// Midpoint returns the point halfway between ac and b on the great circle
+// joining them.
+func (ac AngleCoord) Midpoint(b AngleCoord) (AngleCoord, error) {
+	return ac.Interpolate(b, 0.5)
+}
+
+// ArcDistance returns the smallest angle between ac and the great circle
+// arc from a to b, the shorter way round.
+func (ac AngleCoord) ArcDistance(a, b AngleCoord) Angle {
+	d := math.Min(ac.Separation(a).Radian().Value, ac.Separation(b).Radian().Value)
+	p, va, vb := ac.UnitVector(), a.UnitVector(), b.UnitVector()
+	n := cross(va, vb)
+	nn := math.Sqrt(dot(n, n))
+	if nn < 1e-12 {
+		return NewAngle(Radian, d)
+	}
+	for idx := range n {
+		n[idx] /= nn
+	}
+	// the foot of p on the great circle lies within the arc
+	pn := dot(p, n)
+	q := [3]float64{p[0] - pn*n[0], p[1] - pn*n[1], p[2] - pn*n[2]}
+	if dot(cross(va, q), n) >= 0.0 && dot(cross(q, vb), n) >= 0.0 {
+		d = math.Min(d, math.Abs(math.Asin(math.Max(-1.0, math.Min(1.0, pn)))))
+	}
+	return NewAngle(Radian, d)
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func cross(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
@@ -0,0 +1,83 @@
+package astrounit
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestSeparation(t *testing.T) {
+	// Arcturus to Spica, about 32.8 degrees
+	arc := NewRaDecCoord(Hour, 14.261, Degree, 19.1825)
+	spi := NewRaDecCoord(Hour, 13.4199, Degree, -11.1613)
+	th.CheckFT(t, arc.Separation(spi).Degree().Value, 32.79, 0.01, "Separation Error")
+	th.CheckFT(t, arc.Separation(arc).Degree().Value, 0.0, 1e-12, "Zero separation Error")
+
+	// small and antipodal separations keep their precision
+	a := NewAzElCoord(Degree, 10.0, 20.0)
+	b := NewAzElCoord(Degree, 10.0, 20.0+1e-9)
+	th.CheckFT(t, a.Separation(b).Degree().Value, 1e-9, 1e-14, "Small separation Error")
+	c := NewAzElCoord(Degree, 190.0, -20.0)
+	th.CheckFT(t, a.Separation(c).Degree().Value, 180.0, 1e-9, "Antipodal separation Error")
+}
+
+func TestPositionAngleOffset(t *testing.T) {
+	a := NewRaDecCoord(Hour, 6.0, Degree, 30.0)
+	north := NewRaDecCoord(Hour, 6.0, Degree, 31.0)
+	east := a.Offset(NewAngle(Degree, 1.0), NewAngle(Degree, 90.0))
+	th.CheckFT(t, a.PositionAngle(north).Degree().Value, 0.0, 1e-9, "North PA Error")
+	th.CheckFT(t, a.PositionAngle(east).Degree().Value, 90.0, 1e-9, "East PA Error")
+	if east.Ra().Unit != Hour || east.Ra().Value <= 6.0 {
+		fmt.Println("East offset should increase RA in hours: ", east)
+		t.Fail()
+	}
+
+	for _, pa := range []float64{0.0, 45.0, 135.0, 200.0, 359.0} {
+		for _, sep := range []float64{0.001, 1.0, 30.0, 120.0} {
+			b := a.Offset(NewAngle(Degree, sep), NewAngle(Degree, pa))
+			th.CheckFT(t, a.Separation(b).Degree().Value, sep, 1e-9, "Offset separation Error")
+			th.CheckFT(t, a.PositionAngle(b).Degree().Value, pa, 1e-7, "Offset PA Error")
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	a := NewAzElCoord(Degree, 350.0, 10.0)
+	b := NewAzElCoord(Degree, 30.0, 50.0)
+	m, err := a.Midpoint(b)
+	if err != nil {
+		fmt.Println("Midpoint error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, a.Separation(m).Degree().Value, b.Separation(m).Degree().Value, 1e-9, "Midpoint Error")
+	th.CheckFT(t, a.Separation(m).Degree().Value*2.0, a.Separation(b).Degree().Value, 1e-9, "Midpoint on arc Error")
+
+	q, _ := a.Interpolate(b, 0.25)
+	th.CheckFT(t, a.Separation(q).Degree().Value, a.Separation(b).Degree().Value/4.0, 1e-9, "Interpolate Error")
+	e, _ := a.Interpolate(b, 0.0)
+	th.CheckFT(t, e.Separation(a).Degree().Value, 0.0, 1e-9, "Interpolate start Error")
+	th.CheckFT(t, e.Az().Value, 350.0, 1e-9, "Interpolate unit Error")
+
+	_, err = a.Midpoint(NewAzElCoord(Degree, 170.0, -10.0))
+	th.CheckErrorNil(t, err, "Expected antipodal error")
+}
+
+func TestUnitVector(t *testing.T) {
+	a := NewRaDecCoord(Hour, 18.0, Degree, -45.0)
+	v := a.UnitVector()
+	th.CheckFT(t, math.Sqrt(v[0]*v[0]+v[1]*v[1]+v[2]*v[2]), 1.0, 1e-15, "Unit vector norm Error")
+	b := NewAngleCoordVector([3]float64{2.0 * v[0], 2.0 * v[1], 2.0 * v[2]}, Hour, Degree)
+	th.CheckFT(t, b.Ra().Value, 18.0, 1e-12, "Vector RA Error")
+	th.CheckFT(t, b.Dec().Value, -45.0, 1e-12, "Vector Dec Error")
+}
+
+func TestArcDistance(t *testing.T) {
+	a := NewAzElCoord(Degree, 100.0, 0.0)
+	b := NewAzElCoord(Degree, 120.0, 0.0)
+	th.CheckFT(t, NewAzElCoord(Degree, 110.0, 3.0).ArcDistance(a, b).Degree().Value, 3.0, 1e-9, "ArcDistance Error")
+	// beyond the end of the arc the endpoint is closest
+	p := NewAzElCoord(Degree, 125.0, 0.0)
+	th.CheckFT(t, p.ArcDistance(a, b).Degree().Value, 5.0, 1e-9, "ArcDistance end Error")
+}
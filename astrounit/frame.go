@@ -0,0 +1,308 @@
+// Celestial reference frames
+package astrounit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+)
+
+type Frame int
+
+const (
+	// Enums to identify the reference frame
+	_ Frame = iota
+	ICRS
+	FK5
+	FK4
+	Galactic
+	Ecliptic
+	EclipticOfDate
+	Supergalactic
+
+	// Frame strings
+	ICRSStr           = "icrs"
+	FK5Str            = "fk5"
+	FK4Str            = "fk4"
+	GalacticStr       = "galactic"
+	EclipticStr       = "ecliptic"
+	EclipticOfDateStr = "eclipticofdate"
+	SupergalacticStr  = "supergalactic"
+)
+
+// FrameCoord is a direction in a reference frame. Ac holds RA/Dec for the
+// equatorial frames (ICRS, FK5 J2000, FK4 B1950) and longitude/latitude
+// for the others, in the same A1/A2 order. Epoch is the equinox of an
+// EclipticOfDate coordinate and is ignored by the other frames.
+type FrameCoord struct {
+	Frame Frame      `yaml:"frame" json:"frame"`
+	Ac    AngleCoord `yaml:"coord" json:"coord"`
+	Epoch time.Time  `yaml:"epoch" json:"epoch"`
+}
+
+var (
+	// ICRS to Galactic (Hipparcos, ESA 1997, vol 1 sec 1.5.3)
+	icrsToGalactic = [3][3]float64{
+		{-0.0548755604162154, -0.8734370902348850, -0.4838350155487132},
+		{+0.4941094278755837, -0.4448296299600112, +0.7469822444972189},
+		{-0.8676661490190047, -0.1980763734312015, +0.4559837761750669},
+	}
+	// Galactic to Supergalactic: the supergalactic north pole is at
+	// l = 47.37, b = +6.32 and the origin at l = 137.37, b = 0 (de
+	// Vaucouleurs et al. 1976)
+	galacticToSupergalactic = poleMatrix(137.37, 0.0, 47.37, 6.32)
+	// FK4 B1950 to FK5 J2000 for a star with no proper motion at B1950
+	// (Standish 1982, Aoki et al. 1983)
+	fk4ToFK5 = [3][3]float64{
+		{0.9999256782, -0.0111820611, -0.0048579477},
+		{0.0111820610, 0.9999374784, -0.0000271765},
+		{0.0048579479, -0.0000271474, 0.9999881997},
+	}
+	// E-terms of aberration included in FK4 positions, radians
+	fk4ETerms = [3]float64{-1.62557e-6, -0.31919e-6, -0.13843e-6}
+)
+
+// String returns the name of the frame.
+func (f Frame) String() string {
+	var s string
+	switch f {
+	case ICRS:
+		s = ICRSStr
+	case FK5:
+		s = FK5Str
+	case FK4:
+		s = FK4Str
+	case Galactic:
+		s = GalacticStr
+	case Ecliptic:
+		s = EclipticStr
+	case EclipticOfDate:
+		s = EclipticOfDateStr
+	case Supergalactic:
+		s = SupergalacticStr
+	}
+	return s
+}
+
+// ParseFrame returns the Frame named s, ignoring case. "j2000" and "b1950"
+// are accepted for FK5 and FK4.
+func ParseFrame(s string) (Frame, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case ICRSStr:
+		return ICRS, nil
+	case FK5Str, "j2000":
+		return FK5, nil
+	case FK4Str, "b1950":
+		return FK4, nil
+	case GalacticStr:
+		return Galactic, nil
+	case EclipticStr:
+		return Ecliptic, nil
+	case EclipticOfDateStr:
+		return EclipticOfDate, nil
+	case SupergalacticStr:
+		return Supergalactic, nil
+	}
+	emsg := fmt.Sprintf("Unknown frame: %s", s)
+	return 0, errors.New(emsg)
+}
+
+// equatorial reports whether the frame's longitude is a right ascension.
+func (f Frame) equatorial() bool {
+	return f == ICRS || f == FK5 || f == FK4
+}
+
+// NewFrameCoord returns a coordinate in frame f. a1 is the RA or longitude
+// and a2 the Dec or latitude.
+func NewFrameCoord(f Frame, a1u AngleUnit, a1 float64, a2u AngleUnit, a2 float64) FrameCoord {
+	return FrameCoord{Frame: f, Ac: NewRaDecCoord(a1u, a1, a2u, a2)}
+}
+
+// NewFrameCoordA returns the coordinate ac in frame f.
+func NewFrameCoordA(f Frame, ac AngleCoord) FrameCoord {
+	return FrameCoord{Frame: f, Ac: ac}
+}
+
+// NewFrameCoordEpoch returns the coordinate ac in frame f with the equinox
+// epoch, as needed by EclipticOfDate.
+func NewFrameCoordEpoch(f Frame, ac AngleCoord, epoch time.Time) FrameCoord {
+	return FrameCoord{Frame: f, Ac: ac, Epoch: epoch}
+}
+
+// NewGalacticCoord returns a Galactic coordinate from l and b.
+func NewGalacticCoord(au AngleUnit, l, b float64) FrameCoord {
+	return NewFrameCoord(Galactic, au, l, au, b)
+}
+
+// NewEclipticCoord returns a J2000 ecliptic coordinate from lon and lat.
+func NewEclipticCoord(au AngleUnit, lon, lat float64) FrameCoord {
+	return NewFrameCoord(Ecliptic, au, lon, au, lat)
+}
+
+// NewSupergalacticCoord returns a Supergalactic coordinate from sgl and
+// sgb.
+func NewSupergalacticCoord(au AngleUnit, sgl, sgb float64) FrameCoord {
+	return NewFrameCoord(Supergalactic, au, sgl, au, sgb)
+}
+
+// Lon returns the longitude, or RA, of the coordinate.
+func (fc FrameCoord) Lon() Angle {
+	return fc.Ac.A1
+}
+
+// Lat returns the latitude, or Dec, of the coordinate.
+func (fc FrameCoord) Lat() Angle {
+	return fc.Ac.A2
+}
+
+// String returns the frame and coordinate.
+func (fc FrameCoord) String() string {
+	return fmt.Sprintf("%s %v %v", fc.Frame, fc.Ac.A1, fc.Ac.A2)
+}
+
+// poleMatrix returns the rotation onto the frame whose origin is at lon0,
+// lat0 and north pole at lonP, latP (degrees) in the source frame.
+func poleMatrix(lon0, lat0, lonP, latP float64) [3][3]float64 {
+	x := NewAzElCoord(Degree, lon0, lat0).UnitVector()
+	z := NewAzElCoord(Degree, lonP, latP).UnitVector()
+	return [3][3]float64{x, cross(z, x), z}
+}
+
+func matVec(m [3][3]float64, v [3]float64) [3]float64 {
+	return [3]float64{dot(m[0], v), dot(m[1], v), dot(m[2], v)}
+}
+
+func transpose(m [3][3]float64) [3][3]float64 {
+	var t [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			t[i][j] = m[j][i]
+		}
+	}
+	return t
+}
+
+// addETerms adds the FK4 E-terms of aberration to the unit vector v.
+func addETerms(v [3]float64) [3]float64 {
+	// iterate since the E-terms depend on the position they are added to
+	r := v
+	for i := 0; i < 3; i++ {
+		w := dot(r, fk4ETerms)
+		for idx := range r {
+			r[idx] = v[idx] + fk4ETerms[idx] - w*r[idx]
+		}
+		n := math.Sqrt(dot(r, r))
+		for idx := range r {
+			r[idx] /= n
+		}
+	}
+	return r
+}
+
+// removeETerms removes the FK4 E-terms of aberration from the unit vector
+// v.
+func removeETerms(v [3]float64) [3]float64 {
+	w := dot(v, fk4ETerms)
+	r := [3]float64{
+		v[0] - fk4ETerms[0] + w*v[0],
+		v[1] - fk4ETerms[1] + w*v[1],
+		v[2] - fk4ETerms[2] + w*v[2],
+	}
+	n := math.Sqrt(dot(r, r))
+	for idx := range r {
+		r[idx] /= n
+	}
+	return r
+}
+
+// frameMatrix returns the rotation from the ICRS to frame f, using epoch
+// as the equinox of EclipticOfDate. FK4 is not a rotation of the ICRS; its
+// matrix is that of FK5, onto which FK4 positions are mapped first.
+func frameMatrix(f Frame, epoch time.Time) ([3][3]float64, error) {
+	j2000 := at.NewJD(at.J2000, 0.0)
+	switch f {
+	case ICRS:
+		return [3][3]float64{{1.0, 0.0, 0.0}, {0.0, 1.0, 0.0}, {0.0, 0.0, 1.0}}, nil
+	case FK5, FK4:
+		return at.BiasPrecessionMatrix(j2000), nil
+	case Galactic:
+		return icrsToGalactic, nil
+	case Supergalactic:
+		return at.MatMul(galacticToSupergalactic, icrsToGalactic), nil
+	case Ecliptic:
+		return at.EclipticMatrix(j2000), nil
+	case EclipticOfDate:
+		if epoch.IsZero() {
+			return [3][3]float64{}, errors.New("Ecliptic of date needs an epoch")
+		}
+		return at.EclipticMatrix(at.JDFromTime(at.ToTT(epoch))), nil
+	}
+	emsg := fmt.Sprintf("Unknown frame: %d", f)
+	return [3][3]float64{}, errors.New(emsg)
+}
+
+// toICRS returns the ICRS unit vector of fc.
+func (fc FrameCoord) toICRS() ([3]float64, error) {
+	v := fc.Ac.UnitVector()
+	m, err := frameMatrix(fc.Frame, fc.Epoch)
+	if err != nil {
+		return v, err
+	}
+	if fc.Frame == FK4 {
+		v = matVec(fk4ToFK5, removeETerms(v))
+	}
+	return matVec(transpose(m), v), nil
+}
+
+// fromICRS returns the unit vector in frame f of the ICRS unit vector v.
+// epoch is the equinox for EclipticOfDate.
+func fromICRS(v [3]float64, f Frame, epoch time.Time) ([3]float64, error) {
+	m, err := frameMatrix(f, epoch)
+	if err != nil {
+		return v, err
+	}
+	v = matVec(m, v)
+	if f == FK4 {
+		v = addETerms(matVec(transpose(fk4ToFK5), v))
+	}
+	return v, nil
+}
+
+// Convert returns the coordinate in frame f, using epoch as the equinox
+// if f is EclipticOfDate. RA is returned in hours and everything else in
+// degrees. The FK4 conversion assumes no proper motion at B1950.
+func (fc FrameCoord) Convert(f Frame, epoch time.Time) (FrameCoord, error) {
+	v, err := fc.toICRS()
+	if err != nil {
+		return fc, err
+	}
+	v, err = fromICRS(v, f, epoch)
+	if err != nil {
+		return fc, err
+	}
+	a1u := Degree
+	if f.equatorial() {
+		a1u = Hour
+	}
+	out := FrameCoord{Frame: f, Ac: NewAngleCoordVector(v, a1u, Degree)}
+	if f == EclipticOfDate {
+		out.Epoch = epoch
+	}
+	return out, nil
+}
+
+// To returns the coordinate in frame f. An EclipticOfDate result keeps the
+// epoch of fc.
+func (fc FrameCoord) To(f Frame) (FrameCoord, error) {
+	return fc.Convert(f, fc.Epoch)
+}
+
+// ICRS returns the ICRS RA/Dec of the coordinate.
+func (fc FrameCoord) ICRS() (AngleCoord, error) {
+	c, err := fc.To(ICRS)
+	return c.Ac, err
+}
@@ -0,0 +1,103 @@
+package astrounit
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestGalactic(t *testing.T) {
+	// Galactic centre and north Galactic pole
+	gc := NewFrameCoord(ICRS, Degree, 266.4049882, Degree, -28.9361739)
+	g, err := gc.To(Galactic)
+	if err != nil {
+		fmt.Println("Galactic conversion error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, math.Mod(g.Lon().Value+180.0, 360.0)-180.0, 0.0, 2e-5, "Galactic centre l Error")
+	th.CheckFT(t, g.Lat().Value, 0.0, 2e-5, "Galactic centre b Error")
+	if g.Lon().Unit != Degree || g.Frame != Galactic {
+		fmt.Println("Galactic coordinate should be in degrees: ", g)
+		t.Fail()
+	}
+
+	ngp := NewGalacticCoord(Degree, 0.0, 90.0)
+	rd, _ := ngp.ICRS()
+	th.CheckFT(t, rd.Ra().Hour().Value, 192.85948/15.0, 1e-6, "NGP RA Error")
+	th.CheckFT(t, rd.Dec().Degree().Value, 27.12825, 1e-5, "NGP Dec Error")
+	if rd.Ra().Unit != Hour {
+		fmt.Println("ICRS RA should be in hours: ", rd)
+		t.Fail()
+	}
+}
+
+func TestSupergalactic(t *testing.T) {
+	sgp, _ := NewSupergalacticCoord(Degree, 0.0, 90.0).To(Galactic)
+	th.CheckFT(t, sgp.Lon().Value, 47.37, 1e-9, "SGP l Error")
+	th.CheckFT(t, sgp.Lat().Value, 6.32, 1e-9, "SGP b Error")
+	origin, _ := NewGalacticCoord(Degree, 137.37, 0.0).To(Supergalactic)
+	th.CheckFT(t, math.Mod(origin.Lon().Value+180.0, 360.0)-180.0, 0.0, 1e-9, "SGL origin Error")
+	th.CheckFT(t, origin.Lat().Value, 0.0, 1e-9, "SGB origin Error")
+}
+
+func TestFK4FK5(t *testing.T) {
+	// 3C 273
+	b1950 := NewFrameCoord(FK4, Hour, 12.0+26.0/60.0+33.246/3600.0, Degree, 2.0+19.0/60.0+43.53/3600.0)
+	j, _ := b1950.To(FK5)
+	th.CheckFT(t, j.Lon().Hour().Value, 12.0+29.0/60.0+6.6997/3600.0, 0.05/3600.0, "FK5 RA Error")
+	th.CheckFT(t, j.Lat().Degree().Value, 2.0+3.0/60.0+8.598/3600.0, 0.5/3600.0, "FK5 Dec Error")
+
+	back, _ := j.To(FK4)
+	th.CheckFT(t, back.Ac.Separation(b1950.Ac).ArcSecond().Value, 0.0, 1e-4, "FK4 round trip Error")
+
+	// ICRS and FK5 differ by the frame bias, tens of mas
+	i, _ := j.To(ICRS)
+	d := i.Ac.Separation(j.Ac).MilliArcSecond().Value
+	if d < 1.0 || d > 30.0 {
+		fmt.Println("Frame bias out of range [mas]: ", d)
+		t.Fail()
+	}
+}
+
+func TestEcliptic(t *testing.T) {
+	// the north ecliptic pole
+	nep := NewFrameCoord(ICRS, Hour, 18.0, Degree, 90.0-23.4392794)
+	e, _ := nep.To(Ecliptic)
+	th.CheckFT(t, e.Lat().Value, 90.0, 1e-4, "Ecliptic pole Error")
+
+	// precession carries ecliptic longitudes about 50.3 arcsec a year
+	star := NewEclipticCoord(Degree, 100.0, 10.0)
+	_, err := star.To(EclipticOfDate)
+	th.CheckErrorNil(t, err, "Expected missing epoch error")
+	epoch := time.Date(2050, 1, 1, 12, 0, 0, 0, time.UTC)
+	d, err := star.Convert(EclipticOfDate, epoch)
+	if err != nil {
+		fmt.Println("Ecliptic of date error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, d.Lon().Value-100.0, 50.29*50.0/3600.0, 0.01, "Precession Error")
+	th.CheckFT(t, d.Lat().Value, 10.0, 0.01, "Ecliptic latitude Error")
+	if !d.Epoch.Equal(epoch) {
+		fmt.Println("Ecliptic of date lost its epoch: ", d.Epoch)
+		t.Fail()
+	}
+	back, _ := d.To(Ecliptic)
+	th.CheckFT(t, back.Ac.Separation(star.Ac).ArcSecond().Value, 0.0, 1e-6, "Ecliptic round trip Error")
+}
+
+func TestParseFrame(t *testing.T) {
+	for _, f := range []Frame{ICRS, FK5, FK4, Galactic, Ecliptic, EclipticOfDate, Supergalactic} {
+		p, err := ParseFrame(f.String())
+		if err != nil || p != f {
+			fmt.Println("ParseFrame failed for ", f)
+			t.Fail()
+		}
+	}
+	p, _ := ParseFrame(" B1950")
+	th.CheckI(t, int(p), int(FK4), "B1950 Error")
+	_, err := ParseFrame("altaz")
+	th.CheckErrorNil(t, err, "Expected unknown frame error")
+}
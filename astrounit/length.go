@@ -15,16 +15,21 @@ const (
 	Femtometer
 	Decimeter
 	Kilometer
+	AstronomicalUnit
 
 	// Length unit strings
-	MeterStr      = "m"
-	CentimeterStr = "cm"
-	MillimeterStr = "mm"
-	MicrometerStr = "um"
-	NanometerStr  = "nm"
-	FemtometerStr = "fm"
-	DecimeterStr  = "dm"
-	KilometerStr  = "km"
+	MeterStr            = "m"
+	CentimeterStr       = "cm"
+	MillimeterStr       = "mm"
+	MicrometerStr       = "um"
+	NanometerStr        = "nm"
+	FemtometerStr       = "fm"
+	DecimeterStr        = "dm"
+	KilometerStr        = "km"
+	AstronomicalUnitStr = "au"
+
+	// meters per astronomical unit, IAU 2012 Resolution B2
+	MeterPerAstronomicalUnit = float64(149597870700.0)
 )
 
 // Length supports a value and associated unit
@@ -57,6 +62,8 @@ func (l Length) UnitString() string {
 		ls = FemtometerStr
 	case Kilometer:
 		ls = KilometerStr
+	case AstronomicalUnit:
+		ls = AstronomicalUnitStr
 	}
 	return ls
 }
@@ -79,6 +86,8 @@ func (l Length) Meter() Length {
 		ll.Value = l.Value * 1e2
 	case Kilometer:
 		ll.Value = l.Value * 1e3
+	case AstronomicalUnit:
+		ll.Value = l.Value * MeterPerAstronomicalUnit
 	}
 	return ll
 }
@@ -131,3 +140,10 @@ func (l Length) Kilometer() Length {
 	ll.Value = l.Meter().Value * MilliF
 	return ll
 }
+
+func (l Length) AstronomicalUnit() Length {
+	var ll Length
+	ll.Unit = AstronomicalUnit
+	ll.Value = l.Meter().Value / MeterPerAstronomicalUnit
+	return ll
+}
@@ -2,6 +2,7 @@ package astrounit
 
 import (
 	"fmt"
+	"math"
 	"testing"
 )
 
@@ -16,3 +17,20 @@ func TestNewLength(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestAstronomicalUnit(t *testing.T) {
+	l := NewLength(AstronomicalUnit, 1.0)
+	if math.Abs(l.Kilometer().Value-149597870.7) > 1e-6 {
+		fmt.Println("Fail AU to km: Expected 149597870.7, Got ", l.Kilometer().Value)
+		t.Fail()
+	}
+	if l.UnitString() != "au" {
+		fmt.Println("Fail AU UnitString: Got ", l.UnitString())
+		t.Fail()
+	}
+	ll := NewLength(Kilometer, 384400.0).AstronomicalUnit()
+	if ll.Unit != AstronomicalUnit || math.Abs(ll.Value-0.0025695552898) > 1e-12 {
+		fmt.Println("Fail km to AU: Got ", ll)
+		t.Fail()
+	}
+}
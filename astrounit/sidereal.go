@@ -0,0 +1,39 @@
+// Sidereal time as Angles
+package astrounit
+
+import (
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+)
+
+// GMST returns the Greenwich mean sidereal time at the UTC time t as an
+// Angle in Hour units.
+func GMST(t time.Time) Angle {
+	return NewAngle(Hour, at.GMST(t))
+}
+
+// GAST returns the Greenwich apparent sidereal time at the UTC time t as an
+// Angle in Hour units.
+func GAST(t time.Time) Angle {
+	return NewAngle(Hour, at.GAST(t))
+}
+
+// LST returns the local apparent sidereal time at the UTC time t for the
+// east longitude lon, as an Angle in Hour units.
+func LST(t time.Time, lon Angle) Angle {
+	return NewAngle(Hour, at.LST(t, lon.Degree().Value))
+}
+
+// LMST returns the local mean sidereal time at the UTC time t for the east
+// longitude lon, as an Angle in Hour units.
+func LMST(t time.Time, lon Angle) Angle {
+	return NewAngle(Hour, at.LMST(t, lon.Degree().Value))
+}
+
+// HourAngle returns the hour angle, in [-12h, 12h), of the right ascension
+// ra at the UTC time t for the east longitude lon, as an Angle in Hour
+// units.
+func HourAngle(t time.Time, lon, ra Angle) Angle {
+	return NewAngle(Hour, at.HourAngle(t, lon.Degree().Value, ra.Hour().Value))
+}
@@ -0,0 +1,25 @@
+package astrounit
+
+import (
+	"testing"
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestSidereal(t *testing.T) {
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	lon := NewAngle(Degree, -118.2817)
+
+	lst := LST(ti, lon)
+	th.CheckI(t, int(lst.Unit), int(Hour), "LST Unit Error")
+	th.CheckF(t, lst.Value, at.LST(ti, -118.2817), "LST Error")
+	th.CheckF(t, GMST(ti).Value, at.GMST(ti), "GMST Error")
+	th.CheckF(t, GAST(ti).Value, at.GAST(ti), "GAST Error")
+	th.CheckF(t, LMST(ti, lon.Radian()).Value, at.LMST(ti, -118.2817), "LMST Error")
+
+	ra := lst.Sub(NewAngle(Hour, 2.5))
+	ha := HourAngle(ti, lon, ra)
+	th.CheckFT(t, ha.Hour().Value, 2.5, 1e-9, "HourAngle Error")
+}
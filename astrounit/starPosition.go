@@ -0,0 +1,193 @@
+// Catalog star positions with proper motion
+package astrounit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+)
+
+const (
+	// radians per year to arcseconds per century
+	pmCentury = 100.0 * 3600.0 * 180.0 / math.Pi
+	// km/s to AU per tropical century
+	kmsToAUCentury = 21.095
+)
+
+var (
+	// FK4 B1950 to FK5 J2000 for position and velocity, the velocity in
+	// arcseconds per century (Standish 1982, Aoki et al. 1983, as used by
+	// SLALIB FK425)
+	fk425 = [6][6]float64{
+		{0.9999256782, -0.0111820611, -0.0048579477, 0.00000242395018, -0.00000002710663, -0.00000001177656},
+		{0.0111820610, 0.9999374784, -0.0000271765, 0.00000002710663, 0.00000242397878, -0.00000000006587},
+		{0.0048579479, -0.0000271474, 0.9999881997, 0.00000001177656, -0.00000000006582, 0.00000242410173},
+		{-0.000551, -0.238565, 0.435739, 0.99994704, -0.01118251, -0.00485767},
+		{0.238514, -0.002667, -0.008541, 0.01118251, 0.99995883, -0.00002718},
+		{-0.435623, 0.012254, 0.002117, 0.00485767, -0.00002714, 1.00000956},
+	}
+	// rate of change of the FK4 E-terms, arcseconds per century
+	fk4ETermRates = [3]float64{1.245e-3, -1.580e-3, -0.659e-3}
+)
+
+// StarPosition is a catalog position and space motion. Epoch is the year
+// the position is for: Julian, or Besselian for FK4. Equinox is the Julian
+// equinox of an FK5 position, J2000 if zero; FK4 positions are always for
+// the B1950 equinox. PMRA includes the cos(Dec) factor, as in Hipparcos and
+// NOVAS, and both proper motions are per year.
+type StarPosition struct {
+	Frame    Frame      `yaml:"frame" json:"frame"`
+	Equinox  float64    `yaml:"equinox" json:"equinox"`
+	Epoch    float64    `yaml:"epoch" json:"epoch"`
+	Coord    AngleCoord `yaml:"coord" json:"coord"`
+	PMRA     Angle      `yaml:"pmra" json:"pmra"`
+	PMDec    Angle      `yaml:"pmdec" json:"pmdec"`
+	Parallax Angle      `yaml:"parallax" json:"parallax"`
+	RadVel   float64    `yaml:"radVel" json:"radVel"` // km/s
+}
+
+// tangentBasis returns the unit vectors toward increasing RA and Dec at
+// the unit vector r.
+func tangentBasis(r [3]float64) ([3]float64, [3]float64) {
+	ea := [3]float64{-r[1], r[0], 0.0}
+	n := math.Hypot(r[0], r[1])
+	if n < 1e-15 {
+		// at a pole take RA = 0
+		return [3]float64{0.0, 1.0, 0.0}, [3]float64{-r[2], 0.0, 0.0}
+	}
+	for idx := range ea {
+		ea[idx] /= n
+	}
+	return ea, cross(r, ea)
+}
+
+// vectors returns the unit position vector and the proper motion vector in
+// radians per year.
+func (sp StarPosition) vectors() ([3]float64, [3]float64) {
+	r := sp.Coord.UnitVector()
+	ea, ed := tangentBasis(r)
+	pa, pd := sp.PMRA.Radian().Value, sp.PMDec.Radian().Value
+	return r, [3]float64{pa*ea[0] + pd*ed[0], pa*ea[1] + pd*ed[1], pa*ea[2] + pd*ed[2]}
+}
+
+// withVectors returns sp with the position and proper motion of r and v,
+// RA in hours, Dec in degrees and the proper motions in mas per year.
+func (sp StarPosition) withVectors(r, v [3]float64) StarPosition {
+	sp.Coord = NewAngleCoordVector(r, Hour, Degree)
+	u := sp.Coord.UnitVector()
+	ea, ed := tangentBasis(u)
+	sp.PMRA = NewAngle(Radian, dot(v, ea)).MilliArcSecond()
+	sp.PMDec = NewAngle(Radian, dot(v, ed)).MilliArcSecond()
+	sp.Parallax = sp.Parallax.MilliArcSecond()
+	return sp
+}
+
+// propagate moves r linearly along v over dy years.
+func propagate(r, v [3]float64, dy float64) [3]float64 {
+	return [3]float64{r[0] + v[0]*dy, r[1] + v[1]*dy, r[2] + v[2]*dy}
+}
+
+// fk4ToFK5 converts an FK4 B1950 position and space motion at epoch B1950
+// to FK5 J2000 at epoch J2000, removing the E-terms of aberration, as in
+// SLALIB FK425.
+func (sp StarPosition) fk4ToFK5() StarPosition {
+	r0, pm := sp.vectors()
+	px := sp.Parallax.ArcSecond().Value
+	w := kmsToAUCentury * sp.RadVel * px
+	var v1 [6]float64
+	we, wd := dot(r0, fk4ETerms), dot(r0, fk4ETermRates)
+	for idx := 0; idx < 3; idx++ {
+		v1[idx] = r0[idx] - fk4ETerms[idx] + we*r0[idx]
+		v1[idx+3] = pm[idx]*pmCentury + w*r0[idx] - fk4ETermRates[idx] + wd*r0[idx]
+	}
+	var v2 [6]float64
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 6; j++ {
+			v2[i] += fk425[i][j] * v1[j]
+		}
+	}
+	r := [3]float64{v2[0], v2[1], v2[2]}
+	rd := [3]float64{v2[3], v2[4], v2[5]}
+	n := math.Sqrt(dot(r, r))
+	out := sp
+	out.Frame, out.Equinox, out.Epoch = FK5, 2000.0, 2000.0
+	if px > 1e-30 {
+		out.RadVel = dot(r, rd) / (px * n * kmsToAUCentury)
+		out.Parallax = NewAngle(ArcSecond, px/n)
+	}
+	// the radial part of rd does not change the direction
+	u := [3]float64{r[0] / n, r[1] / n, r[2] / n}
+	ru := dot(u, rd)
+	for idx := range rd {
+		rd[idx] = (rd[idx] - ru*u[idx]) / (n * pmCentury)
+	}
+	return out.withVectors(u, rd)
+}
+
+// ICRS returns the position and proper motion in the ICRS at epoch
+// J2000.0, RA in hours, Dec in degrees and the proper motions and parallax
+// in mas. Apart from the FK4 conversion, positions are moved to J2000.0
+// linearly along the proper motion and the parallax and radial velocity
+// are carried through unchanged.
+func (sp StarPosition) ICRS() (StarPosition, error) {
+	if sp.Frame == FK4 {
+		if sp.Equinox != 0.0 && sp.Equinox != 1950.0 {
+			emsg := fmt.Sprintf("Unsupported FK4 equinox B%.1f", sp.Equinox)
+			return sp, errors.New(emsg)
+		}
+		// bring the position to epoch B1950 within FK4 first
+		r, v := sp.vectors()
+		epoch := sp.Epoch
+		if epoch == 0.0 {
+			epoch = 1950.0
+		}
+		sp = sp.withVectors(propagate(r, v, 1950.0-epoch), v)
+		sp.Frame, sp.Epoch = FK4, 1950.0
+		sp = sp.fk4ToFK5()
+	}
+	var m [3][3]float64
+	switch sp.Frame {
+	case FK5:
+		eq := sp.Equinox
+		if eq == 0.0 {
+			eq = 2000.0
+		}
+		m = at.BiasPrecessionMatrix(at.JulianEpochJD(eq))
+	case ICRS, Galactic, Supergalactic, Ecliptic:
+		var err error
+		m, err = frameMatrix(sp.Frame, time.Time{})
+		if err != nil {
+			return sp, err
+		}
+	default:
+		emsg := fmt.Sprintf("Unsupported catalog frame: %s", sp.Frame)
+		return sp, errors.New(emsg)
+	}
+	r, v := sp.vectors()
+	mt := transpose(m)
+	r, v = matVec(mt, r), matVec(mt, v)
+	epoch := sp.Epoch
+	if epoch == 0.0 {
+		epoch = 2000.0
+	}
+	out := sp
+	out.Frame, out.Equinox, out.Epoch = ICRS, 0.0, 2000.0
+	return out.withVectors(propagate(r, v, 2000.0-epoch), v), nil
+}
+
+// FK5 returns the position and proper motion in FK5 J2000 at epoch
+// J2000.0, the inverse of ICRS for FK5 positions.
+func (sp StarPosition) FK5() (StarPosition, error) {
+	i, err := sp.ICRS()
+	if err != nil {
+		return sp, err
+	}
+	m := at.BiasPrecessionMatrix(at.JulianEpochJD(2000.0))
+	r, v := i.vectors()
+	r, v = matVec(m, r), matVec(m, v)
+	i.Frame, i.Equinox = FK5, 2000.0
+	return i.withVectors(r, v), nil
+}
@@ -0,0 +1,84 @@
+package astrounit
+
+import (
+	"math"
+	"testing"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestFK4StarPosition(t *testing.T) {
+	// HD 119288 from the SAO catalog, IDL Astronomy Library jprecess example
+	rd := NewRaDecCoord(Hour, 13.0+39.0/60.0+44.526/3600.0, Degree, 8.0+38.0/60.0+28.63/3600.0)
+	cosd := math.Cos(rd.Dec().Radian().Value)
+	sp := StarPosition{
+		Frame: FK4,
+		Epoch: 1950.0,
+		Coord: rd,
+		PMRA:  NewAngle(ArcSecond, -0.0259*15.0*cosd),
+		PMDec: NewAngle(ArcSecond, -0.093),
+	}
+	j := sp.fk4ToFK5()
+	th.CheckFT(t, j.Coord.Ra().Hour().Value, 13.0+42.0/60.0+12.740/3600.0, 0.002/3600.0, "FK5 RA Error")
+	th.CheckFT(t, j.Coord.Dec().Degree().Value, 8.0+23.0/60.0+17.69/3600.0, 0.02/3600.0, "FK5 Dec Error")
+	cosd = math.Cos(j.Coord.Dec().Radian().Value)
+	th.CheckFT(t, j.PMRA.ArcSecond().Value/(15.0*cosd), -0.0257, 0.0001, "FK5 PMRA Error")
+	th.CheckFT(t, j.PMDec.ArcSecond().Value, -0.090, 0.001, "FK5 PMDec Error")
+
+	// the ICRS differs from FK5 by the frame bias only
+	i, err := sp.ICRS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, int(i.Frame), int(ICRS), "ICRS frame Error")
+	th.CheckFT(t, i.Coord.Separation(j.Coord).MilliArcSecond().Value, 15.0, 15.0, "Frame bias Error")
+
+	// a position given at a later epoch is moved back along the proper motion
+	r, v := sp.vectors()
+	later := sp.withVectors(propagate(r, v, 50.0), v)
+	later.Epoch = 2000.0
+	lj, _ := later.ICRS()
+	th.CheckFT(t, lj.Coord.Separation(i.Coord).MilliArcSecond().Value, 0.0, 50.0, "FK4 epoch Error")
+
+	sp.Equinox = 1900.0
+	_, err = sp.ICRS()
+	th.CheckErrorNil(t, err, "Expected FK4 equinox error")
+}
+
+func TestFK5StarPosition(t *testing.T) {
+	// a star moving north 1 arcsec a year observed in 2010 was 10 arcsec
+	// south in 2000
+	rd := NewRaDecCoord(Hour, 6.0, Degree, 20.0)
+	sp := StarPosition{Frame: ICRS, Epoch: 2010.0, Coord: rd, PMDec: NewAngle(ArcSecond, 1.0)}
+	i, _ := sp.ICRS()
+	th.CheckFT(t, i.Coord.Dec().Degree().Value, 20.0-10.0/3600.0, 1e-9, "Epoch propagation Error")
+	th.CheckFT(t, i.PMDec.MilliArcSecond().Value, 1000.0, 1e-5, "Proper motion Error")
+	th.CheckFT(t, i.PMRA.MilliArcSecond().Value, 0.0, 1e-6, "Proper motion Error")
+
+	// equinox of date positions are precessed back to J2000
+	fc := NewFrameCoord(FK5, Hour, 6.0, Degree, 20.0)
+	d, _ := fc.To(ICRS)
+	eq := StarPosition{Frame: FK5, Equinox: 2050.0, Epoch: 2000.0, Coord: rd}
+	p, _ := eq.ICRS()
+	sep := p.Coord.Separation(d.Ac).ArcSecond().Value
+	// at 6h precession is all in RA, m + n sin(RA) tan(Dec) with m = 46.12
+	// and n = 20.04 arcsec a year
+	dec := 20.0 * math.Pi / 180.0
+	th.CheckFT(t, sep, (46.12+20.04*math.Tan(dec))*math.Cos(dec)*50.0, 5.0, "Equinox precession Error")
+
+	_, err := StarPosition{Frame: EclipticOfDate, Coord: rd}.ICRS()
+	th.CheckErrorNil(t, err, "Expected unsupported frame error")
+
+	// FK5 undoes ICRS, proper motion included
+	fk := StarPosition{Frame: FK5, Epoch: 2000.0, Coord: rd,
+		PMRA: NewAngle(MilliArcSecond, -1093.4), PMDec: NewAngle(MilliArcSecond, -1999.4)}
+	i, _ = fk.ICRS()
+	back, err := i.FK5()
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, int(back.Frame), int(FK5), "FK5 frame Error")
+	th.CheckFT(t, back.Coord.Separation(rd).MilliArcSecond().Value, 0.0, 1e-6, "FK5 position Error")
+	th.CheckFT(t, back.PMRA.MilliArcSecond().Value, -1093.4, 1e-6, "FK5 PMRA Error")
+	th.CheckFT(t, back.PMDec.MilliArcSecond().Value, -1999.4, 1e-6, "FK5 PMDec Error")
+}
@@ -0,0 +1,287 @@
+// Sun, twilight and Moon almanac
+package ephemeris
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	gu "github.com/rh-codebase/genutilsgo"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+const (
+	// Sun and Moon elevations (degrees) defining the almanac events. Rise
+	// and set are for the upper limb with standard refraction; the
+	// positions are topocentric so the Moon's parallax is included.
+	RiseSetEl              = float64(-0.833)
+	CivilTwilightEl        = float64(-6.0)
+	NauticalTwilightEl     = float64(-12.0)
+	AstronomicalTwilightEl = float64(-18.0)
+
+	// Moon phase names
+	NewMoon        = "new"
+	WaxingCrescent = "waxing crescent"
+	FirstQuarter   = "first quarter"
+	WaxingGibbous  = "waxing gibbous"
+	FullMoon       = "full"
+	WaningGibbous  = "waning gibbous"
+	LastQuarter    = "last quarter"
+	WaningCrescent = "waning crescent"
+
+	// illuminated fraction within which a named phase is reported
+	phaseTolerance = 0.03
+)
+
+// Almanac holds the Sun and Moon events for one night at a location. The
+// night runs from local mean noon on Night to local mean noon the next
+// day. Times are UTC and zero when the event does not happen that night,
+// e.g. astronomical twilight in high latitude summers. The Moon's
+// illumination and phase are for local mean midnight.
+type Almanac struct {
+	Night            string    `yaml:"night" json:"night"`
+	Latitude         float64   `yaml:"latitude" json:"latitude"`   // degrees
+	Longitude        float64   `yaml:"longitude" json:"longitude"` // degrees, east
+	Sunset           time.Time `yaml:"sunset" json:"sunset"`
+	CivilDusk        time.Time `yaml:"civilDusk" json:"civilDusk"`
+	NauticalDusk     time.Time `yaml:"nauticalDusk" json:"nauticalDusk"`
+	AstronomicalDusk time.Time `yaml:"astronomicalDusk" json:"astronomicalDusk"`
+	AstronomicalDawn time.Time `yaml:"astronomicalDawn" json:"astronomicalDawn"`
+	NauticalDawn     time.Time `yaml:"nauticalDawn" json:"nauticalDawn"`
+	CivilDawn        time.Time `yaml:"civilDawn" json:"civilDawn"`
+	Sunrise          time.Time `yaml:"sunrise" json:"sunrise"`
+	Moonrise         time.Time `yaml:"moonrise" json:"moonrise"`
+	Moonset          time.Time `yaml:"moonset" json:"moonset"`
+	MoonIllumination float64   `yaml:"moonIllumination" json:"moonIllumination"` // fraction
+	MoonPhaseAngle   float64   `yaml:"moonPhaseAngle" json:"moonPhaseAngle"`     // degrees
+	MoonPhase        string    `yaml:"moonPhase" json:"moonPhase"`
+}
+
+// crossing is a time at which a target passes through an elevation.
+type crossing struct {
+	t      time.Time
+	rising bool
+}
+
+// crossings returns every time in [start, end] at which the elevation
+// given by f passes through limit (degrees).
+func crossings(f func(time.Time) (sample, error), start, end time.Time, limit float64) ([]crossing, error) {
+	var cs []crossing
+	above := func(s sample) bool { return s.el >= limit }
+	prev, err := f(start)
+	if err != nil {
+		return nil, err
+	}
+	for ti := start.Add(riseSetStep); ; ti = ti.Add(riseSetStep) {
+		if ti.After(end) {
+			ti = end
+		}
+		cur, err := f(ti)
+		if err != nil {
+			return nil, err
+		}
+		if above(prev) != above(cur) {
+			c, err := bisect(f, prev, cur, above)
+			if err != nil {
+				return nil, err
+			}
+			cs = append(cs, crossing{t: c.t, rising: above(cur)})
+		}
+		if !ti.Before(end) {
+			break
+		}
+		prev = cur
+	}
+	return cs, nil
+}
+
+// first returns the first crossing in the given direction, or a zero time.
+func first(cs []crossing, rising bool) time.Time {
+	for _, c := range cs {
+		if c.rising == rising {
+			return c.t
+		}
+	}
+	return time.Time{}
+}
+
+// onSurface returns the NOVAS site for l with no weather.
+func (l *Location) onSurface() nov.OnSurface {
+	var si nov.OnSurface
+	si.Latitude = l.Latitude.Degree().Value
+	si.Longitude = l.Longitude.Degree().Value
+	si.Height = l.Height.Meter().Value
+	return si
+}
+
+// siteLocation returns the Location of the NOVAS observer si.
+func siteLocation(si nov.OnSurface) Location {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, si.Latitude)
+	loc.Longitude = au.NewAngle(au.Degree, si.Longitude)
+	loc.Height = au.NewLength(au.Meter, si.Height)
+	return loc
+}
+
+// localNoon returns local mean noon, in UTC, on the calendar date of
+// 'date' at east longitude lonDeg.
+func localNoon(date time.Time, lonDeg float64) time.Time {
+	y, m, d := date.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	return noon.Add(-time.Duration(lonDeg / 15.0 * float64(time.Hour)))
+}
+
+// NewAlmanac returns the almanac for the night starting on the calendar
+// date of 'date' at loc. WithBackend selects the Backend.
+func NewAlmanac(loc Location, date time.Time, opts ...Option) (Almanac, error) {
+	var a Almanac
+	be := applyOptions(opts).backend
+	si := loc.onSurface()
+	a.Night = date.Format(time.DateOnly)
+	a.Latitude = si.Latitude
+	a.Longitude = si.Longitude
+	start := localNoon(date, si.Longitude)
+	end := start.Add(24 * time.Hour)
+
+	sun, err := resolveTarget(Sun, nil)
+	if err != nil {
+		return a, err
+	}
+	fsun := sampler(be, sun, loc, nil)
+	events := []struct {
+		el         float64
+		dusk, dawn *time.Time
+	}{
+		{RiseSetEl, &a.Sunset, &a.Sunrise},
+		{CivilTwilightEl, &a.CivilDusk, &a.CivilDawn},
+		{NauticalTwilightEl, &a.NauticalDusk, &a.NauticalDawn},
+		{AstronomicalTwilightEl, &a.AstronomicalDusk, &a.AstronomicalDawn},
+	}
+	for _, ev := range events {
+		cs, err := crossings(fsun, start, end, ev.el)
+		if err != nil {
+			return a, err
+		}
+		*ev.dusk = first(cs, false)
+		*ev.dawn = first(cs, true)
+	}
+
+	moon, err := resolveTarget(Moon, nil)
+	if err != nil {
+		return a, err
+	}
+	cs, err := crossings(sampler(be, moon, loc, nil), start, end, RiseSetEl)
+	if err != nil {
+		return a, err
+	}
+	a.Moonrise = first(cs, true)
+	a.Moonset = first(cs, false)
+
+	frac, phase, waxing, err := moonPhase(be, loc, start.Add(12*time.Hour))
+	if err != nil {
+		return a, err
+	}
+	a.MoonIllumination = frac
+	a.MoonPhaseAngle = phase
+	a.MoonPhase = moonPhaseName(frac, waxing)
+	return a, nil
+}
+
+// NewAlmanacCalendar returns the almanacs for 'nights' nights starting on
+// the calendar date of 'start'. opts are as in NewAlmanac.
+func NewAlmanacCalendar(loc Location, start time.Time, nights int, opts ...Option) ([]Almanac, error) {
+	if nights < 1 {
+		emsg := fmt.Sprintf("Invalid number of nights: %d", nights)
+		return nil, errors.New(emsg)
+	}
+	as := make([]Almanac, nights)
+	for idx := range as {
+		a, err := NewAlmanac(loc, start.AddDate(0, 0, idx), opts...)
+		if err != nil {
+			return nil, err
+		}
+		as[idx] = a
+	}
+	return as, nil
+}
+
+// moonPhase returns the Moon's illuminated fraction, phase angle in
+// degrees and whether it is waxing as seen from loc at ti, computed by be.
+func moonPhase(be Backend, loc Location, ti time.Time) (float64, float64, bool, error) {
+	sun, err := resolveTarget(Sun, nil)
+	if err != nil {
+		return 0.0, 0.0, false, err
+	}
+	moon, err := resolveTarget(Moon, nil)
+	if err != nil {
+		return 0.0, 0.0, false, err
+	}
+	ep := NewInstant(ti)
+	ras, decs, diss, err := sun.topo(be, ep, loc)
+	if err != nil {
+		return 0.0, 0.0, false, err
+	}
+	ram, decm, dism, err := moon.topo(be, ep, loc)
+	if err != nil {
+		return 0.0, 0.0, false, err
+	}
+	rad := math.Pi / 180.0
+	dra := (ras - ram) * 15.0 * rad
+	cosPsi := math.Sin(decs*rad)*math.Sin(decm*rad) +
+		math.Cos(decs*rad)*math.Cos(decm*rad)*math.Cos(dra)
+	psi := math.Acos(math.Max(-1.0, math.Min(1.0, cosPsi)))
+	// phase angle, Sun-Moon-observer
+	i := math.Atan2(diss*math.Sin(psi), dism-diss*math.Cos(psi))
+	frac := (1.0 + math.Cos(i)) / 2.0
+	// the Moon is east of the Sun while waxing
+	waxing := math.Mod(ram-ras+24.0, 24.0) < 12.0
+	return frac, i / rad, waxing, nil
+}
+
+// moonPhaseName names the phase for the illuminated fraction frac.
+func moonPhaseName(frac float64, waxing bool) string {
+	switch {
+	case frac < phaseTolerance:
+		return NewMoon
+	case frac > 1.0-phaseTolerance:
+		return FullMoon
+	case math.Abs(frac-0.5) < phaseTolerance && waxing:
+		return FirstQuarter
+	case math.Abs(frac-0.5) < phaseTolerance:
+		return LastQuarter
+	case frac < 0.5 && waxing:
+		return WaxingCrescent
+	case frac < 0.5:
+		return WaningCrescent
+	case waxing:
+		return WaxingGibbous
+	default:
+		return WaningGibbous
+	}
+}
+
+// MoonIllumination returns the illuminated fraction of the Moon, its phase
+// angle and whether it is waxing, as seen from loc at t. opts are as in
+// NewAlmanac.
+func MoonIllumination(loc Location, t time.Time, opts ...Option) (float64, au.Angle, bool, error) {
+	frac, phase, waxing, err := moonPhase(applyOptions(opts).backend, loc, t)
+	return frac, au.NewAngle(au.Degree, phase), waxing, err
+}
+
+// WriteYaml writes the almanac to the YAML file fn.
+func (a *Almanac) WriteYaml(fn string) error {
+	return gu.WriteYaml(fn, a)
+}
+
+// WriteJSON writes the almanac to the JSON file fn.
+func (a *Almanac) WriteJSON(fn string) error {
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fn, b, 0644)
+}
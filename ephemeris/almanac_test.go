@@ -0,0 +1,115 @@
+package ephemeris
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+func TestAlmanac(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	a, err := NewAlmanac(loc, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		fmt.Println("NewAlmanac error: ", err)
+		t.Fail()
+		return
+	}
+	th.CheckS(t, a.Night, "2025-04-01", "Night Error")
+	order := []time.Time{a.Sunset, a.CivilDusk, a.NauticalDusk, a.AstronomicalDusk,
+		a.AstronomicalDawn, a.NauticalDawn, a.CivilDawn, a.Sunrise}
+	for idx := 1; idx < len(order); idx++ {
+		if !order[idx].After(order[idx-1]) {
+			fmt.Println("Almanac events out of order: ", a)
+			t.Fail()
+		}
+	}
+	// sunset at OVRO is about 02:15 UTC in early April
+	if a.Sunset.Before(time.Date(2025, 4, 2, 1, 45, 0, 0, time.UTC)) ||
+		a.Sunset.After(time.Date(2025, 4, 2, 2, 45, 0, 0, time.UTC)) {
+		fmt.Println("Sunset Error: ", a.Sunset)
+		t.Fail()
+	}
+	var si nov.OnSurface
+	nov.MakeOnSurface(37.2339, -118.282, 1222., 0.0, 0.0, &si)
+	sun, _ := SimpleTrack(si, Sun, nil)
+	for ti, el := range map[time.Time]float64{a.Sunset: RiseSetEl, a.CivilDawn: CivilTwilightEl,
+		a.NauticalDusk: NauticalTwilightEl, a.AstronomicalDawn: AstronomicalTwilightEl} {
+		_, sel, _ := sun(ti)
+		th.CheckFT(t, sel, el, 0.01, "Sun elevation Error")
+	}
+	moon, _ := SimpleTrack(si, Moon, nil)
+	for _, ti := range []time.Time{a.Moonrise, a.Moonset} {
+		if ti.IsZero() {
+			continue
+		}
+		_, mel, _ := moon(ti)
+		th.CheckFT(t, mel, RiseSetEl, 0.01, "Moon elevation Error")
+	}
+	// three days after new moon
+	if a.MoonIllumination < 0.05 || a.MoonIllumination > 0.35 || a.MoonPhase != WaxingCrescent {
+		fmt.Println("Moon phase Error: ", a.MoonIllumination, a.MoonPhase)
+		t.Fail()
+	}
+
+	// full moon on 2025-04-13
+	frac, phase, waxing, err := MoonIllumination(loc, time.Date(2025, 4, 13, 0, 22, 0, 0, time.UTC))
+	if err != nil || frac < 0.99 || phase.Degree().Value > 12.0 {
+		fmt.Println("Full moon Error: ", frac, phase.Degree().Value, waxing, err)
+		t.Fail()
+	}
+	th.CheckS(t, moonPhaseName(0.5, false), LastQuarter, "Phase name Error")
+}
+
+func TestAlmanacCalendar(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	as, err := NewAlmanacCalendar(loc, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), 3)
+	if err != nil || len(as) != 3 {
+		fmt.Println("NewAlmanacCalendar error: ", err)
+		t.Fail()
+		return
+	}
+	th.CheckS(t, as[2].Night, "2025-04-03", "Night Error")
+	if !(as[1].MoonIllumination > as[0].MoonIllumination) {
+		fmt.Println("Moon should be waxing: ", as[0].MoonIllumination, as[1].MoonIllumination)
+		t.Fail()
+	}
+
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "almanac.json")
+	err = as[0].WriteJSON(fn)
+	if err != nil {
+		fmt.Println("WriteJSON error: ", err)
+		t.Fail()
+	}
+	b, _ := os.ReadFile(fn)
+	var a Almanac
+	err = json.Unmarshal(b, &a)
+	if err != nil || !a.Sunset.Equal(as[0].Sunset) {
+		fmt.Println("JSON round trip Error: ", err)
+		t.Fail()
+	}
+	fn = filepath.Join(dir, "almanac.yml")
+	err = as[0].WriteYaml(fn)
+	if err != nil {
+		fmt.Println("WriteYaml error: ", err)
+		t.Fail()
+	}
+	var ay Almanac
+	err = th.ReadYaml(fn, &ay)
+	if err != nil || !ay.Sunrise.Equal(as[0].Sunrise) || ay.MoonPhase != as[0].MoonPhase {
+		fmt.Println("YAML round trip Error: ", err)
+		t.Fail()
+	}
+}
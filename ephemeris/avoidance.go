@@ -0,0 +1,288 @@
+// Sun, Moon and planet avoidance
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+const (
+	// scan step when looking for avoidance zone entries and exits
+	avoidanceStep = 5 * time.Minute
+)
+
+// AvoidanceZone is a circle of Radius around a body (Sun, Moon, a planet
+// or a serialized RaDec) which must not be pointed at.
+type AvoidanceZone struct {
+	Body   string   `yaml:"body" json:"body"`
+	Radius au.Angle `yaml:"radius" json:"radius"`
+}
+
+// Interval is a span of time.
+type Interval struct {
+	Body  string    `yaml:"body" json:"body"`
+	Start time.Time `yaml:"start" json:"start"`
+	End   time.Time `yaml:"end" json:"end"`
+}
+
+// SeparationSample is the separation of two targets at a time.
+type SeparationSample struct {
+	Time       time.Time `yaml:"time" json:"time"`
+	Separation au.Angle  `yaml:"separation" json:"separation"`
+}
+
+// TrackViolation is a point, or the closest point of a segment, of an
+// az/el track inside an avoidance zone.
+type TrackViolation struct {
+	Body       string    `yaml:"body" json:"body"`
+	Time       time.Time `yaml:"time" json:"time"`
+	Separation au.Angle  `yaml:"separation" json:"separation"`
+}
+
+// separationSampler returns a function giving the topocentric separation
+// of tg and body from loc, computed by be.
+func separationSampler(be Backend, tg, body target, loc Location) func(time.Time) (sample, error) {
+	return func(ti time.Time) (sample, error) {
+		s := sample{t: ti}
+		ep := NewInstant(ti)
+		ra1, dec1, _, err := tg.topo(be, ep, loc)
+		if err != nil {
+			return s, err
+		}
+		ra2, dec2, _, err := body.topo(be, ep, loc)
+		if err != nil {
+			return s, err
+		}
+		c1 := au.NewRaDecCoord(au.Hour, ra1, au.Degree, dec1)
+		c2 := au.NewRaDecCoord(au.Hour, ra2, au.Degree, dec2)
+		s.sep = c1.Separation(c2).Degree().Value
+		return s, nil
+	}
+}
+
+// resolveBody resolves an avoidance body, which may be a planet or a
+// serialized RaDec.
+func resolveBody(body string) (target, error) {
+	return resolveTarget(body, nil)
+}
+
+// Separation returns the topocentric angular separation between the
+// source and body at t.
+func (e *Ephemeris) Separation(body string, t time.Time) (au.Angle, error) {
+	ss, err := e.SeparationSeries(body, t, t, time.Second)
+	if err != nil {
+		return au.Angle{}, err
+	}
+	return ss[0].Separation, nil
+}
+
+// SeparationSeries returns the topocentric angular separation between the
+// source and body every step from start to end.
+func (e *Ephemeris) SeparationSeries(body string, start, end time.Time, step time.Duration) ([]SeparationSample, error) {
+	if step <= 0 {
+		return nil, errors.New("Step must be positive")
+	}
+	f, err := e.separationSampler(body)
+	if err != nil {
+		return nil, err
+	}
+	var ss []SeparationSample
+	for ti := start; !ti.After(end); ti = ti.Add(step) {
+		s, err := f(ti)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, SeparationSample{Time: ti, Separation: au.NewAngle(au.Degree, s.sep)})
+	}
+	return ss, nil
+}
+
+// separationSampler returns the separation sampler between the source and
+// body.
+func (e *Ephemeris) separationSampler(body string) (func(time.Time) (sample, error), error) {
+	b, err := resolveBody(body)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.hasTarget {
+		return nil, errors.New("Ephemeris has no source")
+	}
+	return separationSampler(e.backend, e.target, b, e.location), nil
+}
+
+// ForbiddenIntervals returns the intervals between start and end when the
+// source is inside any of the avoidance zones, ordered by start time. The
+// separation is sampled every 5 minutes and around each closest approach
+// between samples, so brief passes through a zone are found too.
+func (e *Ephemeris) ForbiddenIntervals(zones []AvoidanceZone, start, end time.Time) ([]Interval, error) {
+	if end.Before(start) {
+		emsg := fmt.Sprintf("End %v is before start %v", end, start)
+		return nil, errors.New(emsg)
+	}
+	var ivs []Interval
+	for _, z := range zones {
+		f, err := e.separationSampler(z.Body)
+		if err != nil {
+			return nil, err
+		}
+		r := z.Radius.Degree().Value
+		inside := func(s sample) bool { return s.sep < r }
+		prev, err := f(start)
+		if err != nil {
+			return nil, err
+		}
+		var iv *Interval
+		if inside(prev) {
+			iv = &Interval{Body: z.Body, Start: start}
+		}
+		// the sample before prev, once there is one
+		var before sample
+		for ti := start.Add(avoidanceStep); ; ti = ti.Add(avoidanceStep) {
+			if ti.After(end) {
+				ti = end
+			}
+			cur, err := f(ti)
+			if err != nil {
+				return nil, err
+			}
+			if !before.t.IsZero() && !inside(prev) && !inside(cur) && prev.sep < before.sep && prev.sep <= cur.sep {
+				// the closest approach is near prev, look for a pass
+				// through the zone shorter than the step
+				closest, err := minSeparation(f, before, cur)
+				if err != nil {
+					return nil, err
+				}
+				if inside(closest) {
+					in, err := bisect(f, before, closest, inside)
+					if err != nil {
+						return nil, err
+					}
+					out, err := bisect(f, closest, cur, inside)
+					if err != nil {
+						return nil, err
+					}
+					ivs = append(ivs, Interval{Body: z.Body, Start: in.t, End: out.t})
+				}
+			}
+			if inside(prev) != inside(cur) {
+				c, err := bisect(f, prev, cur, inside)
+				if err != nil {
+					return nil, err
+				}
+				if inside(cur) {
+					iv = &Interval{Body: z.Body, Start: c.t}
+				} else {
+					iv.End = c.t
+					ivs = append(ivs, *iv)
+					iv = nil
+				}
+			}
+			if !ti.Before(end) {
+				break
+			}
+			before, prev = prev, cur
+		}
+		if iv != nil {
+			iv.End = end
+			ivs = append(ivs, *iv)
+		}
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].Start.Before(ivs[j].Start) })
+	return ivs, nil
+}
+
+// minSeparation returns the closest approach between a and b by golden
+// section search, to riseSetResolution.
+func minSeparation(f func(time.Time) (sample, error), a, b sample) (sample, error) {
+	const g = 0.6180339887498949
+	lo, hi := a.t, b.t
+	c := hi.Add(-time.Duration(g * float64(hi.Sub(lo))))
+	d := lo.Add(time.Duration(g * float64(hi.Sub(lo))))
+	sc, err := f(c)
+	if err != nil {
+		return sc, err
+	}
+	sd, err := f(d)
+	if err != nil {
+		return sd, err
+	}
+	for hi.Sub(lo) > riseSetResolution {
+		if sc.sep < sd.sep {
+			hi, d, sd = d, c, sc
+			c = hi.Add(-time.Duration(g * float64(hi.Sub(lo))))
+			sc, err = f(c)
+		} else {
+			lo, c, sc = c, d, sd
+			d = lo.Add(time.Duration(g * float64(hi.Sub(lo))))
+			sd, err = f(d)
+		}
+		if err != nil {
+			return a, err
+		}
+	}
+	if sc.sep < sd.sep {
+		return sc, nil
+	}
+	return sd, nil
+}
+
+// CheckTrack reports where an az/el track from loc passes inside any of
+// the avoidance zones. Each point is checked, as is the great circle
+// segment between consecutive points outside the zone against the body's
+// position at the middle of the segment. Body positions are unrefracted,
+// so the track should be too. An empty result means the track is clear.
+// WithBackend selects the Backend.
+func CheckTrack(loc Location, track []au.AngleCoordEpoch, zones []AvoidanceZone, opts ...Option) ([]TrackViolation, error) {
+	be := applyOptions(opts).backend
+	var vs []TrackViolation
+	for _, z := range zones {
+		b, err := resolveBody(z.Body)
+		if err != nil {
+			return nil, err
+		}
+		r := z.Radius.Degree().Value
+		bodyAt := func(ti time.Time) (au.AngleCoord, error) {
+			ep := NewInstant(ti)
+			ra, dec, _, err := b.topo(be, ep, loc)
+			if err != nil {
+				return au.AngleCoord{}, err
+			}
+			az, zd := be.Horizon(ep, loc, ra, dec)
+			return au.NewAzElCoord(au.Degree, az, 90.0-zd), nil
+		}
+		prevInside := false
+		for idx, p := range track {
+			bc, err := bodyAt(p.Epoch)
+			if err != nil {
+				return nil, err
+			}
+			if d := p.Ac.Separation(bc).Degree().Value; d < r {
+				vs = append(vs, TrackViolation{Body: z.Body, Time: p.Epoch, Separation: au.NewAngle(au.Degree, d)})
+				prevInside = true
+				continue
+			}
+			// segments touching a point already reported are not
+			// reported again
+			if idx == 0 || prevInside {
+				prevInside = false
+				continue
+			}
+			prev := track[idx-1]
+			mid := prev.Epoch.Add(p.Epoch.Sub(prev.Epoch) / 2)
+			bm, err := bodyAt(mid)
+			if err != nil {
+				return nil, err
+			}
+			if d := bm.ArcDistance(prev.Ac, p.Ac).Degree().Value; d < r {
+				vs = append(vs, TrackViolation{Body: z.Body, Time: mid, Separation: au.NewAngle(au.Degree, d)})
+			}
+		}
+	}
+	return vs, nil
+}
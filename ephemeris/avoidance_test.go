@@ -0,0 +1,169 @@
+package ephemeris
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestForbiddenIntervals(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+
+	// a source on the Sun's path a couple of days out
+	sun, _ := NewEphemeris(Sun, loc, nil)
+	sun.SetTime(time.Date(2025, 4, 3, 12, 0, 0, 0, time.UTC))
+	rd, _ := sun.GetRaDec()
+	src := fmt.Sprintf("ra: %f\ndec: %f", rd.Ra().Hour().Value, rd.Dec().Degree().Value)
+	e, err := NewEphemeris(src, loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(5 * 24 * time.Hour)
+	zones := []AvoidanceZone{
+		{Sun, au.NewAngle(au.Degree, 1.0)},
+		{Moon, au.NewAngle(au.Degree, 0.5)},
+	}
+	ivs, err := e.ForbiddenIntervals(zones, start, end)
+	if err != nil || len(ivs) != 1 || ivs[0].Body != Sun {
+		fmt.Println("ForbiddenIntervals Error: ", ivs, err)
+		t.Fail()
+		return
+	}
+	iv := ivs[0]
+	if !iv.Start.After(start) || !iv.End.Before(end) {
+		fmt.Println("Interval should be inside the window: ", iv)
+		t.Fail()
+	}
+	for _, ti := range []time.Time{iv.Start, iv.End} {
+		sep, err := e.Separation(Sun, ti)
+		if err != nil {
+			fmt.Println("Separation error: ", err)
+			t.Fail()
+		}
+		th.CheckFT(t, sep.Degree().Value, 1.0, 1e-3, "Boundary separation Error")
+	}
+	ss, err := e.SeparationSeries(Sun, start, end, 24*time.Hour)
+	if err != nil || len(ss) != 6 {
+		fmt.Println("SeparationSeries Error: ", len(ss), err)
+		t.Fail()
+		return
+	}
+	// the Sun moves about a degree a day
+	th.CheckFT(t, ss[0].Separation.Degree().Value, 2.5, 0.5, "Separation Error")
+	mid := iv.Start.Add(iv.End.Sub(iv.Start) / 2)
+	sep, _ := e.Separation(Sun, mid)
+	if sep.Degree().Value > 1.0 {
+		fmt.Println("Interval middle should be inside: ", sep.Degree().Value)
+		t.Fail()
+	}
+}
+
+// a grazing pass through a zone between two scan samples
+func TestForbiddenIntervalsShort(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+
+	// a source on the Moon's path
+	moon, _ := NewEphemeris(Moon, loc, nil)
+	moon.SetTime(time.Date(2025, 4, 5, 6, 0, 0, 0, time.UTC))
+	rd, _ := moon.GetRaDec()
+	src := fmt.Sprintf("ra: %f\ndec: %f", rd.Ra().Hour().Value, rd.Dec().Degree().Value)
+	e, err := NewEphemeris(src, loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	start := time.Date(2025, 4, 5, 0, 0, 0, 0, time.UTC)
+	end := start.Add(12 * time.Hour)
+	ss, err := e.SeparationSeries(Moon, start, end, 10*time.Second)
+	if err != nil {
+		fmt.Println("SeparationSeries error: ", err)
+		t.Fail()
+		return
+	}
+	closest := ss[0].Separation.Degree().Value
+	for _, s := range ss {
+		closest = min(closest, s.Separation.Degree().Value)
+	}
+	r := closest + 0.001
+	var inside time.Duration
+	for _, s := range ss {
+		if s.Separation.Degree().Value < r {
+			inside += 10 * time.Second
+		}
+	}
+	if inside == 0 || inside >= avoidanceStep {
+		fmt.Println("Pass should be shorter than the scan step: ", inside)
+		t.Fail()
+	}
+
+	ivs, err := e.ForbiddenIntervals([]AvoidanceZone{{Moon, au.NewAngle(au.Degree, r)}}, start, end)
+	if err != nil || len(ivs) != 1 {
+		fmt.Println("ForbiddenIntervals short Error: ", ivs, err)
+		t.Fail()
+		return
+	}
+	th.CheckFT(t, ivs[0].End.Sub(ivs[0].Start).Seconds(), inside.Seconds(), 20.0, "Short interval length Error")
+	for _, ti := range []time.Time{ivs[0].Start, ivs[0].End} {
+		sep, _ := e.Separation(Moon, ti)
+		th.CheckFT(t, sep.Degree().Value, r, 1e-4, "Short interval boundary Error")
+	}
+}
+
+func TestCheckTrack(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	ti := time.Date(2025, 4, 1, 19, 0, 0, 0, time.UTC)
+	sun, _ := NewEphemeris(Sun, loc, nil)
+	sun.SetTime(ti)
+	ae, _ := sun.GetAzEl()
+	az, el := ae.Az().Degree().Value, ae.El().Degree().Value
+	zones := []AvoidanceZone{{Sun, au.NewAngle(au.Degree, 5.0)}}
+
+	// straight through the Sun
+	track := []au.AngleCoordEpoch{
+		au.NewAzElCoordEpoch(au.Degree, az-20.0, el, ti.Add(-time.Minute)),
+		au.NewAzElCoordEpoch(au.Degree, az, el, ti),
+		au.NewAzElCoordEpoch(au.Degree, az+20.0, el, ti.Add(time.Minute)),
+	}
+	vs, err := CheckTrack(loc, track, zones)
+	if err != nil || len(vs) != 1 || !vs[0].Time.Equal(ti) || vs[0].Separation.Degree().Value > 0.1 {
+		fmt.Println("CheckTrack Error: ", vs, err)
+		t.Fail()
+	}
+
+	// endpoints clear but the slew between them is not
+	track = []au.AngleCoordEpoch{
+		au.NewAzElCoordEpoch(au.Degree, az-10.0, el, ti.Add(-time.Second)),
+		au.NewAzElCoordEpoch(au.Degree, az+10.0, el, ti.Add(time.Second)),
+	}
+	vs, err = CheckTrack(loc, track, zones)
+	if err != nil || len(vs) != 1 || !vs[0].Time.Equal(ti) {
+		fmt.Println("CheckTrack segment Error: ", vs, err)
+		t.Fail()
+	}
+
+	// opposite side of the sky
+	track = []au.AngleCoordEpoch{
+		au.NewAzElCoordEpoch(au.Degree, az+180.0, 45.0, ti),
+		au.NewAzElCoordEpoch(au.Degree, az+170.0, 50.0, ti.Add(time.Minute)),
+	}
+	vs, err = CheckTrack(loc, track, zones)
+	if err != nil || len(vs) != 0 {
+		fmt.Println("CheckTrack clear Error: ", vs, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,211 @@
+// Ephemeris backends
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+// Backend computes the places of stars and major solar system bodies. An
+// Ephemeris uses the backend it was constructed with; the package level
+// functions use NOVASBackend unless given WithBackend. Positions are in
+// the true equator and equinox of date, RA in hours, Dec in degrees,
+// distances in AU.
+type Backend interface {
+	// Name identifies the backend.
+	Name() string
+	// Apparent returns the geocentric apparent place of b at in and its
+	// distance, 0 for stars.
+	Apparent(in Instant, b Body) (ra, dec, dis float64, err error)
+	// Topocentric returns the topocentric apparent place of b at in as seen
+	// from loc and its distance, 0 for stars.
+	Topocentric(in Instant, b Body, loc Location) (ra, dec, dis float64, err error)
+	// Horizon converts the topocentric place ra, dec at in to azimuth and
+	// zenith distance in degrees at loc, including polar motion. No
+	// refraction is applied.
+	Horizon(in Instant, loc Location, ra, dec float64) (az, zd float64)
+}
+
+// Body is a source a Backend places: a major solar system body by NOVAS
+// number, 1-9 Mercury to Pluto, 10 the Sun and 11 the Moon, or, when
+// Number is 0, the star Star, with its proper motion in RA on the sky.
+type Body struct {
+	Name   string
+	Number int16
+	Star   StarInfo
+}
+
+// defaultBackend is used by the package level functions unless
+// WithBackend is given.
+var defaultBackend Backend = novasBackend{}
+
+// Option configures a package level function.
+type Option func(*options)
+
+type options struct {
+	backend Backend
+}
+
+// WithBackend computes positions with be in place of NOVASBackend().
+func WithBackend(be Backend) Option {
+	return func(o *options) {
+		o.backend = be
+	}
+}
+
+// applyOptions returns the defaults as changed by opts.
+func applyOptions(opts []Option) options {
+	o := options{backend: defaultBackend}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// novasBackend calls NOVAS, serialized by novasMu.
+type novasBackend struct{}
+
+// NOVASBackend returns the Backend calling the NOVAS library. NOVAS reads
+// only its own ephemeris file, so while an ephemeris is set with
+// SetPlanetaryEphemeris the Sun, Moon and planets are placed by
+// NewGoBackend() instead, as Name reports.
+func NOVASBackend() Backend {
+	return novasBackend{}
+}
+
+// Name is "novas", or "novas, planets go <ephemeris>" while planets are
+// placed by the Go backend.
+func (novasBackend) Name() string {
+	if e := PlanetaryEphemeris(); e != nil {
+		return "novas, planets go " + e.Name
+	}
+	return "novas"
+}
+
+// catEntry returns the NOVAS catalog entry of the star s.
+func catEntry(s StarInfo) nov.CatEntry {
+	var ce nov.CatEntry
+	nov.MakeCatEntry(s.Name, s.Catalog, s.StarNum, s.Ra_hr, s.Dec_deg,
+		s.PMRA_masPerYr, s.PMDEC_masPerYr, s.Parallax_mas, s.RadVel_kmPerSec, &ce)
+	return ce
+}
+
+// setPoleOffsets sets the NOVAS celestial pole offsets from the EOP of
+// in. The caller must hold novasMu.
+func setPoleOffsets(in Instant) {
+	ddpsi, ddeps := in.EOP.PoleOffsets(instantTT(in))
+	nov.PSI_COR = ddpsi / at.ArcsecondToRadian
+	nov.EPS_COR = ddeps / at.ArcsecondToRadian
+}
+
+// planetObject returns the NOVAS object of the major body b.
+func planetObject(b Body) nov.Object {
+	var obj nov.Object
+	var none nov.CatEntry
+	nov.MakeObject(0, b.Number, b.Name, &none, &obj)
+	return obj
+}
+
+// poleSite is the distance of the poles from the geocenter, AU, on the
+// NOVAS ellipsoid.
+const poleSite = nov.ERAD / 1000.0 * (1.0 - nov.F) / nov.AU_KM
+
+// fromPole returns the geocentric apparent direction and distance of obj
+// at in from its topocentric place seen from a pole, north if sign is 1
+// and south if -1, where the observer does not move with the rotation of
+// the Earth. The place is taken again at the time that makes the light
+// time the one from the geocenter. The caller must hold novasMu.
+func fromPole(in Instant, obj *nov.Object, sign float64) (u [3]float64, dis float64, err error) {
+	var pole nov.OnSurface
+	nov.MakeOnSurface(90.0*sign, 0.0, 0.0, 0.0, 0.0, &pole)
+	jd := in.JDTT
+	for idx := 0; idx < 2; idx++ {
+		var ra, dec, d float64
+		err = nov.TopoPlanet(jd, obj, in.DeltaT, &pole, accuracy, &ra, &dec, &d)
+		if err != nil {
+			return u, dis, err
+		}
+		sr, cr := math.Sincos(ra * math.Pi / 12.0)
+		sd, cd := math.Sincos(dec * math.Pi / 180.0)
+		g := [3]float64{d * cd * cr, d * cd * sr, d*sd + sign*poleSite}
+		dis = norm(g)
+		for k := range u {
+			u[k] = g[k] / dis
+		}
+		jd = in.JDTT + (d-dis)/cAUPerDay
+	}
+	return u, dis, nil
+}
+
+// geocentricPlanet returns the geocentric apparent place of the solar
+// system body obj at in, which novasgo has no function for, as the mean of
+// the places from the two poles, in which the light deflection by the
+// Earth cancels. The caller must hold novasMu.
+func geocentricPlanet(in Instant, obj *nov.Object) (ra, dec, dis float64, err error) {
+	un, dn, err := fromPole(in, obj, 1.0)
+	if err != nil {
+		return ra, dec, dis, err
+	}
+	us, ds, err := fromPole(in, obj, -1.0)
+	if err != nil {
+		return ra, dec, dis, err
+	}
+	ra, dec, _ = vectorRaDec([3]float64{un[0] + us[0], un[1] + us[1], un[2] + us[2]})
+	return ra, dec, (dn + ds) / 2.0, nil
+}
+
+// Apparent uses AppStar for stars and TopoPlanet, from the poles, for
+// solar system bodies.
+func (be novasBackend) Apparent(in Instant, b Body) (ra, dec, dis float64, err error) {
+	if b.Number != 0 && PlanetaryEphemeris() != nil {
+		return NewGoBackend().Apparent(in, b)
+	}
+	novasMu.Lock()
+	defer novasMu.Unlock()
+	setPoleOffsets(in)
+	if b.Number == 0 {
+		if rc := nov.AppStar(in.JDTT, catEntry(b.Star), accuracy, &ra, &dec); rc != 0 {
+			emsg := fmt.Sprintf("NOVAS AppStar failed for %s: error %d", b.Name, rc)
+			return ra, dec, dis, errors.New(emsg)
+		}
+		return ra, dec, 0.0, nil
+	}
+	obj := planetObject(b)
+	return geocentricPlanet(in, &obj)
+}
+
+// Topocentric places planets with NewGoBackend() when an ephemeris is set
+// with SetPlanetaryEphemeris, as NOVAS reads only its own file.
+func (novasBackend) Topocentric(in Instant, b Body, loc Location) (ra, dec, dis float64, err error) {
+	if PlanetaryEphemeris() != nil && b.Number != 0 {
+		return NewGoBackend().Topocentric(in, b, loc)
+	}
+	si := loc.onSurface()
+	novasMu.Lock()
+	defer novasMu.Unlock()
+	setPoleOffsets(in)
+	if b.Number == 0 {
+		star := catEntry(b.Star)
+		err = nov.TopoStar(in.JDTT, in.DeltaT, &star, &si, accuracy, &ra, &dec)
+		return ra, dec, 0.0, err
+	}
+	obj := planetObject(b)
+	err = nov.TopoPlanet(in.JDTT, &obj, in.DeltaT, &si, accuracy, &ra, &dec, &dis)
+	return ra, dec, dis, err
+}
+
+func (novasBackend) Horizon(in Instant, loc Location, ra, dec float64) (az, zd float64) {
+	var rar, decr float64
+	doRefraction := int16(0)
+	si := loc.onSurface()
+	novasMu.Lock()
+	defer novasMu.Unlock()
+	setPoleOffsets(in)
+	nov.Equ2hor(in.JDUT1, in.DeltaT, accuracy, in.EOP.Xp, in.EOP.Yp, &si, ra, dec,
+		doRefraction, &zd, &az, &rar, &decr)
+	return az, zd
+}
@@ -0,0 +1,261 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+// checkPlace compares RA (hours) and Dec (degrees) to within tol arcsec.
+func checkPlace(t *testing.T, ra, dec, expRa, expDec, tol float64, msg string) {
+	dra := math.Remainder(ra-expRa, 24.0) * 54000.0 * math.Cos(expDec*math.Pi/180.0)
+	th.CheckFT(t, dra, 0.0, tol, msg+" RA Error")
+	th.CheckFT(t, (dec-expDec)*3600.0, 0.0, tol, msg+" Dec Error")
+}
+
+// novasFileBackend returns the Go backend reading the ephemeris file NOVAS
+// reads, so that the places differ only by the algorithm.
+func novasFileBackend(t *testing.T) Backend {
+	de, err := OpenJPLEphemeris("JPLEPH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { de.Close() })
+	return &goBackend{eph: de}
+}
+
+func TestGoBackend(t *testing.T) {
+	gb := novasFileBackend(t)
+	nb := NOVASBackend()
+	th.CheckS(t, NewGoBackend().Name(), "go", "Go backend name Error")
+	th.CheckS(t, gb.Name(), "go DE405", "Go backend file name Error")
+	th.CheckS(t, nb.Name(), "novas", "NOVAS backend name Error")
+
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	alpboo := StarInfo{Name: "alpboo", Catalog: "BSC", StarNum: 1, Ra_hr: 14.26103, Dec_deg: 19.18241,
+		PMRA_masPerYr: -1093.39, PMDEC_masPerYr: -2000.06, Parallax_mas: 88.83, RadVel_kmPerSec: -5.19}
+	bodies := []Body{{Name: "alpboo", Star: alpboo}}
+	for n := int16(1); n <= 11; n++ {
+		if n != 3 {
+			bodies = append(bodies, Body{Name: fmt.Sprintf("body %d", n), Number: n})
+		}
+	}
+	// the same ephemeris, IAU 2000B against the 2000A nutation of NOVAS
+	const tol = 0.003 // arcsec
+	for _, ti := range []time.Time{
+		time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC),
+		time.Date(1990, 7, 1, 18, 0, 0, 0, time.UTC),
+		time.Date(1960, 2, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(2045, 1, 10, 0, 0, 0, 0, time.UTC),
+	} {
+		in := NewInstant(ti)
+		for _, b := range bodies {
+			msg := fmt.Sprintf("%v %s", ti, b.Name)
+			ra1, dec1, dis1, err := nb.Topocentric(in, b, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ra2, dec2, dis2, err := gb.Topocentric(in, b, loc)
+			if err != nil {
+				fmt.Println("Topocentric error: ", err)
+				t.Fail()
+				continue
+			}
+			checkPlace(t, ra2, dec2, ra1, dec1, tol, msg+" topocentric")
+			th.CheckFT(t, dis2, dis1, 1e-11, msg+" topocentric distance Error")
+
+			ra1, dec1, dis1, err = nb.Apparent(in, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ra2, dec2, dis2, err = gb.Apparent(in, b)
+			if err != nil {
+				fmt.Println("Apparent error: ", err)
+				t.Fail()
+				continue
+			}
+			checkPlace(t, ra2, dec2, ra1, dec1, tol, msg+" apparent")
+			th.CheckFT(t, dis2, dis1, 1e-11, msg+" apparent distance Error")
+
+			az1, zd1 := nb.Horizon(in, loc, ra1, dec1)
+			az2, zd2 := gb.Horizon(in, loc, ra1, dec1)
+			th.CheckFT(t, math.Remainder(az2-az1, 360.0)*3600.0*math.Sin(zd1*math.Pi/180.0), 0.0, tol, msg+" Az Error")
+			th.CheckFT(t, (zd2-zd1)*3600.0, 0.0, tol, msg+" ZD Error")
+		}
+	}
+
+	// the Moon's horizontal parallax is about a degree at most
+	in := NewInstant(time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC))
+	moon := Body{Name: Moon, Number: 11}
+	ra1, dec1, _, _ := gb.Apparent(in, moon)
+	ra2, dec2, _, _ := gb.Topocentric(in, moon, loc)
+	sep := au.NewRaDecCoord(au.Hour, ra1, au.Degree, dec1).Separation(au.NewRaDecCoord(au.Hour, ra2, au.Degree, dec2))
+	if sep.Degree().Value < 0.1 || sep.Degree().Value > 1.1 {
+		fmt.Println("Unexpected lunar parallax: ", sep.Degree().Value)
+		t.Fail()
+	}
+
+	_, _, _, err := gb.Apparent(in, Body{Name: Earth, Number: 3})
+	th.CheckErrorNil(t, err, "Apparent expected error for the Earth")
+	_, _, _, err = gb.Apparent(in, Body{Name: "bad", Number: 12})
+	th.CheckErrorNil(t, err, "Apparent expected error for a bad body")
+	_, _, _, err = gb.Apparent(NewInstant(time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)), moon)
+	th.CheckErrorNil(t, err, "Apparent expected error outside the ephemeris")
+}
+
+func TestEphemerisWithBackend(t *testing.T) {
+	bsc := DefaultBSC()
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	for _, src := range []string{"alpboo", Jupiter, Moon} {
+		e1, err := NewEphemeris(src, loc, &bsc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		th.CheckS(t, e1.Backend().Name(), "novas", "Default backend Error")
+		e2, err := NewEphemerisWithBackend(src, loc, &bsc, novasFileBackend(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e1.SetTime(ti)
+		e2.SetTime(ti)
+		azel1, err := e1.GetAzEl()
+		if err != nil {
+			t.Fatal(err)
+		}
+		azel2, err := e2.GetAzEl()
+		if err != nil {
+			fmt.Println("GetAzEl error: ", err)
+			t.Fail()
+			continue
+		}
+		th.CheckFT(t, azel2.Az().Degree().Value, azel1.Az().Degree().Value, 1e-6, src+" Az Error")
+		th.CheckFT(t, azel2.El().Degree().Value, azel1.El().Degree().Value, 1e-6, src+" El Error")
+	}
+}
+
+func TestGAST(t *testing.T) {
+	for _, ti := range []time.Time{
+		time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC),
+		time.Date(1999, 12, 31, 23, 59, 0, 0, time.UTC),
+		time.Date(2040, 8, 15, 13, 30, 0, 0, time.UTC),
+	} {
+		in := NewInstant(ti)
+		var gst float64
+		novasMu.Lock()
+		setPoleOffsets(in)
+		nov.SiderealTime(in.JDUT1, 0.0, in.DeltaT, 1, 1, accuracy, &gst)
+		novasMu.Unlock()
+		// 1 mas of time
+		th.CheckFT(t, gast(in)*12.0/math.Pi, gst, 1e-3/3600.0/15.0, ti.String()+" GAST Error")
+	}
+}
+
+// fixedHorizon is a Backend putting every source at az 123, el 45.
+type fixedHorizon struct {
+	Backend
+}
+
+func (fixedHorizon) Horizon(in Instant, loc Location, ra, dec float64) (az, zd float64) {
+	return 123.0, 45.0
+}
+
+func TestWithBackend(t *testing.T) {
+	bsc := DefaultBSC()
+	loc, _ := Site("OVRO")
+	si := loc.onSurface()
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	be := fixedHorizon{NOVASBackend()}
+
+	track, err := SimpleTrack(si, "alpboo", &bsc, WithBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	az, el, _ := track(ti)
+	th.CheckFT(t, az, 123.0, 1e-12, "SimpleTrack backend Az Error")
+	th.CheckFT(t, el, 45.0, 1e-12, "SimpleTrack backend El Error")
+
+	e, err := NewEphemeris("alpboo", loc, &bsc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetBackend(be)
+	e.SetTime(ti)
+	azel, _ := e.GetAzEl()
+	th.CheckFT(t, azel.Az().Degree().Value, 123.0, 1e-12, "SetBackend Az Error")
+
+	ci := NewCatalogIndex(&bsc)
+	ci.SetBackend(be)
+	ms, err := ci.BoxAzEl(loc, ti, au.NewAngle(au.Degree, 120.0), au.NewAngle(au.Degree, 125.0),
+		au.NewAngle(au.Degree, 40.0), au.NewAngle(au.Degree, 50.0), BrighterThan(2.0))
+	if err != nil || len(ms) == 0 {
+		fmt.Println("BoxAzEl with a backend found nothing: ", err)
+		t.Fail()
+	}
+
+	// the Go backend gives the same almanac as NOVAS
+	a1, err := NewAlmanac(loc, ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := NewAlmanac(loc, ti, WithBackend(novasFileBackend(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckFT(t, a2.Sunset.Sub(a1.Sunset).Seconds(), 0.0, 1.0, "Go backend sunset Error")
+	th.CheckFT(t, a2.MoonIllumination, a1.MoonIllumination, 1e-6, "Go backend illumination Error")
+}
+
+func TestPoleOffsets(t *testing.T) {
+	loc, _ := Site("OVRO")
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	at.SetEOPTable(nil)
+	in0 := NewInstant(ti)
+	at.SetEOPTable(at.NewEOPTable([]at.EOP{
+		{MJD: 60765, DX: 50.0, DY: -30.0},
+		{MJD: 60766, DX: 50.0, DY: -30.0},
+		{MJD: 60767, DX: 50.0, DY: -30.0},
+	}))
+	defer at.SetEOPTable(nil)
+	in := NewInstant(ti)
+	gb := novasFileBackend(t)
+	nb := NOVASBackend()
+	for _, b := range []Body{
+		{Name: "alpboo", Star: StarInfo{Name: "alpboo", Catalog: "BSC", Ra_hr: 14.26103, Dec_deg: 19.18241}},
+		{Name: Jupiter, Number: 5},
+	} {
+		ra0, dec0, _, _ := nb.Topocentric(in0, b, loc)
+		ra1, dec1, _, err := nb.Topocentric(in, b, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the pole offsets move the place by tens of mas
+		sep := au.NewRaDecCoord(au.Hour, ra0, au.Degree, dec0).Separation(au.NewRaDecCoord(au.Hour, ra1, au.Degree, dec1))
+		if sep.ArcSecond().Value < 0.02 || sep.ArcSecond().Value > 0.2 {
+			fmt.Println("Unexpected pole offset shift (arcsec): ", b.Name, sep.ArcSecond().Value)
+			t.Fail()
+		}
+		// and the same in both backends
+		ra2, dec2, _, err := gb.Topocentric(in, b, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPlace(t, ra2, dec2, ra1, dec1, 0.003, b.Name+" pole offset")
+		az1, zd1 := nb.Horizon(in, loc, ra1, dec1)
+		az2, zd2 := gb.Horizon(in, loc, ra1, dec1)
+		th.CheckFT(t, math.Remainder(az2-az1, 360.0)*3600.0*math.Sin(zd1*math.Pi/180.0), 0.0, 0.003, b.Name+" pole offset Az Error")
+		th.CheckFT(t, (zd2-zd1)*3600.0, 0.0, 0.003, b.Name+" pole offset ZD Error")
+	}
+}
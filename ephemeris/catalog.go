@@ -4,28 +4,95 @@ package ephemeris
 import (
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	au "github.com/rh-codebase/astrogo/astrounit"
-	gu "github.com/rh-codebase/genutilsgo"
+	"gopkg.in/yaml.v2"
 )
 
+// bSCstr is a source as written in a catalog file. Everything after
+// Magnitude is optional.
 type bSCstr struct {
-	Epoch     string  `yaml:"Epoch"`
-	RA_hms    string  `yaml:"RA_hms"`
-	DEC_dms   string  `yaml:"DEC_dms"`
-	PMRA_hms  string  `yaml:"PMRA_hms"`
-	PMDEC_dms string  `yaml:"PMDEC_dms"`
-	Magnitude float64 `yaml:"Magnitude"`
+	Epoch        string        `yaml:"Epoch"`
+	RA_hms       string        `yaml:"RA_hms"`
+	DEC_dms      string        `yaml:"DEC_dms"`
+	PMRA_hms     string        `yaml:"PMRA_hms"`
+	PMDEC_dms    string        `yaml:"PMDEC_dms"`
+	Magnitude    float64       `yaml:"Magnitude"`
+	Parallax_mas float64       `yaml:"Parallax_mas,omitempty"`
+	RadVel_kms   float64       `yaml:"RadVel_kms,omitempty"`
+	VLSR_kms     float64       `yaml:"VLSR_kms,omitempty"`
+	SpectralType string        `yaml:"SpectralType,omitempty"`
+	Flux         []FluxDensity `yaml:"Flux,omitempty"`
+	HR           int           `yaml:"HR,omitempty"`
+	Bayer        string        `yaml:"Bayer,omitempty"`
+	CommonName   string        `yaml:"CommonName,omitempty"`
+	Aliases      []string      `yaml:"Aliases,omitempty"`
+	Tags         []string      `yaml:"Tags,omitempty"`
 }
 
+// FluxDensity is a radio flux density measurement.
+type FluxDensity struct {
+	Freq_GHz float64 `yaml:"Freq_GHz" json:"freqGHz"`
+	Flux_Jy  float64 `yaml:"Flux_Jy" json:"fluxJy"`
+}
+
+// BSCdata is a catalog source. Epoch is as given in the catalog file, but
+// whatever the epoch and equinox, RA and DEC are converted to ICRS at
+// epoch J2000.0 on load, and Frame records that. PMRA is the proper
+// motion in RA on the sky, including the cos(DEC) factor, as in the BSC5
+// and Hipparcos catalogs.
+// The source can be looked up by Name, its HR number ("HR 5340"), Bayer
+// designation, common name or any of its Aliases. Provenance records the
+// catalog, file and line it was read from.
 type BSCdata struct {
-	Epoch     string
-	RA        au.Angle
-	DEC       au.Angle
-	PMRA      au.Angle
-	PMDEC     au.Angle
-	Magnitude float64 `yaml:"Magnitude"`
+	Provenance
+	Name         string
+	Epoch        string
+	Frame        string
+	RA           au.Angle
+	DEC          au.Angle
+	PMRA         au.Angle
+	PMDEC        au.Angle
+	Parallax     au.Angle
+	RadVel       float64 // km/s, heliocentric
+	VLSR         float64 // km/s
+	SpectralType string
+	Flux         []FluxDensity
+	HR           int
+	Bayer        string
+	CommonName   string
+	Aliases      []string
+	Tags         []string
+	Magnitude    float64 `yaml:"Magnitude"`
+}
+
+// Provenance is where a catalog source was read from. Catalog is the name
+// the catalog was added under, empty if the file was read directly.
+type Provenance struct {
+	Catalog string
+	File    string
+	Line    int
+}
+
+// String returns the provenance as catalog (file:line).
+func (p Provenance) String() string {
+	return fmt.Sprintf("%s (%s:%d)", p.Catalog, p.File, p.Line)
+}
+
+// Duplicate is a source found in more than one catalog. Used is where
+// the source returned by unqualified lookups came from, and Shadowed where
+// the others, still found with catalog:source lookups, came from.
+type Duplicate struct {
+	Name     string
+	Used     Provenance
+	Shadowed []Provenance
 }
 
 type bSCs map[string]bSCstr
@@ -40,24 +107,132 @@ type CatalogSource struct {
 type Catalogs []CatalogSource
 
 var (
-	cats Catalogs
+	// sources in different catalogs closer than this are reported by
+	// Duplicates
+	duplicateSep = au.NewAngle(au.ArcSecond, 3.0)
+
+	// catalogsMu guards cats, catalogOrder, sourceCats and formats, which
+	// may be changed while a CatalogManager reloads or sources are looked
+	// up.
+	catalogsMu sync.RWMutex
+	cats       Catalogs
+	// catalog names in search order, set with SetCatalogOrder
+	catalogOrder []string
+	// catalogs sources are pinned to with SetSourceCat
+	sourceCats = make(map[string]string)
 )
 
 func AddCatalog(cat CatalogSource) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
 	cats = append(cats, cat)
 }
 
+// ClearCatalogs removes every catalog, along with the search order and the
+// sources pinned to catalogs.
 func ClearCatalogs() {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
 	cats = nil
+	catalogOrder = nil
+	formats = make(map[string]catalogFormat)
+	sourceCats = make(map[string]string)
+}
+
+// SetCatalogOrder sets the order catalogs are searched in, by name. A
+// source in more than one catalog is taken from the first of them; the
+// others can still be reached with catalog:source lookups. Catalogs not
+// named follow in the order they were added, which is also the default.
+// The order applies from the next LoadCatalogs.
+func SetCatalogOrder(names ...string) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalogOrder = append([]string(nil), names...)
+}
+
+// catalogsInOrder returns the added catalogs in search order.
+func catalogsInOrder() Catalogs {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	rank := func(c CatalogSource) int {
+		for idx, n := range catalogOrder {
+			if strings.EqualFold(n, c.Name) {
+				return idx
+			}
+		}
+		return len(catalogOrder)
+	}
+	ordered := append(Catalogs(nil), cats...)
+	sort.SliceStable(ordered, func(i, j int) bool { return rank(ordered[i]) < rank(ordered[j]) })
+	return ordered
+}
+
+// SetSourceCat pins sourceName to the catalog catalogName, so that it is
+// always looked up there whatever the search order. An empty catalogName
+// removes the pin.
+func SetSourceCat(sourceName, catalogName string) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	if catalogName == "" {
+		delete(sourceCats, normalizeName(sourceName))
+		return
+	}
+	sourceCats[normalizeName(sourceName)] = catalogName
 }
 
+// qualifiedKey returns the key of a source that is shadowed by one of the
+// same name in a catalog earlier in the search order.
+func qualifiedKey(catalog, name string) string {
+	return strings.ToLower(catalog + ":" + name)
+}
+
+// LoadCatalogs reads every added catalog into bsc in search order. A
+// source already in bsc from another catalog is kept, and the new one is
+// stored under catalog:source; see Duplicates.
 func (bsc *BSC) LoadCatalogs() error {
+	return bsc.loadCatalogs(catalogFiles())
+}
+
+// catalogFile is an added catalog along with its format, or the error
+// finding it.
+type catalogFile struct {
+	CatalogSource
+	format catalogFormat
+	err    error
+}
+
+// catalogFiles returns the added catalogs in search order with their
+// formats.
+func catalogFiles() []catalogFile {
+	var cfs []catalogFile
+	for _, c := range catalogsInOrder() {
+		cf, err := catalogFormatOf(c.Filename)
+		cfs = append(cfs, catalogFile{CatalogSource: c, format: cf, err: err})
+	}
+	return cfs
+}
+
+// loadCatalogs reads the catalogs cfs into bsc in order.
+func (bsc *BSC) loadCatalogs(cfs []catalogFile) error {
+	defer bsc.forgetNames()
 	var emsg string
-	for _, c := range cats {
-		err := bsc.ReadYaml(c.Filename)
+	for _, c := range cfs {
+		cb := make(BSC)
+		err := c.err
+		if err == nil {
+			err = cb.readCatalogAs(c.CatalogSource, c.format)
+		}
 		if err != nil {
-			msg := fmt.Sprintf("Could not load catalog %s at %s, ", c.Name, c.Filename)
+			msg := fmt.Sprintf("Could not load catalog %s at %s: %v, ", c.Name, c.Filename, err)
 			emsg += msg
+			continue
+		}
+		for k, d := range cb {
+			d.Catalog = c.Name
+			if prev, ok := (*bsc)[k]; ok && prev.Catalog != c.Name {
+				k = qualifiedKey(c.Name, k)
+			}
+			(*bsc)[k] = d
 		}
 	}
 	if len(emsg) != 0 {
@@ -66,17 +241,223 @@ func (bsc *BSC) LoadCatalogs() error {
 	return nil
 }
 
+// Duplicates returns the sources loaded from more than one catalog, sorted
+// by name. Sources in different catalogs are taken to be the same if they
+// share a name, alias or HR number, or lie within duplicateSep of each
+// other. Used is the source not shadowed by another catalog, the first by
+// key if there are several, as GetSource resolves a shared alias.
+func (bsc *BSC) Duplicates() []Duplicate {
+	keys := make([]string, 0, len(*bsc))
+	for k := range *bsc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// union-find of the sources, by index into keys
+	parent := make([]int, len(keys))
+	for idx := range parent {
+		parent[idx] = idx
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	join := func(i, j int) {
+		if (*bsc)[keys[i]].Catalog != (*bsc)[keys[j]].Catalog {
+			parent[root(j)] = root(i)
+		}
+	}
+
+	byName := make(map[string][]int)
+	for idx, k := range keys {
+		d := (*bsc)[k]
+		for _, n := range d.names() {
+			if n = normalizeName(n); n != "" {
+				byName[n] = append(byName[n], idx)
+			}
+		}
+	}
+	for _, idxs := range byName {
+		for _, j := range idxs[1:] {
+			join(idxs[0], j)
+		}
+	}
+	// sweep in declination for sources close on the sky
+	sep := duplicateSep.Radian().Value
+	byDec := make([]int, len(keys))
+	for idx := range byDec {
+		byDec[idx] = idx
+	}
+	dec := func(i int) float64 { return (*bsc)[keys[i]].DEC.Radian().Value }
+	sort.Slice(byDec, func(i, j int) bool { return dec(byDec[i]) < dec(byDec[j]) })
+	for a, i := range byDec {
+		di := (*bsc)[keys[i]]
+		ci := au.NewRaDecCoordA(di.RA, di.DEC)
+		for _, j := range byDec[a+1:] {
+			if dec(j)-dec(i) > sep {
+				break
+			}
+			dj := (*bsc)[keys[j]]
+			if ci.Separation(au.NewRaDecCoordA(dj.RA, dj.DEC)).Radian().Value < sep {
+				join(i, j)
+			}
+		}
+	}
+
+	groups := make(map[int][]int)
+	for idx := range keys {
+		r := root(idx)
+		groups[r] = append(groups[r], idx)
+	}
+	shadowed := func(k string) bool { return strings.Contains(k, ":") }
+	var res []Duplicate
+	for _, idxs := range groups {
+		if len(idxs) < 2 {
+			continue
+		}
+		// keys are sorted, so the first unshadowed one is used
+		used := idxs[0]
+		for _, idx := range idxs {
+			if !shadowed(keys[idx]) {
+				used = idx
+				break
+			}
+		}
+		d := (*bsc)[keys[used]]
+		dup := Duplicate{Name: d.Name, Used: d.Provenance}
+		for _, idx := range idxs {
+			if idx != used {
+				dup.Shadowed = append(dup.Shadowed, (*bsc)[keys[idx]].Provenance)
+			}
+		}
+		sort.SliceStable(dup.Shadowed, func(i, j int) bool { return dup.Shadowed[i].Catalog < dup.Shadowed[j].Catalog })
+		res = append(res, dup)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+const (
+	// Frame of BSCdata once loaded
+	ICRSFrame = "ICRS"
+)
+
+// ParseCatalogEpoch returns the frame, Julian equinox and epoch (year) of
+// a catalog Epoch string: "J2000" or "FK5" (the default when empty), a
+// Julian epoch such as "J2015.5" for FK5 positions of that equinox and
+// epoch, "B1950" or "FK4", or "ICRS" optionally followed by the Julian
+// epoch of the positions, e.g. "ICRS J2016.0".
+func ParseCatalogEpoch(epoch string) (au.Frame, float64, float64, error) {
+	s := strings.ToUpper(strings.TrimSpace(epoch))
+	year := func(y string) (float64, error) {
+		v, err := strconv.ParseFloat(y, 64)
+		if err != nil {
+			emsg := fmt.Sprintf("Invalid catalog epoch: %s", epoch)
+			return 0.0, errors.New(emsg)
+		}
+		return v, nil
+	}
+	switch {
+	case s == "" || s == "FK5":
+		return au.FK5, 2000.0, 2000.0, nil
+	case s == "FK4":
+		return au.FK4, 1950.0, 1950.0, nil
+	case strings.HasPrefix(s, "ICRS"):
+		ep := strings.TrimSpace(strings.TrimPrefix(s, "ICRS"))
+		if ep == "" {
+			return au.ICRS, 0.0, 2000.0, nil
+		}
+		y, err := year(strings.TrimPrefix(ep, "J"))
+		return au.ICRS, 0.0, y, err
+	case strings.HasPrefix(s, "J"):
+		y, err := year(s[1:])
+		return au.FK5, y, y, err
+	case strings.HasPrefix(s, "B"):
+		y, err := year(s[1:])
+		if err == nil && y != 1950.0 {
+			emsg := fmt.Sprintf("Unsupported catalog epoch %s, only B1950 FK4 is supported", epoch)
+			return au.FK4, y, y, errors.New(emsg)
+		}
+		return au.FK4, y, y, err
+	}
+	emsg := fmt.Sprintf("Invalid catalog epoch: %s", epoch)
+	return 0, 0.0, 0.0, errors.New(emsg)
+}
+
+// starPosition returns the position and space motion of bscd as given in
+// frame f, Julian equinox eq and epoch ep.
+func (bscd *BSCdata) starPosition(f au.Frame, eq, ep float64) au.StarPosition {
+	return au.StarPosition{
+		Frame:    f,
+		Equinox:  eq,
+		Epoch:    ep,
+		Coord:    au.NewRaDecCoordA(bscd.RA, bscd.DEC),
+		PMRA:     bscd.PMRA,
+		PMDec:    bscd.PMDEC,
+		Parallax: bscd.Parallax,
+		RadVel:   bscd.RadVel,
+	}
+}
+
+// toICRS converts bscd from its catalog epoch to ICRS at J2000.0.
+func (bscd *BSCdata) toICRS() error {
+	f, eq, ep, err := ParseCatalogEpoch(bscd.Epoch)
+	if err != nil {
+		return err
+	}
+	icrs, err := bscd.starPosition(f, eq, ep).ICRS()
+	if err != nil {
+		return err
+	}
+	bscd.Frame = ICRSFrame
+	bscd.RA = icrs.Coord.Ra()
+	bscd.DEC = icrs.Coord.Dec()
+	bscd.PMRA = icrs.PMRA.Hour()
+	bscd.PMDEC = icrs.PMDec.ArcSecond()
+	bscd.Parallax = icrs.Parallax
+	bscd.RadVel = icrs.RadVel
+	return nil
+}
+
+// yamlKeyLines returns the line numbers of the top level keys of the YAML
+// document buf, which yaml.v2 does not keep.
+func yamlKeyLines(buf []byte) map[string]int {
+	lines := make(map[string]int)
+	for idx, l := range strings.Split(string(buf), "\n") {
+		if l == "" || strings.ContainsAny(l[:1], " \t#-") {
+			continue
+		}
+		if k, _, ok := strings.Cut(l, ":"); ok {
+			lines[strings.Trim(strings.TrimSpace(k), "\"'")] = idx + 1
+		}
+	}
+	return lines
+}
+
 func (bsc *BSC) ReadYaml(fn string) error {
-	bscs := make(bSCs)
-	err := gu.ReadYaml(fn, &bscs)
+	buf, err := os.ReadFile(fn)
 	if err != nil {
 		return err
 	}
+	return bsc.parseYaml(buf, fn)
+}
+
+// parseYaml adds the sources of the YAML catalog buf, read from fn.
+func (bsc *BSC) parseYaml(buf []byte, fn string) error {
+	defer bsc.forgetNames()
+	bscs := make(bSCs)
+	err := yaml.UnmarshalStrict(buf, &bscs)
+	if err != nil {
+		emsg := fmt.Sprintf("Error unmarshalling %s: %v", fn, err)
+		return errors.New(emsg)
+	}
+	lines := yamlKeyLines(buf)
 
 	// convert bscs to BSC
 	for k, v := range bscs {
 		var bscd BSCdata
-		bscd.Epoch = v.Epoch
 		valh, err := au.NewHMS(v.RA_hms)
 		if err != nil {
 			return err
@@ -89,32 +470,256 @@ func (bsc *BSC) ReadYaml(fn string) error {
 		}
 		bscd.DEC = vald.Angle()
 
-		valh, err = au.NewHMS(v.PMRA_hms)
-		if err != nil {
-			return err
+		// proper motions are optional, e.g. for radio sources
+		bscd.PMRA = au.NewAngle(au.Hour, 0.0)
+		if v.PMRA_hms != "" {
+			valh, err = au.NewHMS(v.PMRA_hms)
+			if err != nil {
+				return err
+			}
+			bscd.PMRA = valh.Angle()
 		}
-		bscd.PMRA = valh.Angle()
 
-		vald, err = au.NewDMS(v.PMDEC_dms)
-		if err != nil {
-			return err
+		bscd.PMDEC = au.NewAngle(au.Degree, 0.0)
+		if v.PMDEC_dms != "" {
+			vald, err = au.NewDMS(v.PMDEC_dms)
+			if err != nil {
+				return err
+			}
+			bscd.PMDEC = vald.Angle()
 		}
-		bscd.PMDEC = vald.Angle()
 
 		bscd.Magnitude = v.Magnitude
+		bscd.Name = k
+		bscd.File = fn
+		bscd.Line = lines[k]
+		bscd.Parallax = au.NewAngle(au.MilliArcSecond, v.Parallax_mas)
+		bscd.RadVel = v.RadVel_kms
+		bscd.VLSR = v.VLSR_kms
+		bscd.SpectralType = v.SpectralType
+		bscd.Flux = v.Flux
+		bscd.HR = v.HR
+		bscd.Bayer = v.Bayer
+		bscd.CommonName = v.CommonName
+		bscd.Aliases = v.Aliases
+		bscd.Tags = v.Tags
+
+		bscd.Epoch = v.Epoch
+		err = bscd.toICRS()
+		if err != nil {
+			emsg := fmt.Sprintf("Source %s: %v", k, err)
+			return errors.New(emsg)
+		}
 
 		(*bsc)[strings.ToLower(k)] = bscd
 	}
 	return nil
 }
 
+// normalizeName folds case and drops white space so that "HR 5340",
+// "hr5340" and "Hr 5340" all match.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "")
+}
+
+// names returns every name the source may be looked up by.
+func (d *BSCdata) names() []string {
+	ns := []string{d.Name, d.Bayer, d.CommonName}
+	if d.HR > 0 {
+		ns = append(ns, fmt.Sprintf("HR%d", d.HR))
+	}
+	return append(ns, d.Aliases...)
+}
+
+// HasName reports whether name is the source's name or one of its aliases.
+func (d *BSCdata) HasName(name string) bool {
+	n := normalizeName(name)
+	for _, a := range d.names() {
+		if a != "" && normalizeName(a) == n {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTag reports whether the source carries tag, ignoring case.
+func (d *BSCdata) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// FluxAt returns the flux density in Jy at freqGHz, interpolating between,
+// or extrapolating from, the nearest measurements as a power law. It
+// returns false if the source has no flux measurements.
+func (d *BSCdata) FluxAt(freqGHz float64) (float64, bool) {
+	if len(d.Flux) == 0 {
+		return 0.0, false
+	}
+	fs := append([]FluxDensity(nil), d.Flux...)
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Freq_GHz < fs[j].Freq_GHz })
+	if len(fs) == 1 {
+		return fs[0].Flux_Jy, true
+	}
+	idx := sort.Search(len(fs), func(i int) bool { return fs[i].Freq_GHz >= freqGHz })
+	if idx == 0 {
+		idx = 1
+	} else if idx == len(fs) {
+		idx = len(fs) - 1
+	}
+	a, b := fs[idx-1], fs[idx]
+	if a.Flux_Jy <= 0.0 || b.Flux_Jy <= 0.0 || a.Freq_GHz <= 0.0 || a.Freq_GHz == b.Freq_GHz {
+		return a.Flux_Jy, true
+	}
+	alpha := math.Log(b.Flux_Jy/a.Flux_Jy) / math.Log(b.Freq_GHz/a.Freq_GHz)
+	return a.Flux_Jy * math.Pow(freqGHz/a.Freq_GHz, alpha), true
+}
+
+// nameIndex maps the normalized names of the sources of a BSC to their
+// keys, so that looking a source up by an alias does not scan the BSC.
+type nameIndex struct {
+	// the BSC indexed, held so that its map is not reused for another
+	bsc BSC
+	// len(bsc) when indexed; a BSC with sources added or removed since is
+	// indexed again
+	size int
+	keys map[string][]string
+}
+
+const (
+	// number of BSCs whose name indexes are kept
+	maxNameIndexes = 4
+)
+
+var (
+	// nameIndexesMu guards nameIndexes
+	nameIndexesMu sync.Mutex
+	// nameIndexes holds the indexes of the BSCs last looked up by alias,
+	// the most recent last
+	nameIndexes []*nameIndex
+)
+
+// sameBSC reports whether a and b are the same map.
+func sameBSC(a, b BSC) bool {
+	return reflect.ValueOf(a).UnsafePointer() == reflect.ValueOf(b).UnsafePointer()
+}
+
+// forgetNames drops the name index of bsc, after its sources have been
+// changed.
+func (bsc *BSC) forgetNames() {
+	nameIndexesMu.Lock()
+	defer nameIndexesMu.Unlock()
+	for idx, ni := range nameIndexes {
+		if sameBSC(ni.bsc, *bsc) {
+			nameIndexes = append(nameIndexes[:idx], nameIndexes[idx+1:]...)
+			return
+		}
+	}
+}
+
+// keysNamed returns the keys of the sources having the name or alias src,
+// as HasName, from the index of the BSC, built on first use and again
+// when sources have been added or removed.
+func (bsc *BSC) keysNamed(src string) []string {
+	nameIndexesMu.Lock()
+	var ni *nameIndex
+	for idx, n := range nameIndexes {
+		if sameBSC(n.bsc, *bsc) {
+			nameIndexes = append(nameIndexes[:idx], nameIndexes[idx+1:]...)
+			if n.size == len(*bsc) {
+				ni = n
+			}
+			break
+		}
+	}
+	if ni == nil {
+		ni = &nameIndex{bsc: *bsc, size: len(*bsc), keys: make(map[string][]string)}
+		for k, d := range *bsc {
+			for _, a := range d.names() {
+				if a != "" {
+					n := normalizeName(a)
+					ni.keys[n] = append(ni.keys[n], k)
+				}
+			}
+		}
+	}
+	if len(nameIndexes) == maxNameIndexes {
+		nameIndexes = nameIndexes[1:]
+	}
+	nameIndexes = append(nameIndexes, ni)
+	keys := ni.keys[normalizeName(src)]
+	nameIndexesMu.Unlock()
+
+	// a source changed in place may no longer have the name
+	var found []string
+	for _, k := range keys {
+		if d, ok := (*bsc)[k]; ok && d.HasName(src) {
+			found = append(found, k)
+		}
+	}
+	return found
+}
+
+// GetSource returns the source src, looked up by its name or any of its
+// aliases, ignoring case and white space. src may be qualified with the
+// name of the catalog to take it from, as in "BSC:AlpBoo", and sources
+// pinned with SetSourceCat are always taken from their catalog. Aliases
+// are looked up in an index of the BSC built on first use, so a source
+// given new names in place is found by them only once sources have been
+// added, removed or read into the BSC.
 func (bsc *BSC) GetSource(src string) (BSCdata, error) {
+	catalogsMu.RLock()
+	cat, ok := sourceCats[normalizeName(src)]
+	catalogsMu.RUnlock()
+	if ok {
+		return bsc.getCatalogSource(cat, src)
+	}
 	src = strings.ToLower(src)
-	if star, ok := (*bsc)[src]; !ok {
-		emsg := fmt.Sprintf("Source %s not found in catalog", src)
+	if star, ok := (*bsc)[src]; ok {
+		return star, nil
+	}
+	if idx := strings.Index(src, ":"); idx > 0 {
+		if star, err := bsc.getCatalogSource(src[:idx], src[idx+1:]); err == nil {
+			return star, nil
+		}
+	}
+	// an alias shared by several sources resolves to the one not shadowed
+	// by another catalog, then the first by key
+	var found string
+	shadowed := func(k string) bool { return strings.Contains(k, ":") }
+	for _, k := range bsc.keysNamed(src) {
+		if found == "" || shadowed(found) && !shadowed(k) || shadowed(found) == shadowed(k) && k < found {
+			found = k
+		}
+	}
+	if found != "" {
+		return (*bsc)[found], nil
+	}
+	emsg := fmt.Sprintf("Source %s not found in catalog", src)
+	return BSCdata{}, errors.New(emsg)
+}
 
-		return star, errors.New(emsg)
-	} else {
+// getCatalogSource returns the source src from the catalog named cat.
+func (bsc *BSC) getCatalogSource(cat, src string) (BSCdata, error) {
+	src = strings.ToLower(src)
+	if star, ok := (*bsc)[src]; ok && strings.EqualFold(star.Catalog, cat) {
 		return star, nil
 	}
+	if star, ok := (*bsc)[qualifiedKey(cat, src)]; ok {
+		return star, nil
+	}
+	var found string
+	for _, k := range bsc.keysNamed(src) {
+		if strings.EqualFold((*bsc)[k].Catalog, cat) && (found == "" || k < found) {
+			found = k
+		}
+	}
+	if found != "" {
+		return (*bsc)[found], nil
+	}
+	emsg := fmt.Sprintf("Source %s not found in catalog %s", src, cat)
+	return BSCdata{}, errors.New(emsg)
 }
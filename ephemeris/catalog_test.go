@@ -2,21 +2,19 @@ package ephemeris
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	au "github.com/rh-codebase/astrogo/astrounit"
 	th "github.com/rh-codebase/genutilsgo"
 )
 
 func TestReadCatalog(t *testing.T) {
 
-	bsc := make(BSC)
-
-	err := bsc.ReadYaml("brightSourceCatalog.yml")
-	if err != nil {
-		fmt.Println("ReadYML returned err: ", err)
-		t.Fail()
-	}
+	bsc := DefaultBSC()
 	for k, v := range bsc {
 		fmt.Printf("Source: %s, DEC: %s, RA: %s, Mag: %6.3f\n", k, v.DEC.SexagesimalDMS(), v.PMRA.SexagesimalHMS(), v.Magnitude)
 	}
@@ -38,9 +36,7 @@ func TestLoadCatalogs(t *testing.T) {
 		t.Fail()
 	}
 	ClearCatalogs()
-	cs = CatalogSource{"BSC", "brightSourceCatalog.yml"}
-	AddCatalog(cs)
-	err = bsc.LoadCatalogs()
+	bsc, err = LoadDefaultBSC()
 	if err != nil {
 		t.Fail()
 	}
@@ -58,10 +54,8 @@ func TestClearCatalogs(t *testing.T) {
 }
 
 func TestSourceNameCase(t *testing.T) {
-	cs := CatalogSource{"BSC", "brightSourceCatalog.yml"}
-	AddCatalog(cs)
-	bsc := make(BSC)
-	err := bsc.LoadCatalogs()
+	ClearCatalogs()
+	bsc, err := LoadDefaultBSC()
 	if err != nil {
 		fmt.Println("Failed to load Catalog")
 		t.Fail()
@@ -82,3 +76,314 @@ func TestSourceNameCase(t *testing.T) {
 		th.CheckFT(t, s.Magnitude, 4.01, 1e-6, "Value Error")
 	}
 }
+
+func TestSourceAliases(t *testing.T) {
+	bsc := DefaultBSC()
+	_, err := bsc.GetSource("no such star")
+	th.CheckErrorNil(t, err, "Expected error for an unknown alias")
+
+	// the index follows sources added, changed and read
+	n, _ := bsc.GetSource("alpboo")
+	n.Name, n.HR, n.Aliases = "Nova", 9999, []string{"Nova Boo"}
+	bsc["nova"] = n
+	for _, name := range []string{"novaboo", "HR 9999"} {
+		s, err := bsc.GetSource(name)
+		if err != nil || s.Name != "Nova" {
+			fmt.Println("Added alias not found: ", name, err)
+			t.Fail()
+		}
+	}
+	n.Aliases = nil
+	bsc["nova"] = n
+	_, err = bsc.GetSource("Nova Boo")
+	th.CheckErrorNil(t, err, "Expected error for a removed alias")
+	err = bsc.ReadYaml(writeFile(t, t.TempDir(), "nova.yml", "Nova:\n  RA_hms: 14:15:39.70\n  DEC_dms: +19:10:57.0\n  Magnitude: 6.0\n  Aliases: [Nova Boo 2]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bsc.GetSource("NOVA BOO 2")
+	if err != nil {
+		fmt.Println("Alias read in place not found: ", err)
+		t.Fail()
+	}
+}
+
+func TestParseCatalogEpoch(t *testing.T) {
+	cases := []struct {
+		s           string
+		f           au.Frame
+		equinox, ep float64
+	}{
+		{"", au.FK5, 2000.0, 2000.0},
+		{"J2000", au.FK5, 2000.0, 2000.0},
+		{"j2015.5", au.FK5, 2015.5, 2015.5},
+		{"B1950", au.FK4, 1950.0, 1950.0},
+		{"FK4", au.FK4, 1950.0, 1950.0},
+		{"ICRS", au.ICRS, 0.0, 2000.0},
+		{"ICRS J2016.0", au.ICRS, 0.0, 2016.0},
+	}
+	for _, c := range cases {
+		f, eq, ep, err := ParseCatalogEpoch(c.s)
+		if err != nil || f != c.f || eq != c.equinox || ep != c.ep {
+			fmt.Println("ParseCatalogEpoch failed for ", c.s, f, eq, ep, err)
+			t.Fail()
+		}
+	}
+	for _, s := range []string{"B1900", "Jxyz", "GAL"} {
+		_, _, _, err := ParseCatalogEpoch(s)
+		th.CheckErrorNil(t, err, "Expected catalog epoch error for "+s)
+	}
+}
+
+func TestCatalogEpochs(t *testing.T) {
+	// HD 119288 at B1950 and J2000, the proper motion in RA with the
+	// cos(Dec) factor
+	fn := filepath.Join(t.TempDir(), "b1950.yml")
+	cat := `HD119288:
+  Epoch: B1950
+  RA_hms: 13:39:44.526
+  DEC_dms: +08:38:28.63
+  PMRA_hms: -0:0:00.025606
+  PMDEC_dms: -0:0:00.093
+  Magnitude: 6.0
+`
+	err := os.WriteFile(fn, []byte(cat), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bsc := make(BSC)
+	err = bsc.ReadYaml(fn)
+	if err != nil {
+		fmt.Println("ReadYaml error: ", err)
+		t.Fail()
+		return
+	}
+	s, _ := bsc.GetSource("hd119288")
+	th.CheckS(t, s.Frame, ICRSFrame, "Frame Error")
+	th.CheckS(t, s.Epoch, "B1950", "Catalog epoch Error")
+	// FK5 J2000 and the ICRS differ by tens of mas
+	th.CheckFT(t, s.RA.Hour().Value, 13.0+42.0/60.0+12.740/3600.0, 0.005/3600.0, "B1950 RA Error")
+	th.CheckFT(t, s.DEC.Degree().Value, 8.0+23.0/60.0+17.69/3600.0, 0.05/3600.0, "B1950 Dec Error")
+	th.CheckFT(t, s.PMRA.Hour().Value*3600.0, -0.025425, 0.0001, "B1950 PMRA Error")
+	th.CheckFT(t, s.PMDEC.ArcSecond().Value, -0.090, 0.001, "B1950 PMDEC Error")
+
+	bad := filepath.Join(t.TempDir(), "bad.yml")
+	os.WriteFile(bad, []byte(strings.Replace(cat, "B1950", "B1875", 1)), 0644)
+	th.CheckErrorNil(t, bsc.ReadYaml(bad), "Expected unsupported epoch error")
+
+	// J2000 catalog positions move by the frame bias only
+	bsc = DefaultBSC()
+	a, _ := bsc.GetSource("alpboo")
+	ra := au.NewRaDecCoord(au.Hour, 14.0+15.0/60.0+39.70/3600.0, au.Degree, 19.0+10.0/60.0+57.0/3600.0)
+	sep := au.NewRaDecCoordA(a.RA, a.DEC).Separation(ra).MilliArcSecond().Value
+	if sep > 30.0 {
+		fmt.Println("J2000 source moved too far [mas]: ", sep)
+		t.Fail()
+	}
+}
+
+func TestExtendedSchema(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "ext.yml")
+	cat := `AlpBoo:
+  Epoch: J2000
+  RA_hms: 14:15:39.70
+  DEC_dms: +19:10:57.0
+  PMRA_hms: -0:0:00.0729
+  PMDEC_dms: -0:0:01.998
+  Magnitude: -0.04
+  Parallax_mas: 88.83
+  RadVel_kms: -5.2
+  SpectralType: K1.5III
+  HR: 5340
+  Bayer: Alpha Boo
+  CommonName: Arcturus
+  Tags: [pointing, optical]
+3C273:
+  Epoch: J2000
+  RA_hms: 12:29:06.70
+  DEC_dms: +02:03:08.6
+  Magnitude: 12.9
+  VLSR_kms: 0.0
+  Flux:
+    - {Freq_GHz: 1.4, Flux_Jy: 40.0}
+    - {Freq_GHz: 14.0, Flux_Jy: 20.0}
+  Aliases: [PKS 1226+023, J1229+0203]
+  Tags: [calibrator]
+`
+	err := os.WriteFile(fn, []byte(cat), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bsc := make(BSC)
+	err = bsc.ReadYaml(fn)
+	if err != nil {
+		fmt.Println("ReadYaml error: ", err)
+		t.Fail()
+		return
+	}
+	for _, name := range []string{"alpboo", "HR 5340", "hr5340", "alpha boo", "ARCTURUS"} {
+		s, err := bsc.GetSource(name)
+		if err != nil || s.Name != "AlpBoo" {
+			fmt.Println("Alias lookup failed for ", name, err)
+			t.Fail()
+		}
+	}
+	s, _ := bsc.GetSource("arcturus")
+	th.CheckFT(t, s.Parallax.MilliArcSecond().Value, 88.83, 0.01, "Parallax Error")
+	th.CheckFT(t, s.RadVel, -5.2, 1e-9, "RadVel Error")
+	th.CheckS(t, s.SpectralType, "K1.5III", "Spectral type Error")
+	if !s.HasTag("Pointing") || s.HasTag("calibrator") {
+		fmt.Println("Tag Error: ", s.Tags)
+		t.Fail()
+	}
+
+	q, err := bsc.GetSource("pks 1226+023")
+	if err != nil {
+		fmt.Println("Alias lookup failed for 3C273: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, q.PMRA.Value, 0.0, 1e-12, "Missing proper motion Error")
+	f, ok := q.FluxAt(1.4)
+	th.CheckFT(t, f, 40.0, 1e-9, "Flux Error")
+	f, _ = q.FluxAt(4.427188724)
+	th.CheckFT(t, f, 40.0/math.Sqrt2, 1e-6, "Flux interpolation Error")
+	f, _ = q.FluxAt(140.0)
+	th.CheckFT(t, f, 10.0, 1e-6, "Flux extrapolation Error")
+	if !ok {
+		t.Fail()
+	}
+	if _, ok = s.FluxAt(1.4); ok {
+		fmt.Println("AlpBoo should have no flux")
+		t.Fail()
+	}
+	_, err = bsc.GetSource("vega")
+	th.CheckErrorNil(t, err, "Expected unknown source error")
+
+	// the parallax reaches NOVAS
+	si, err := getSourceFromCatalog("Arcturus", &bsc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckFT(t, si.Parallax_mas, 88.83, 0.01, "NOVAS parallax Error")
+	th.CheckFT(t, si.RadVel_kmPerSec, -5.2, 1e-9, "NOVAS radial velocity Error")
+}
+
+func TestCatalogNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	radio := writeFile(t, dir, "radio.cat", "# radio pointing sources\nJ2000 AlpBoo 14:15:40.00 +19:11:00.0\nB1950 3C273 12:26:33.246 +02:19:43.53\n")
+	// the default catalog as a file of its own, to be searched in order
+	optical := writeFile(t, dir, defaultCatalogFile, string(defaultCatalog))
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"BSC", optical})
+	AddCatalog(CatalogSource{"Radio", radio})
+	bsc := make(BSC)
+	err := bsc.LoadCatalogs()
+	if err != nil {
+		fmt.Println("LoadCatalogs error: ", err)
+		t.Fail()
+	}
+
+	// the first catalog added wins, the other is still reachable
+	a, _ := bsc.GetSource("AlpBoo")
+	th.CheckS(t, a.Catalog, "BSC", "Search order Error")
+	th.CheckS(t, a.File, optical, "Provenance file Error")
+	th.CheckI(t, a.Line, 5182, "Provenance line Error")
+	r, err := bsc.GetSource("radio:alpboo")
+	if err != nil {
+		fmt.Println("Qualified lookup error: ", err)
+		t.Fail()
+	}
+	th.CheckS(t, r.Catalog, "Radio", "Qualified lookup Error")
+	th.CheckI(t, r.Line, 2, "Provenance line Error")
+	q, _ := bsc.GetSource("Radio:3C273")
+	th.CheckS(t, q.Name, "3C273", "Qualified lookup Error")
+	b, _ := bsc.GetSource("BSC:AlpBoo")
+	th.CheckS(t, b.Catalog, "BSC", "Qualified lookup Error")
+	_, err = bsc.GetSource("Radio:ZetPav")
+	th.CheckErrorNil(t, err, "Expected source not in catalog error")
+
+	dups := bsc.Duplicates()
+	th.CheckI(t, len(dups), 1, "Duplicate count Error")
+	if len(dups) == 1 {
+		th.CheckS(t, dups[0].Name, "AlpBoo", "Duplicate name Error")
+		th.CheckS(t, dups[0].Used.Catalog, "BSC", "Duplicate used Error")
+		th.CheckI(t, len(dups[0].Shadowed), 1, "Duplicate shadowed Error")
+		th.CheckS(t, dups[0].Shadowed[0].String(), "Radio ("+radio+":2)", "Duplicate provenance Error")
+	}
+
+	// pinned sources ignore the search order
+	SetSourceCat("Alp Boo", "Radio")
+	p, _ := bsc.GetSource("alpboo")
+	th.CheckS(t, p.Catalog, "Radio", "Pinned source Error")
+	SetSourceCat("alpboo", "")
+	p, _ = bsc.GetSource("alpboo")
+	th.CheckS(t, p.Catalog, "BSC", "Unpinned source Error")
+
+	SetCatalogOrder("Radio")
+	bsc = make(BSC)
+	bsc.LoadCatalogs()
+	a, _ = bsc.GetSource("AlpBoo")
+	th.CheckS(t, a.Catalog, "Radio", "SetCatalogOrder Error")
+	z, _ := bsc.GetSource("ZetPav")
+	th.CheckS(t, z.Catalog, "BSC", "Unordered catalog Error")
+}
+
+func TestDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	optical := writeFile(t, dir, "optical.yml", `Arcturus:
+  Epoch: J2000
+  RA_hms: 14:15:39.70
+  DEC_dms: +19:10:57.0
+  Magnitude: -0.04
+  HR: 5340
+Vega:
+  Epoch: J2000
+  RA_hms: 18:36:56.34
+  DEC_dms: +38:47:01.3
+  Magnitude: 0.03
+  Aliases: [Alp Lyr]
+ZetPav:
+  Epoch: J2000
+  RA_hms: 18:43:02.11
+  DEC_dms: -71:25:41.2
+  Magnitude: 4.01
+`)
+	// found by HR number, alias and position, and one too far away
+	radio := writeFile(t, dir, "radio.cat", `J2000 HR5340 14:15:40.00 +19:11:00.0
+J2000 AlpLyr 18:36:56.40 +38:47:02.0
+J2000 PavStar 18:43:02.20 -71:25:42.0
+J2000 Far 18:43:10.00 -71:25:41.2
+`)
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"Optical", optical})
+	AddCatalog(CatalogSource{"Radio", radio})
+	bsc := make(BSC)
+	err := bsc.LoadCatalogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dups := bsc.Duplicates()
+	if len(dups) != 3 {
+		fmt.Println("Duplicates Error: ", dups)
+		t.Fail()
+		return
+	}
+	// an alias lookup resolves to the first key, alplyr
+	for idx, exp := range []struct{ name, used, shadowed string }{
+		{"AlpLyr", "Radio", "Optical"},
+		{"Arcturus", "Optical", "Radio"},
+		{"PavStar", "Radio", "Optical"},
+	} {
+		d := dups[idx]
+		th.CheckS(t, d.Name, exp.name, "Duplicate name Error")
+		th.CheckS(t, d.Used.Catalog, exp.used, exp.name+" used Error")
+		if len(d.Shadowed) != 1 {
+			fmt.Println("Duplicate shadowed Error: ", d)
+			t.Fail()
+			continue
+		}
+		th.CheckS(t, d.Shadowed[0].Catalog, exp.shadowed, exp.name+" shadowed Error")
+	}
+}
@@ -0,0 +1,816 @@
+// Star catalog file formats
+package ephemeris
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+type CatalogFormat int
+
+const (
+	// Enums to identify catalog file formats
+	_ CatalogFormat = iota
+	YAMLFormat
+	BSC5Format
+	HipparcosFormat
+	CSVFormat
+	VOTableFormat
+	TextFormat
+
+	// Catalog format strings
+	YAMLStr      = "yaml"
+	BSC5Str      = "bsc5"
+	HipparcosStr = "hipparcos"
+	CSVStr       = "csv"
+	VOTableStr   = "votable"
+	TextStr      = "text"
+
+	// epochs of the catalog positions
+	bsc5Epoch      = "J2000"
+	hipparcosEpoch = "ICRS J1991.25"
+)
+
+// String returns the name of the format.
+func (cf CatalogFormat) String() string {
+	var s string
+	switch cf {
+	case YAMLFormat:
+		s = YAMLStr
+	case BSC5Format:
+		s = BSC5Str
+	case HipparcosFormat:
+		s = HipparcosStr
+	case CSVFormat:
+		s = CSVStr
+	case VOTableFormat:
+		s = VOTableStr
+	case TextFormat:
+		s = TextStr
+	}
+	return s
+}
+
+// CSVSpec maps the columns of a CSV file onto source fields. The *Col
+// fields are header names, matched ignoring case; empty ones are absent
+// from the file. RA is in RAUnit (Hour if zero) and Dec in DecUnit (Degree
+// if zero) unless written sexagesimally, with ':' or spaces. Proper
+// motions are in mas/yr, PMRA including the cos(Dec) factor, parallax in
+// mas and radial velocity in km/s. Aliases are separated by ';'. Epoch is
+// the catalog epoch of rows without an EpochCol value.
+type CSVSpec struct {
+	Comma           rune
+	Epoch           string
+	NameCol         string
+	RACol           string
+	DecCol          string
+	PMRACol         string
+	PMDecCol        string
+	ParallaxCol     string
+	RadVelCol       string
+	MagnitudeCol    string
+	SpectralTypeCol string
+	AliasesCol      string
+	EpochCol        string
+	RAUnit          au.AngleUnit
+	DecUnit         au.AngleUnit
+}
+
+// catalogFormat is a format declared for a catalog file.
+type catalogFormat struct {
+	format CatalogFormat
+	csv    CSVSpec
+}
+
+var (
+	// formats declared with AddCatalogFormat and AddCSVCatalog, by file,
+	// guarded by catalogsMu
+	formats = make(map[string]catalogFormat)
+)
+
+// DefaultCSVSpec returns the spec for CSV files with the header
+// name,ra,dec,pmra,pmdec,parallax,radvel,mag,sptype,aliases,epoch, RA in
+// hours and J2000 positions. Any of the columns but name, ra and dec may be
+// left out.
+func DefaultCSVSpec() CSVSpec {
+	return CSVSpec{
+		Comma:           ',',
+		Epoch:           "J2000",
+		NameCol:         "name",
+		RACol:           "ra",
+		DecCol:          "dec",
+		PMRACol:         "pmra",
+		PMDecCol:        "pmdec",
+		ParallaxCol:     "parallax",
+		RadVelCol:       "radvel",
+		MagnitudeCol:    "mag",
+		SpectralTypeCol: "sptype",
+		AliasesCol:      "aliases",
+		EpochCol:        "epoch",
+		RAUnit:          au.Hour,
+		DecUnit:         au.Degree,
+	}
+}
+
+// AddCatalogFormat adds a catalog whose file is in the given format rather
+// than one implied by its name.
+func AddCatalogFormat(cat CatalogSource, format CatalogFormat) {
+	catalogsMu.Lock()
+	formats[cat.Filename] = catalogFormat{format: format, csv: DefaultCSVSpec()}
+	catalogsMu.Unlock()
+	AddCatalog(cat)
+}
+
+// AddCSVCatalog adds a CSV catalog read using spec.
+func AddCSVCatalog(cat CatalogSource, spec CSVSpec) {
+	catalogsMu.Lock()
+	formats[cat.Filename] = catalogFormat{format: CSVFormat, csv: spec}
+	catalogsMu.Unlock()
+	AddCatalog(cat)
+}
+
+// CatalogFormatFromFilename returns the format implied by the name of fn,
+// ignoring a trailing .gz: .yml and .yaml are YAML, .csv CSV, .tsv tab
+// separated CSV, .vot, .votable and .xml VOTable, hip_main.dat and .hip
+// Hipparcos, catalog, bsc5.dat, ybsc5 and .bsc5 the Yale Bright Star
+// Catalog, and .cat and .src the text source list format.
+func CatalogFormatFromFilename(fn string) (CatalogFormat, error) {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(fn), ".gz"))
+	ext := filepath.Ext(base)
+	switch {
+	case ext == ".yml" || ext == ".yaml":
+		return YAMLFormat, nil
+	case ext == ".csv" || ext == ".tsv":
+		return CSVFormat, nil
+	case ext == ".vot" || ext == ".votable" || ext == ".xml":
+		return VOTableFormat, nil
+	case ext == ".cat" || ext == ".src":
+		return TextFormat, nil
+	case ext == ".hip" || strings.HasPrefix(base, "hip_main"):
+		return HipparcosFormat, nil
+	case ext == ".bsc5" || base == "catalog" || strings.HasPrefix(base, "bsc5") ||
+		strings.HasPrefix(base, "ybsc5"):
+		return BSC5Format, nil
+	}
+	emsg := fmt.Sprintf("Cannot tell the catalog format of %s, use AddCatalogFormat", fn)
+	return 0, errors.New(emsg)
+}
+
+// ReadCatalog reads the catalog c in its declared format, or the one
+// implied by its file name.
+func (bsc *BSC) ReadCatalog(c CatalogSource) error {
+	cf, err := catalogFormatOf(c.Filename)
+	if err != nil {
+		return err
+	}
+	return bsc.readCatalogAs(c, cf)
+}
+
+// catalogFormatOf returns the declared format of the catalog file fn, or
+// the one implied by its name.
+func catalogFormatOf(fn string) (catalogFormat, error) {
+	catalogsMu.RLock()
+	cf, ok := formats[fn]
+	catalogsMu.RUnlock()
+	if ok {
+		return cf, nil
+	}
+	f, err := CatalogFormatFromFilename(fn)
+	if err != nil {
+		return catalogFormat{}, err
+	}
+	cf = catalogFormat{format: f, csv: DefaultCSVSpec()}
+	if strings.HasSuffix(strings.TrimSuffix(strings.ToLower(fn), ".gz"), ".tsv") {
+		cf.csv.Comma = '\t'
+	}
+	return cf, nil
+}
+
+// readCatalogAs reads the catalog c in the format cf.
+func (bsc *BSC) readCatalogAs(c CatalogSource, cf catalogFormat) error {
+	switch cf.format {
+	case YAMLFormat:
+		return bsc.ReadYaml(c.Filename)
+	case BSC5Format:
+		return bsc.ReadBSC5(c.Filename)
+	case HipparcosFormat:
+		return bsc.ReadHipparcos(c.Filename)
+	case CSVFormat:
+		return bsc.ReadCSV(c.Filename, cf.csv)
+	case VOTableFormat:
+		return bsc.ReadVOTable(c.Filename)
+	case TextFormat:
+		return bsc.ReadText(c.Filename)
+	}
+	emsg := fmt.Sprintf("Unknown catalog format %d for %s", cf.format, c.Filename)
+	return errors.New(emsg)
+}
+
+// readFile opens fn, decompressing it if it ends in .gz, and passes it to
+// parse.
+func readFile(fn string, parse func(io.Reader) error) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(strings.ToLower(fn), ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	err = parse(r)
+	if err != nil {
+		emsg := fmt.Sprintf("%s: %v", fn, err)
+		return errors.New(emsg)
+	}
+	return nil
+}
+
+// catalogRow is a source as read from a catalog file, before conversion to
+// ICRS. pmra includes the cos(Dec) factor.
+type catalogRow struct {
+	name, epoch, spType string
+	ra, dec             au.Angle
+	pmra, pmdec         au.Angle
+	parallax            au.Angle
+	radVel, mag         float64
+	hr                  int
+	bayer               string
+	aliases             []string
+}
+
+// add converts row, read from line of fn, to ICRS and adds it to bsc.
+func (bsc *BSC) add(row catalogRow, fn string, line int) error {
+	var d BSCdata
+	d.Name = row.name
+	d.File = fn
+	d.Line = line
+	d.Epoch = row.epoch
+	d.RA = row.ra
+	d.DEC = row.dec
+	d.PMRA = row.pmra.Hour()
+	d.PMDEC = row.pmdec
+	d.Parallax = row.parallax
+	d.RadVel = row.radVel
+	d.Magnitude = row.mag
+	d.SpectralType = row.spType
+	d.HR = row.hr
+	d.Bayer = row.bayer
+	d.Aliases = row.aliases
+	err := d.toICRS()
+	if err != nil {
+		emsg := fmt.Sprintf("Source %s: %v", row.name, err)
+		return errors.New(emsg)
+	}
+	(*bsc)[strings.ToLower(row.name)] = d
+	return nil
+}
+
+// parseAngle parses a decimal value in unit u or, if it holds ':' or
+// white space, a sexagesimal one in hours if u is Hour and degrees
+// otherwise.
+func parseAngle(s string, u au.AngleUnit) (au.Angle, error) {
+	s = strings.TrimSpace(s)
+	if f := strings.Fields(strings.ReplaceAll(s, ":", " ")); len(f) == 3 {
+		sex := strings.Join(f, ":")
+		if u == au.Hour {
+			return au.NewAngleHMS(sex)
+		}
+		return au.NewAngleDMS(sex)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		emsg := fmt.Sprintf("Invalid angle: %s", s)
+		return au.Angle{}, errors.New(emsg)
+	}
+	return au.NewAngle(u, v), nil
+}
+
+// parseFloat parses s, which may be blank for 0.
+func parseFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0.0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// field returns columns [from, to] (1 based, as in catalog ReadMe files)
+// of line, trimmed.
+func field(line string, from, to int) string {
+	if from > len(line) {
+		return ""
+	}
+	if to > len(line) {
+		to = len(line)
+	}
+	return strings.TrimSpace(line[from-1 : to])
+}
+
+// parseBSC5Line parses one record of the Yale Bright Star Catalog, 5th
+// revised edition (Hoffleit & Warren 1991, catalog V/50). ok is false for
+// the few records with no position.
+func parseBSC5Line(line string) (catalogRow, bool, error) {
+	var row catalogRow
+	if field(line, 76, 77) == "" {
+		return row, false, nil
+	}
+	hr, err := strconv.Atoi(field(line, 1, 4))
+	if err != nil {
+		return row, false, err
+	}
+	row.hr = hr
+	row.epoch = bsc5Epoch
+	// Flamsteed number, Bayer letter and constellation
+	flam, bayer, con := field(line, 5, 7), field(line, 8, 11), field(line, 12, 14)
+	switch {
+	case bayer != "":
+		row.name = bayer + con
+		row.bayer = bayer + " " + con
+	case flam != "":
+		row.name = flam + con
+	default:
+		row.name = fmt.Sprintf("HR%d", hr)
+	}
+	if flam != "" && con != "" {
+		row.aliases = append(row.aliases, flam+" "+con)
+	}
+	if hd := field(line, 26, 31); hd != "" {
+		row.aliases = append(row.aliases, "HD "+hd)
+	}
+
+	ra := fmt.Sprintf("%s:%s:%s", field(line, 76, 77), field(line, 78, 79), field(line, 80, 83))
+	row.ra, err = au.NewAngleHMS(ra)
+	if err != nil {
+		return row, false, err
+	}
+	dec := fmt.Sprintf("%s%s:%s:%s", field(line, 84, 84), field(line, 85, 86),
+		field(line, 87, 88), field(line, 89, 90))
+	row.dec, err = au.NewAngleDMS(dec)
+	if err != nil {
+		return row, false, err
+	}
+	vals := make([]float64, 5)
+	for idx, c := range [][2]int{{103, 107}, {149, 154}, {155, 160}, {162, 166}, {167, 170}} {
+		vals[idx], err = parseFloat(field(line, c[0], c[1]))
+		if err != nil {
+			return row, false, err
+		}
+	}
+	row.mag = vals[0]
+	row.pmra = au.NewAngle(au.ArcSecond, vals[1])
+	row.pmdec = au.NewAngle(au.ArcSecond, vals[2])
+	row.parallax = au.NewAngle(au.ArcSecond, vals[3]).MilliArcSecond()
+	row.radVel = vals[4]
+	row.spType = field(line, 128, 147)
+	return row, true, nil
+}
+
+// ReadBSC5 reads the fixed width Yale Bright Star Catalog, 5th revised
+// edition. Sources are named by Bayer letter (AlpBoo) or Flamsteed number
+// (30Psc) and constellation, or HR number, as in brightSourceCatalog.yml,
+// with the HR and HD numbers as aliases.
+func (bsc *BSC) ReadBSC5(fn string) error {
+	defer bsc.forgetNames()
+	return readFile(fn, func(r io.Reader) error {
+		sc := bufio.NewScanner(r)
+		for ln := 1; sc.Scan(); ln++ {
+			if strings.TrimSpace(sc.Text()) == "" {
+				continue
+			}
+			row, ok, err := parseBSC5Line(sc.Text())
+			if err != nil {
+				emsg := fmt.Sprintf("line %d: %v", ln, err)
+				return errors.New(emsg)
+			}
+			if !ok {
+				continue
+			}
+			err = bsc.add(row, fn, ln)
+			if err != nil {
+				return err
+			}
+		}
+		return sc.Err()
+	})
+}
+
+// parseHipparcosLine parses one '|' separated record of the Hipparcos main
+// catalog (ESA 1997, catalog I/239 hip_main.dat).
+func parseHipparcosLine(line string) (catalogRow, error) {
+	var row catalogRow
+	f := strings.Split(line, "|")
+	if len(f) < 78 {
+		emsg := fmt.Sprintf("Hipparcos record has %d fields, not 78", len(f))
+		return row, errors.New(emsg)
+	}
+	hip := strings.TrimSpace(f[1])
+	row.name = "HIP" + hip
+	row.aliases = []string{"HIP " + hip}
+	if hd := strings.TrimSpace(f[71]); hd != "" {
+		row.aliases = append(row.aliases, "HD "+hd)
+	}
+	row.epoch = hipparcosEpoch
+	var err error
+	if strings.TrimSpace(f[8]) != "" {
+		row.ra, err = parseAngle(f[8], au.Degree)
+		if err != nil {
+			return row, err
+		}
+		row.ra = row.ra.Hour()
+		row.dec, err = parseAngle(f[9], au.Degree)
+		if err != nil {
+			return row, err
+		}
+	} else {
+		// the few stars without an astrometric solution
+		row.ra, err = parseAngle(f[3], au.Hour)
+		if err != nil {
+			return row, err
+		}
+		row.dec, err = parseAngle(f[4], au.Degree)
+		if err != nil {
+			return row, err
+		}
+	}
+	vals := make([]float64, 4)
+	for idx, c := range []int{5, 11, 12, 13} {
+		vals[idx], err = parseFloat(f[c])
+		if err != nil {
+			return row, err
+		}
+	}
+	row.mag = vals[0]
+	// negative parallaxes are noise
+	row.parallax = au.NewAngle(au.MilliArcSecond, math.Max(vals[1], 0.0))
+	row.pmra = au.NewAngle(au.MilliArcSecond, vals[2])
+	row.pmdec = au.NewAngle(au.MilliArcSecond, vals[3])
+	row.spType = strings.TrimSpace(f[76])
+	return row, nil
+}
+
+// ReadHipparcos reads the Hipparcos main catalog. Sources are named
+// HIP<n>, with "HIP <n>" and the HD number as aliases. Positions are ICRS
+// at epoch J1991.25 and are moved to J2000.0 on load.
+func (bsc *BSC) ReadHipparcos(fn string) error {
+	defer bsc.forgetNames()
+	return readFile(fn, func(r io.Reader) error {
+		sc := bufio.NewScanner(r)
+		for ln := 1; sc.Scan(); ln++ {
+			if strings.TrimSpace(sc.Text()) == "" {
+				continue
+			}
+			row, err := parseHipparcosLine(sc.Text())
+			if err != nil {
+				emsg := fmt.Sprintf("line %d: %v", ln, err)
+				return errors.New(emsg)
+			}
+			err = bsc.add(row, fn, ln)
+			if err != nil {
+				return err
+			}
+		}
+		return sc.Err()
+	})
+}
+
+// columnRow builds a source from the values of one table row. col returns
+// the value of a column, or "" if the table does not have it.
+func columnRow(col func(name string) string, spec CSVSpec) (catalogRow, error) {
+	var row catalogRow
+	var err error
+	row.name = col(spec.NameCol)
+	if row.name == "" {
+		return row, errors.New("Source has no name")
+	}
+	raUnit, decUnit := spec.RAUnit, spec.DecUnit
+	if raUnit == 0 {
+		raUnit = au.Hour
+	}
+	if decUnit == 0 {
+		decUnit = au.Degree
+	}
+	row.ra, err = parseAngle(col(spec.RACol), raUnit)
+	if err != nil {
+		return row, err
+	}
+	row.ra = row.ra.Hour()
+	row.dec, err = parseAngle(col(spec.DecCol), decUnit)
+	if err != nil {
+		return row, err
+	}
+	row.dec = row.dec.Degree()
+	vals := make([]float64, 5)
+	for idx, c := range []string{spec.PMRACol, spec.PMDecCol, spec.ParallaxCol, spec.RadVelCol, spec.MagnitudeCol} {
+		vals[idx], err = parseFloat(col(c))
+		if err != nil {
+			return row, err
+		}
+	}
+	row.pmra = au.NewAngle(au.MilliArcSecond, vals[0])
+	row.pmdec = au.NewAngle(au.MilliArcSecond, vals[1])
+	row.parallax = au.NewAngle(au.MilliArcSecond, vals[2])
+	row.radVel = vals[3]
+	row.mag = vals[4]
+	row.spType = col(spec.SpectralTypeCol)
+	for _, a := range strings.Split(col(spec.AliasesCol), ";") {
+		if a = strings.TrimSpace(a); a != "" {
+			row.aliases = append(row.aliases, a)
+		}
+	}
+	row.epoch = col(spec.EpochCol)
+	if row.epoch == "" {
+		row.epoch = spec.Epoch
+	}
+	return row, nil
+}
+
+// ReadCSV reads a CSV catalog with a header row, mapping its columns with
+// spec. Lines starting with '#' are comments.
+func (bsc *BSC) ReadCSV(fn string, spec CSVSpec) error {
+	defer bsc.forgetNames()
+	return readFile(fn, func(r io.Reader) error {
+		cr := csv.NewReader(r)
+		if spec.Comma != 0 {
+			cr.Comma = spec.Comma
+		}
+		cr.Comment = '#'
+		cr.TrimLeadingSpace = true
+		header, err := cr.Read()
+		if err != nil {
+			return err
+		}
+		cols := make(map[string]int)
+		for idx, h := range header {
+			cols[strings.ToLower(strings.TrimSpace(h))] = idx
+		}
+		for _, req := range []string{spec.NameCol, spec.RACol, spec.DecCol} {
+			if _, ok := cols[strings.ToLower(req)]; !ok {
+				emsg := fmt.Sprintf("No column %q in header", req)
+				return errors.New(emsg)
+			}
+		}
+		cr.FieldsPerRecord = len(header)
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			col := func(name string) string {
+				idx, ok := cols[strings.ToLower(name)]
+				if name == "" || !ok {
+					return ""
+				}
+				return strings.TrimSpace(rec[idx])
+			}
+			line, _ := cr.FieldPos(0)
+			row, err := columnRow(col, spec)
+			if err != nil {
+				emsg := fmt.Sprintf("line %d: %v", line, err)
+				return errors.New(emsg)
+			}
+			err = bsc.add(row, fn, line)
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// The parts of a VOTable (IVOA VOTable 1.4) read by ReadVOTable.
+type voTable struct {
+	Resources []voResource `xml:"RESOURCE"`
+}
+
+type voResource struct {
+	CooSys    []voCooSys   `xml:"COOSYS"`
+	Tables    []voTableEl  `xml:"TABLE"`
+	Resources []voResource `xml:"RESOURCE"`
+}
+
+type voCooSys struct {
+	ID      string `xml:"ID,attr"`
+	System  string `xml:"system,attr"`
+	Equinox string `xml:"equinox,attr"`
+	Epoch   string `xml:"epoch,attr"`
+}
+
+type voTableEl struct {
+	Fields []voField `xml:"FIELD"`
+	Rows   []voRow   `xml:"DATA>TABLEDATA>TR"`
+	Binary *struct{} `xml:"DATA>BINARY"`
+}
+
+type voField struct {
+	Name string `xml:"name,attr"`
+	ID   string `xml:"ID,attr"`
+	UCD  string `xml:"ucd,attr"`
+	Unit string `xml:"unit,attr"`
+	Ref  string `xml:"ref,attr"`
+}
+
+type voRow struct {
+	Cells []string `xml:"TD"`
+}
+
+// voColumn finds the column for a source field by UCD, then by name.
+func voColumn(fields []voField, ucds []string, names []string) int {
+	for _, u := range ucds {
+		for idx, f := range fields {
+			if strings.EqualFold(f.UCD, u) {
+				return idx
+			}
+		}
+	}
+	for _, n := range names {
+		for idx, f := range fields {
+			if strings.EqualFold(f.Name, n) || strings.EqualFold(f.ID, n) {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+// voUnit returns the angle unit of a VOTable unit string, def if it is
+// not an angle unit.
+func voUnit(unit string, def au.AngleUnit) au.AngleUnit {
+	u := strings.ToLower(strings.Trim(unit, "\" "))
+	switch {
+	case u == "h" || u == "h:m:s" || u == "hour":
+		return au.Hour
+	case u == "deg" || u == "d:m:s":
+		return au.Degree
+	case u == "rad":
+		return au.Radian
+	case strings.HasPrefix(u, "mas"):
+		return au.MilliArcSecond
+	case strings.HasPrefix(u, "arcsec") || strings.HasPrefix(u, "\""):
+		return au.ArcSecond
+	}
+	return def
+}
+
+// voEpoch returns the catalog epoch for a COOSYS element.
+func voEpoch(cs voCooSys) string {
+	eq := strings.TrimSpace(cs.Equinox)
+	ep := strings.TrimSpace(cs.Epoch)
+	switch strings.ToUpper(cs.System) {
+	case "ICRS":
+		if ep != "" {
+			return "ICRS " + ep
+		}
+		return "ICRS"
+	case "EQ_FK4":
+		if eq == "" {
+			eq = "B1950"
+		}
+		return eq
+	case "EQ_FK5":
+		if eq == "" {
+			eq = "J2000"
+		}
+		return eq
+	}
+	return ""
+}
+
+// tables returns the tables of r and its nested resources, along with the
+// coordinate systems in scope.
+func (r voResource) tables(cs []voCooSys) ([]voTableEl, []voCooSys) {
+	cs = append(cs, r.CooSys...)
+	ts := append([]voTableEl(nil), r.Tables...)
+	for _, sub := range r.Resources {
+		st, scs := sub.tables(cs)
+		ts = append(ts, st...)
+		cs = scs
+	}
+	return ts, cs
+}
+
+// ReadVOTable reads every TABLEDATA table in a VOTable. Columns are found
+// by UCD (pos.eq.ra;meta.main, pos.pm;pos.eq.ra, pos.parallax, phot.mag,
+// ...) or by common names (RAJ2000, pmRA, Plx, Vmag, ...), and the catalog
+// epoch comes from the COOSYS the RA column refers to, ICRS if none.
+func (bsc *BSC) ReadVOTable(fn string) error {
+	defer bsc.forgetNames()
+	return readFile(fn, func(r io.Reader) error {
+		var vt voTable
+		err := xml.NewDecoder(r).Decode(&vt)
+		if err != nil {
+			return err
+		}
+		for _, res := range vt.Resources {
+			ts, cs := res.tables(nil)
+			for _, t := range ts {
+				err = bsc.addVOTable(t, cs, fn)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// addVOTable adds the rows of t, read from fn. The provenance line of a
+// source is its row in the table.
+func (bsc *BSC) addVOTable(t voTableEl, cs []voCooSys, fn string) error {
+	if t.Binary != nil {
+		return errors.New("Only TABLEDATA VOTables are supported")
+	}
+	fs := t.Fields
+	name := voColumn(fs, []string{"meta.id;meta.main"}, []string{"name", "main_id", "hip", "hr", "id"})
+	ra := voColumn(fs, []string{"pos.eq.ra;meta.main", "pos.eq.ra"}, []string{"ra", "raj2000", "ra_icrs", "radeg"})
+	dec := voColumn(fs, []string{"pos.eq.dec;meta.main", "pos.eq.dec"}, []string{"dec", "dej2000", "de_icrs", "dedeg"})
+	if name < 0 || ra < 0 || dec < 0 {
+		return errors.New("VOTable needs name, RA and Dec columns")
+	}
+	cols := map[string]int{
+		"name":   name,
+		"ra":     ra,
+		"dec":    dec,
+		"pmra":   voColumn(fs, []string{"pos.pm;pos.eq.ra"}, []string{"pmra"}),
+		"pmdec":  voColumn(fs, []string{"pos.pm;pos.eq.dec"}, []string{"pmde", "pmdec"}),
+		"plx":    voColumn(fs, []string{"pos.parallax", "pos.parallax.trig"}, []string{"plx", "parallax"}),
+		"rv":     voColumn(fs, []string{"spect.dopplerveloc.opt", "phys.veloc;pos.heliocentric"}, []string{"rv", "radvel"}),
+		"mag":    voColumn(fs, []string{"phot.mag;em.opt.v", "phot.mag"}, []string{"vmag", "mag"}),
+		"sptype": voColumn(fs, []string{"src.spType"}, []string{"sptype", "sp_type"}),
+	}
+	spec := CSVSpec{
+		NameCol:         "name",
+		RACol:           "ra",
+		DecCol:          "dec",
+		PMRACol:         "pmra",
+		PMDecCol:        "pmdec",
+		ParallaxCol:     "plx",
+		RadVelCol:       "rv",
+		MagnitudeCol:    "mag",
+		SpectralTypeCol: "sptype",
+		RAUnit:          voUnit(fs[ra].Unit, au.Degree),
+		DecUnit:         voUnit(fs[dec].Unit, au.Degree),
+		Epoch:           "ICRS",
+	}
+	if len(cs) > 0 {
+		spec.Epoch = voEpoch(cs[0])
+		for _, c := range cs {
+			if c.ID != "" && c.ID == fs[ra].Ref {
+				spec.Epoch = voEpoch(c)
+			}
+		}
+	}
+	// proper motions and parallax scale to mas
+	scale := make(map[string]float64)
+	for _, k := range []string{"pmra", "pmdec", "plx"} {
+		scale[k] = 1.0
+		if idx := cols[k]; idx >= 0 {
+			u := voUnit(fs[idx].Unit, au.MilliArcSecond)
+			scale[k] = au.NewAngle(u, 1.0).MilliArcSecond().Value
+		}
+	}
+	for ln, tr := range t.Rows {
+		col := func(n string) string {
+			idx, ok := cols[n]
+			if !ok || idx < 0 || idx >= len(tr.Cells) {
+				return ""
+			}
+			v := strings.TrimSpace(tr.Cells[idx])
+			if s, ok := scale[n]; ok && s != 1.0 && v != "" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					v = strconv.FormatFloat(f*s, 'g', -1, 64)
+				}
+			}
+			return v
+		}
+		row, err := columnRow(col, spec)
+		if err != nil {
+			emsg := fmt.Sprintf("row %d: %v", ln+1, err)
+			return errors.New(emsg)
+		}
+		err = bsc.add(row, fn, ln+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package ephemeris
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// Arcturus, ICRS J2000
+var arcturus = au.NewRaDecCoord(au.Hour, 14.0+15.0/60.0+39.672/3600.0, au.Degree, 19.0+10.0/60.0+56.67/3600.0)
+
+// checkArcturus checks the position and motion of Arcturus as loaded.
+func checkArcturus(t *testing.T, bsc BSC, name string, tol float64) {
+	s, err := bsc.GetSource(name)
+	if err != nil {
+		fmt.Println("Arcturus not found as ", name)
+		t.Fail()
+		return
+	}
+	sep := au.NewRaDecCoordA(s.RA, s.DEC).Separation(arcturus).MilliArcSecond().Value
+	th.CheckFT(t, sep, 0.0, tol, name+" position Error [mas]")
+	th.CheckS(t, s.Frame, ICRSFrame, name+" frame Error")
+	// every format gives the proper motion in RA on the sky, as the YAML
+	th.CheckFT(t, s.PMRA.ArcSecond().Value, -1.093, 0.002, name+" PMRA Error")
+	th.CheckFT(t, s.PMDEC.ArcSecond().Value, -1.999, 0.002, name+" PMDEC Error")
+	th.CheckFT(t, s.Magnitude, -0.05, 0.02, name+" magnitude Error")
+}
+
+// writeFile writes a catalog file in dir, gzipped if its name ends in .gz.
+func writeFile(t *testing.T, dir, name, content string) string {
+	fn := filepath.Join(dir, name)
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if strings.HasSuffix(name, ".gz") {
+		gz := gzip.NewWriter(f)
+		gz.Write([]byte(content))
+		gz.Close()
+		return fn
+	}
+	f.WriteString(content)
+	return fn
+}
+
+// bsc5Line builds a Yale BSC5 record from values keyed by their first
+// column.
+func bsc5Line(vals map[int]string) string {
+	line := []byte(strings.Repeat(" ", 197))
+	for col, v := range vals {
+		copy(line[col-1:], v)
+	}
+	return string(line)
+}
+
+var bsc5Cat = bsc5Line(map[int]string{
+	1: "5340", 5: " 16", 8: "Alp", 12: "Boo", 26: "124897",
+	76: "14", 78: "15", 80: "39.7", 84: "+", 85: "19", 87: "10", 89: "57",
+	103: "-0.04", 128: "K1.5IIIFe-0.5", 149: "-1.093", 155: "-1.999", 162: "+.088", 167: "  -5",
+}) + "\n" + bsc5Line(map[int]string{1: "  92", 26: "  1983"}) + "\n"
+
+func hipLine(vals map[int]string) string {
+	f := make([]string, 78)
+	f[0] = "H"
+	for idx, v := range vals {
+		f[idx] = v
+	}
+	return strings.Join(f, "|")
+}
+
+var hipCat = hipLine(map[int]string{
+	1: " 69673", 3: "14 15 40.35", 4: "+19 11 14.2", 5: "-0.05", 8: "213.91811422", 9: "+19.18726881",
+	11: "  88.85", 12: "-1093.45", 13: "-1999.40", 71: "124897", 76: "K2IIIp",
+}) + "\n"
+
+const csvCat = `# name, position and motion
+name,ra,dec,pmra,pmdec,parallax,mag,sptype,aliases,epoch
+Arcturus, 14:15:39.672, +19:10:56.67, -1093.45, -1999.40, 88.85, -0.05, K1.5III, alp Boo;HR 5340, ICRS
+3C273, 12 26 33.246, 02 19 43.53,,,,,,,B1950
+`
+
+const voCat = `<?xml version="1.0"?>
+<VOTABLE version="1.4" xmlns="http://www.ivoa.net/xml/VOTable/v1.3">
+ <RESOURCE>
+  <COOSYS ID="H" system="ICRS" epoch="J1991.25"/>
+  <TABLE>
+   <FIELD name="HIP" ucd="meta.id;meta.main" datatype="int"/>
+   <FIELD name="RAdeg" ucd="pos.eq.ra;meta.main" ref="H" unit="deg" datatype="double"/>
+   <FIELD name="DEdeg" ucd="pos.eq.dec;meta.main" ref="H" unit="deg" datatype="double"/>
+   <FIELD name="Plx" ucd="pos.parallax.trig" unit="mas" datatype="float"/>
+   <FIELD name="pmRA" ucd="pos.pm;pos.eq.ra" unit="arcsec/yr" datatype="float"/>
+   <FIELD name="pmDE" ucd="pos.pm;pos.eq.dec" unit="mas/yr" datatype="float"/>
+   <FIELD name="Vmag" ucd="phot.mag;em.opt.V" unit="mag" datatype="float"/>
+   <DATA><TABLEDATA>
+    <TR><TD>69673</TD><TD>213.91811422</TD><TD>19.18726881</TD><TD>88.85</TD><TD>-1.09345</TD><TD>-1999.40</TD><TD>-0.05</TD></TR>
+   </TABLEDATA></DATA>
+  </TABLE>
+ </RESOURCE>
+</VOTABLE>
+`
+
+func TestCatalogFormatFromFilename(t *testing.T) {
+	for fn, exp := range map[string]CatalogFormat{
+		"brightSourceCatalog.yml": YAMLFormat,
+		"sources.yaml":            YAMLFormat,
+		"catalog":                 BSC5Format,
+		"/data/bsc5.dat.gz":       BSC5Format,
+		"hip_main.dat":            HipparcosFormat,
+		"stars.csv":               CSVFormat,
+		"stars.tsv":               CSVFormat,
+		"vizier.vot":              VOTableFormat,
+		"vizier.xml.gz":           VOTableFormat,
+		"radio.cat":               TextFormat,
+	} {
+		f, err := CatalogFormatFromFilename(fn)
+		if err != nil || f != exp {
+			fmt.Printf("Format of %s: %s, expected %s\n", fn, f, exp)
+			t.Fail()
+		}
+	}
+	_, err := CatalogFormatFromFilename("sources.txt")
+	th.CheckErrorNil(t, err, "Expected unknown format error")
+}
+
+func TestReadBSC5(t *testing.T) {
+	fn := writeFile(t, t.TempDir(), "bsc5.dat", bsc5Cat)
+	bsc := make(BSC)
+	err := bsc.ReadBSC5(fn)
+	if err != nil {
+		fmt.Println("ReadBSC5 error: ", err)
+		t.Fail()
+		return
+	}
+	th.CheckI(t, len(bsc), 1, "Records without a position should be skipped")
+	// the BSC5 positions are given to 0.1s and 1 arcsec
+	checkArcturus(t, bsc, "AlpBoo", 1000.0)
+	checkArcturus(t, bsc, "HR 5340", 1000.0)
+	checkArcturus(t, bsc, "HD 124897", 1000.0)
+	checkArcturus(t, bsc, "16 Boo", 1000.0)
+	s, _ := bsc.GetSource("alpboo")
+	th.CheckS(t, s.SpectralType, "K1.5IIIFe-0.5", "Spectral type Error")
+	th.CheckFT(t, s.Parallax.MilliArcSecond().Value, 88.0, 1e-9, "Parallax Error")
+	th.CheckFT(t, s.RadVel, -5.0, 1e-9, "Radial velocity Error")
+}
+
+func TestReadHipparcos(t *testing.T) {
+	fn := writeFile(t, t.TempDir(), "hip_main.dat.gz", hipCat)
+	bsc := make(BSC)
+	err := bsc.ReadHipparcos(fn)
+	if err != nil {
+		fmt.Println("ReadHipparcos error: ", err)
+		t.Fail()
+		return
+	}
+	// moved from J1991.25 to J2000.0
+	checkArcturus(t, bsc, "HIP69673", 20.0)
+	checkArcturus(t, bsc, "HD 124897", 20.0)
+	s, _ := bsc.GetSource("hip 69673")
+	th.CheckS(t, s.Epoch, "ICRS J1991.25", "Catalog epoch Error")
+	th.CheckFT(t, s.Parallax.MilliArcSecond().Value, 88.85, 1e-9, "Parallax Error")
+
+	bad := writeFile(t, t.TempDir(), "hip_main.dat", "H| 1|2\n")
+	th.CheckErrorNil(t, bsc.ReadHipparcos(bad), "Expected short record error")
+}
+
+func TestReadCSV(t *testing.T) {
+	fn := writeFile(t, t.TempDir(), "stars.csv", csvCat)
+	bsc := make(BSC)
+	err := bsc.ReadCSV(fn, DefaultCSVSpec())
+	if err != nil {
+		fmt.Println("ReadCSV error: ", err)
+		t.Fail()
+		return
+	}
+	checkArcturus(t, bsc, "Arcturus", 1.0)
+	checkArcturus(t, bsc, "alp Boo", 1.0)
+	q, _ := bsc.GetSource("3C273")
+	th.CheckS(t, q.Epoch, "B1950", "Catalog epoch Error")
+	th.CheckFT(t, q.RA.Hour().Value, 12.0+29.0/60.0+6.6997/3600.0, 0.05/3600.0, "B1950 RA Error")
+
+	// RA in degrees, tab separated, other column names
+	tsv := "ID\tRA_deg\tDec_deg\nVega\t279.23473479\t38.78368896\n"
+	fn = writeFile(t, t.TempDir(), "stars.tsv", tsv)
+	spec := CSVSpec{Comma: '\t', NameCol: "id", RACol: "RA_deg", DecCol: "Dec_deg", RAUnit: au.Degree, Epoch: "ICRS"}
+	err = bsc.ReadCSV(fn, spec)
+	if err != nil {
+		fmt.Println("ReadCSV error: ", err)
+		t.Fail()
+	}
+	v, _ := bsc.GetSource("vega")
+	th.CheckFT(t, v.RA.Hour().Value, 279.23473479/15.0, 1e-9, "Degree RA Error")
+
+	err = bsc.ReadCSV(fn, DefaultCSVSpec())
+	th.CheckErrorNil(t, err, "Expected missing column error")
+}
+
+func TestReadVOTable(t *testing.T) {
+	fn := writeFile(t, t.TempDir(), "hip.vot", voCat)
+	bsc := make(BSC)
+	err := bsc.ReadVOTable(fn)
+	if err != nil {
+		fmt.Println("ReadVOTable error: ", err)
+		t.Fail()
+		return
+	}
+	checkArcturus(t, bsc, "69673", 20.0)
+	s, _ := bsc.GetSource("69673")
+	th.CheckS(t, s.Epoch, "ICRS J1991.25", "COOSYS epoch Error")
+	th.CheckFT(t, s.PMRA.ArcSecond().Value, -1.093, 0.002, "PMRA unit Error")
+}
+
+func TestLoadMixedCatalogs(t *testing.T) {
+	dir := t.TempDir()
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"YBSC", writeFile(t, dir, "bsc5.dat", bsc5Cat)})
+	AddCatalog(CatalogSource{"HIP", writeFile(t, dir, "hip_main.dat", hipCat)})
+	AddCatalog(CatalogSource{"VO", writeFile(t, dir, "hip.vot", voCat)})
+	AddCatalogFormat(CatalogSource{"Mine", writeFile(t, dir, "mine.txt", csvCat)}, CSVFormat)
+	bsc, err := LoadDefaultBSC()
+	if err != nil {
+		fmt.Println("LoadCatalogs error: ", err)
+		t.Fail()
+	}
+	for _, n := range []string{"ZetPav", "AlpBoo", "HIP69673", "69673", "Arcturus", "3C273"} {
+		if _, err := bsc.GetSource(n); err != nil {
+			fmt.Println("Mixed catalogs missing ", n)
+			t.Fail()
+		}
+	}
+
+	ClearCatalogs()
+	AddCatalog(CatalogSource{"Mine", filepath.Join(dir, "mine.txt")})
+	th.CheckErrorNil(t, bsc.LoadCatalogs(), "Expected unknown format error")
+}
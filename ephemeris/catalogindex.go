@@ -0,0 +1,376 @@
+// Spatial index and cone search over catalog sources
+package ephemeris
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+const (
+	// margin added to the search cap of box queries for rounding, radians
+	boxMargin = 1e-9
+	// allowance for precession, nutation and parallax when bounding an
+	// az/el query by the zenith's catalog position, degrees
+	zenithMargin = 2.0
+)
+
+// SourceFilter selects catalog sources in index queries.
+type SourceFilter func(BSCdata) bool
+
+// BrighterThan selects sources with a magnitude below mag.
+func BrighterThan(mag float64) SourceFilter {
+	return func(d BSCdata) bool { return d.Magnitude < mag }
+}
+
+// WithTag selects sources carrying tag.
+func WithTag(tag string) SourceFilter {
+	return func(d BSCdata) bool { return d.HasTag(tag) }
+}
+
+// InCatalog selects sources loaded from the catalog named catalog.
+func InCatalog(catalog string) SourceFilter {
+	return func(d BSCdata) bool { return strings.EqualFold(d.Catalog, catalog) }
+}
+
+// SourceMatch is a source found by an index query. Key is its key in the
+// BSC, Position its position in the coordinates of the query, RA/Dec or
+// Az/El, and Separation its distance from the query position, zero for box
+// queries.
+type SourceMatch struct {
+	Key        string
+	Source     BSCdata
+	Position   au.AngleCoord
+	Separation au.Angle
+}
+
+// indexEntry is a source in a CatalogIndex.
+type indexEntry struct {
+	key string
+	d   BSCdata
+	v   [3]float64
+}
+
+// kdNode is a node of the k-d tree, splitting on axis at its entry.
+type kdNode struct {
+	entry       int
+	axis        int
+	left, right int
+}
+
+// CatalogIndex is a k-d tree over the unit vectors of the J2000 positions
+// of catalog sources. It holds a copy of the sources, so it should be
+// rebuilt when more catalogs are loaded.
+type CatalogIndex struct {
+	entries []indexEntry
+	nodes   []kdNode
+	root    int
+	backend Backend
+}
+
+// NewCatalogIndex builds the index of the sources in bsc.
+func NewCatalogIndex(bsc *BSC) *CatalogIndex {
+	ci := &CatalogIndex{root: -1, backend: defaultBackend}
+	if bsc == nil {
+		return ci
+	}
+	keys := make([]string, 0, len(*bsc))
+	for k := range *bsc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ids := make([]int, len(keys))
+	for idx, k := range keys {
+		d := (*bsc)[k]
+		ci.entries = append(ci.entries, indexEntry{key: k, d: d, v: au.NewRaDecCoordA(d.RA, d.DEC).UnitVector()})
+		ids[idx] = idx
+	}
+	ci.nodes = make([]kdNode, 0, len(ids))
+	ci.root = ci.build(ids, 0)
+	return ci
+}
+
+// SetBackend sets the Backend computing the positions of BoxAzEl, by
+// default NOVASBackend().
+func (ci *CatalogIndex) SetBackend(be Backend) {
+	ci.backend = be
+}
+
+// LoadCatalogsIndex loads the added catalogs, as LoadCatalogs, and returns
+// the index of bsc once loaded.
+func (bsc *BSC) LoadCatalogsIndex() (*CatalogIndex, error) {
+	err := bsc.LoadCatalogs()
+	return NewCatalogIndex(bsc), err
+}
+
+// build builds the subtree of the entries ids, splitting on the median.
+func (ci *CatalogIndex) build(ids []int, depth int) int {
+	if len(ids) == 0 {
+		return -1
+	}
+	axis := depth % 3
+	sort.Slice(ids, func(i, j int) bool { return ci.entries[ids[i]].v[axis] < ci.entries[ids[j]].v[axis] })
+	m := len(ids) / 2
+	n := len(ci.nodes)
+	ci.nodes = append(ci.nodes, kdNode{entry: ids[m], axis: axis})
+	left := ci.build(ids[:m], depth+1)
+	right := ci.build(ids[m+1:], depth+1)
+	ci.nodes[n].left, ci.nodes[n].right = left, right
+	return n
+}
+
+// Len returns the number of sources in the index.
+func (ci *CatalogIndex) Len() int {
+	return len(ci.entries)
+}
+
+// chord2 returns the squared chord length between unit vectors.
+func chord2(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// keep reports whether d passes every filter.
+func keep(d BSCdata, filters []SourceFilter) bool {
+	for _, f := range filters {
+		if !f(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// within calls found for every entry within the squared chord c2 of q.
+func (ci *CatalogIndex) within(n int, q [3]float64, c2 float64, found func(int)) {
+	if n < 0 {
+		return
+	}
+	node := ci.nodes[n]
+	e := ci.entries[node.entry]
+	if chord2(e.v, q) <= c2 {
+		found(node.entry)
+	}
+	diff := q[node.axis] - e.v[node.axis]
+	near, far := node.left, node.right
+	if diff > 0.0 {
+		near, far = far, near
+	}
+	ci.within(near, q, c2, found)
+	if diff*diff <= c2 {
+		ci.within(far, q, c2, found)
+	}
+}
+
+// inCap returns the entries within radius (radians) of q that pass filters.
+func (ci *CatalogIndex) inCap(q [3]float64, radius float64, filters []SourceFilter) []int {
+	c2 := 4.0
+	if radius < math.Pi {
+		c := 2.0 * math.Sin(radius/2.0)
+		c2 = c * c
+	}
+	var ids []int
+	ci.within(ci.root, q, c2, func(idx int) {
+		if keep(ci.entries[idx].d, filters) {
+			ids = append(ids, idx)
+		}
+	})
+	return ids
+}
+
+// match returns the SourceMatch for entry idx seen from center.
+func (ci *CatalogIndex) match(idx int, center au.AngleCoord) SourceMatch {
+	e := ci.entries[idx]
+	pos := au.NewRaDecCoordA(e.d.RA, e.d.DEC)
+	return SourceMatch{Key: e.key, Source: e.d, Position: pos, Separation: center.Separation(pos)}
+}
+
+// sortMatches sorts by separation, then key.
+func sortMatches(ms []SourceMatch) {
+	sort.Slice(ms, func(i, j int) bool {
+		si, sj := ms[i].Separation.Radian().Value, ms[j].Separation.Radian().Value
+		if si != sj {
+			return si < sj
+		}
+		return ms[i].Key < ms[j].Key
+	})
+}
+
+// ConeSearch returns the sources within radius of the J2000 RA/Dec
+// center that pass every filter, nearest first.
+func (ci *CatalogIndex) ConeSearch(center au.AngleCoord, radius au.Angle, filters ...SourceFilter) []SourceMatch {
+	r := radius.Radian().Value
+	var ms []SourceMatch
+	for _, idx := range ci.inCap(center.UnitVector(), r, filters) {
+		m := ci.match(idx, center)
+		// the chord test is exact up to rounding
+		if m.Separation.Radian().Value <= r {
+			ms = append(ms, m)
+		}
+	}
+	sortMatches(ms)
+	return ms
+}
+
+// Nearest returns up to n sources nearest the J2000 RA/Dec center that
+// pass every filter, nearest first.
+func (ci *CatalogIndex) Nearest(center au.AngleCoord, n int, filters ...SourceFilter) []SourceMatch {
+	if n <= 0 {
+		return nil
+	}
+	q := center.UnitVector()
+	type cand struct {
+		idx int
+		c2  float64
+	}
+	// the best so far, nearest first
+	var best []cand
+	var visit func(int)
+	visit = func(nd int) {
+		if nd < 0 {
+			return
+		}
+		node := ci.nodes[nd]
+		e := ci.entries[node.entry]
+		if c2 := chord2(e.v, q); (len(best) < n || c2 < best[len(best)-1].c2) && keep(e.d, filters) {
+			pos := sort.Search(len(best), func(i int) bool { return best[i].c2 > c2 })
+			best = append(best, cand{})
+			copy(best[pos+1:], best[pos:])
+			best[pos] = cand{idx: node.entry, c2: c2}
+			if len(best) > n {
+				best = best[:n]
+			}
+		}
+		diff := q[node.axis] - e.v[node.axis]
+		near, far := node.left, node.right
+		if diff > 0.0 {
+			near, far = far, near
+		}
+		visit(near)
+		if len(best) < n || diff*diff < best[len(best)-1].c2 {
+			visit(far)
+		}
+	}
+	visit(ci.root)
+	ms := make([]SourceMatch, len(best))
+	for idx, b := range best {
+		ms[idx] = ci.match(b.idx, center)
+	}
+	sortMatches(ms)
+	return ms
+}
+
+// lonRange is a range of longitude, radians, which may wrap through 0.
+type lonRange struct {
+	min, width float64
+}
+
+// newLonRange returns the range from min to max going east. Equal values
+// of different angles, such as 0h to 24h, are the whole circle.
+func newLonRange(min, max au.Angle) lonRange {
+	lo, hi := min.Radian().Value, max.Radian().Value
+	w := math.Mod(hi-lo, 2.0*math.Pi)
+	if w < 0.0 {
+		w += 2.0 * math.Pi
+	}
+	if w == 0.0 && hi != lo {
+		w = 2.0 * math.Pi
+	}
+	return lonRange{min: lo, width: w}
+}
+
+// contains reports whether lon (radians) is in the range.
+func (lr lonRange) contains(lon float64) bool {
+	d := math.Mod(lon-lr.min, 2.0*math.Pi)
+	if d < 0.0 {
+		d += 2.0 * math.Pi
+	}
+	return d <= lr.width
+}
+
+// boundingCap returns the center and radius (radians) of a cap holding
+// the box lr by lat0 to lat1 (radians). Along the parallels the distance
+// from the center grows with the longitude difference, so the farthest
+// point is a corner or, for boxes wider than 12h, the point of a meridian
+// edge farthest from the center.
+func boundingCap(lr lonRange, lat0, lat1 float64) ([3]float64, float64) {
+	clon := lr.min + lr.width/2.0
+	clat := (lat0 + lat1) / 2.0
+	center := au.NewRaDecCoord(au.Radian, clon, au.Radian, clat)
+	lats := []float64{lat0, lat1}
+	// on a meridian edge the cosine of the distance is
+	// sin(clat) sin(lat) + cos(clat) cos(w/2) cos(lat), least at
+	lat := math.Atan2(-math.Sin(clat), -math.Cos(clat)*math.Cos(lr.width/2.0))
+	if lat > lat0 && lat < lat1 {
+		lats = append(lats, lat)
+	}
+	radius := 0.0
+	for _, lat := range lats {
+		p := au.NewRaDecCoord(au.Radian, lr.min, au.Radian, lat)
+		radius = math.Max(radius, center.Separation(p).Radian().Value)
+	}
+	return center.UnitVector(), radius + boxMargin
+}
+
+// Box returns the J2000 sources with RA from raMin east to raMax, wrapping
+// through 0h if raMin > raMax, and Dec from decMin to decMax, that pass
+// every filter, sorted by key.
+func (ci *CatalogIndex) Box(raMin, raMax, decMin, decMax au.Angle, filters ...SourceFilter) []SourceMatch {
+	lr := newLonRange(raMin, raMax)
+	d0, d1 := decMin.Radian().Value, decMax.Radian().Value
+	q, r := boundingCap(lr, d0, d1)
+	var ms []SourceMatch
+	for _, idx := range ci.inCap(q, r, filters) {
+		e := ci.entries[idx]
+		dec := e.d.DEC.Radian().Value
+		if dec >= d0 && dec <= d1 && lr.contains(e.d.RA.Radian().Value) {
+			ms = append(ms, SourceMatch{Key: e.key, Source: e.d, Position: au.NewRaDecCoordA(e.d.RA, e.d.DEC),
+				Separation: au.NewAngle(au.Degree, 0.0)})
+		}
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Key < ms[j].Key })
+	return ms
+}
+
+// BoxAzEl returns the sources at azimuth from azMin east to azMax and
+// elevation from elMin to elMax as seen from loc at t that pass every
+// filter, sorted by key. Positions are the unrefracted topocentric Az/El
+// in degrees from the index Backend.
+func (ci *CatalogIndex) BoxAzEl(loc Location, t time.Time, azMin, azMax, elMin, elMax au.Angle,
+	filters ...SourceFilter) ([]SourceMatch, error) {
+	// sources above elMin are within 90 - elMin of the zenith, whose RA
+	// and Dec of date are the LST and latitude
+	zenith := au.NewRaDecCoordA(au.LST(t, loc.Longitude), loc.Latitude)
+	r := math.Min(90.0-elMin.Degree().Value+zenithMargin, 180.0)
+	lr := newLonRange(azMin, azMax)
+	e0, e1 := elMin.Degree().Value, elMax.Degree().Value
+	ep := NewInstant(t)
+	var ms []SourceMatch
+	for _, idx := range ci.inCap(zenith.UnitVector(), r*math.Pi/180.0, filters) {
+		e := ci.entries[idx]
+		pos, err := sourceAzEl(ci.backend, e.key, e.d, ep, loc)
+		if err != nil {
+			return nil, err
+		}
+		el := pos.El().Value
+		if el >= e0 && el <= e1 && lr.contains(pos.Az().Radian().Value) {
+			ms = append(ms, SourceMatch{Key: e.key, Source: e.d, Position: pos, Separation: au.NewAngle(au.Degree, 0.0)})
+		}
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Key < ms[j].Key })
+	return ms, nil
+}
+
+// sourceAzEl returns the unrefracted topocentric az/el, in degrees, of the
+// catalog source d from loc at ep, computed by be.
+func sourceAzEl(be Backend, name string, d BSCdata, ep Instant, loc Location) (au.AngleCoord, error) {
+	tg := starTarget(starInfo(name, d))
+	ra, dec, _, err := tg.topo(be, ep, loc)
+	if err != nil {
+		return au.AngleCoord{}, err
+	}
+	az, zd := be.Horizon(ep, loc, ra, dec)
+	return au.NewAzElCoord(au.Degree, az, 90.0-zd), nil
+}
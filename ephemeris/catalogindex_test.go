@@ -0,0 +1,194 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// loadIndex returns the default catalog and its index.
+func loadIndex() (BSC, *CatalogIndex) {
+	bsc := DefaultBSC()
+	return bsc, NewCatalogIndex(&bsc)
+}
+
+// keys returns the sorted keys of ms.
+func keys(ms []SourceMatch) []string {
+	ks := make([]string, len(ms))
+	for idx, m := range ms {
+		ks[idx] = m.Key
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// checkKeys compares the keys found by a query with those expected.
+func checkKeys(t *testing.T, got []SourceMatch, exp []string, msg string) {
+	sort.Strings(exp)
+	gk := keys(got)
+	if fmt.Sprint(gk) != fmt.Sprint(exp) {
+		fmt.Println(msg, " got ", gk, " expected ", exp)
+		t.Fail()
+	}
+}
+
+func TestConeSearch(t *testing.T) {
+	bsc, ci := loadIndex()
+	th.CheckI(t, ci.Len(), len(bsc), "Index size Error")
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		center := au.NewRaDecCoord(au.Hour, rnd.Float64()*24.0, au.Degree, math.Asin(2.0*rnd.Float64()-1.0)*180.0/math.Pi)
+		radius := au.NewAngle(au.Degree, 1.0+rnd.Float64()*20.0)
+		var exp, bright []string
+		for k, d := range bsc {
+			if center.Separation(au.NewRaDecCoordA(d.RA, d.DEC)).Radian().Value <= radius.Radian().Value {
+				exp = append(exp, k)
+				if d.Magnitude < 4.0 {
+					bright = append(bright, k)
+				}
+			}
+		}
+		ms := ci.ConeSearch(center, radius)
+		checkKeys(t, ms, exp, "ConeSearch")
+		for idx := 1; idx < len(ms); idx++ {
+			if ms[idx].Separation.Radian().Value < ms[idx-1].Separation.Radian().Value {
+				fmt.Println("ConeSearch results out of order")
+				t.Fail()
+			}
+		}
+		checkKeys(t, ci.ConeSearch(center, radius, BrighterThan(4.0)), bright, "ConeSearch brighter than 4")
+	}
+
+	// Arcturus is the brightest star within 10 degrees of itself
+	a := bsc["alpboo"]
+	ms := ci.ConeSearch(au.NewRaDecCoordA(a.RA, a.DEC), au.NewAngle(au.Degree, 10.0), BrighterThan(0.0))
+	checkKeys(t, ms, []string{"alpboo"}, "Arcturus")
+	th.CheckFT(t, ms[0].Separation.Radian().Value, 0.0, 1e-12, "Zero separation Error")
+	th.CheckI(t, len(ci.ConeSearch(au.NewRaDecCoordA(a.RA, a.DEC), au.NewAngle(au.Degree, 10.0), WithTag("none"))), 0, "Tag filter Error")
+}
+
+func TestNearest(t *testing.T) {
+	bsc, ci := loadIndex()
+	rnd := rand.New(rand.NewSource(2))
+	for n := 0; n < 20; n++ {
+		center := au.NewRaDecCoord(au.Hour, rnd.Float64()*24.0, au.Degree, rnd.Float64()*180.0-90.0)
+		type ks struct {
+			k   string
+			sep float64
+		}
+		var all []ks
+		for k, d := range bsc {
+			if d.Magnitude < 5.0 {
+				all = append(all, ks{k, center.Separation(au.NewRaDecCoordA(d.RA, d.DEC)).Radian().Value})
+			}
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].sep < all[j].sep })
+		ms := ci.Nearest(center, 5, BrighterThan(5.0))
+		th.CheckI(t, len(ms), 5, "Nearest count Error")
+		for idx, m := range ms {
+			th.CheckFT(t, m.Separation.Radian().Value, all[idx].sep, 1e-12, "Nearest separation Error")
+		}
+	}
+	th.CheckI(t, len(ci.Nearest(au.NewRaDecCoord(au.Hour, 0.0, au.Degree, 0.0), 0)), 0, "Nearest zero Error")
+	th.CheckI(t, len(NewCatalogIndex(nil).Nearest(au.NewRaDecCoord(au.Hour, 0.0, au.Degree, 0.0), 3)), 0, "Empty index Error")
+}
+
+func TestBox(t *testing.T) {
+	bsc, ci := loadIndex()
+	for _, b := range [][4]float64{
+		{10.0, 12.0, -30.0, 10.0},
+		{22.0, 2.0, 20.0, 60.0}, // through 0h
+		{0.0, 24.0, 70.0, 90.0}, // polar cap
+		{5.0, 5.5, -90.0, 90.0},
+		{20.0, 6.0, -10.0, 80.0}, // wider than 90 degrees
+		{2.0, 18.0, -60.0, 30.0}, // wider than 180 degrees
+	} {
+		lr := newLonRange(au.NewAngle(au.Hour, b[0]), au.NewAngle(au.Hour, b[1]))
+		var exp []string
+		for k, d := range bsc {
+			dec := d.DEC.Degree().Value
+			if dec >= b[2] && dec <= b[3] && lr.contains(d.RA.Radian().Value) {
+				exp = append(exp, k)
+			}
+		}
+		ms := ci.Box(au.NewAngle(au.Hour, b[0]), au.NewAngle(au.Hour, b[1]), au.NewAngle(au.Degree, b[2]), au.NewAngle(au.Degree, b[3]))
+		if len(exp) == 0 {
+			fmt.Println("Box test should find sources: ", b)
+			t.Fail()
+		}
+		checkKeys(t, ms, exp, fmt.Sprint("Box ", b))
+	}
+}
+
+func TestBoundingCap(t *testing.T) {
+	rad := math.Pi / 180.0
+	for _, b := range [][4]float64{
+		{0.0, 1.0, 10.0, 11.0},
+		{300.0, 60.0, -10.0, 80.0},
+		{30.0, 270.0, -60.0, 30.0},
+		{0.0, 350.0, -85.0, 5.0},
+		{0.0, 360.0, -20.0, 20.0},
+	} {
+		lr := newLonRange(au.NewAngle(au.Degree, b[0]), au.NewAngle(au.Degree, b[1]))
+		q, r := boundingCap(lr, b[2]*rad, b[3]*rad)
+		center := au.NewAngleCoordVector(q, au.Degree, au.Degree)
+		far := 0.0
+		for i := 0; i <= 200; i++ {
+			for j := 0; j <= 200; j++ {
+				lon := lr.min + lr.width*float64(i)/200.0
+				lat := (b[2] + (b[3]-b[2])*float64(j)/200.0) * rad
+				s := center.Separation(au.NewRaDecCoord(au.Radian, lon, au.Radian, lat)).Radian().Value
+				far = math.Max(far, s)
+			}
+		}
+		if far > r {
+			fmt.Println("Box outside its bounding cap: ", b, far, r)
+			t.Fail()
+		}
+		// and the cap is no bigger than it needs to be
+		th.CheckFT(t, r, far, 1e-3, fmt.Sprint("Bounding cap radius Error ", b))
+	}
+}
+
+func TestBoxAzEl(t *testing.T) {
+	bsc, ci := loadIndex()
+	loc, _ := Site("OVRO")
+	ti := time.Date(2024, 3, 20, 8, 0, 0, 0, time.UTC)
+	ms, err := ci.BoxAzEl(loc, ti, au.NewAngle(au.Degree, 300.0), au.NewAngle(au.Degree, 60.0),
+		au.NewAngle(au.Degree, 30.0), au.NewAngle(au.Degree, 90.0), BrighterThan(3.0))
+	if err != nil {
+		fmt.Println("BoxAzEl error: ", err)
+		t.Fail()
+		return
+	}
+	// brute force with SimpleTrack
+	si := loc.onSurface()
+	var exp []string
+	for k, d := range bsc {
+		if d.Magnitude >= 3.0 {
+			continue
+		}
+		track, _ := SimpleTrack(si, k, &bsc)
+		az, el, _ := track(ti)
+		if el >= 30.0 && (az >= 300.0 || az <= 60.0) {
+			exp = append(exp, k)
+		}
+	}
+	if len(exp) == 0 {
+		fmt.Println("BoxAzEl test should find sources")
+		t.Fail()
+	}
+	checkKeys(t, ms, exp, "BoxAzEl")
+	for _, m := range ms {
+		if el := m.Position.El().Degree().Value; el < 30.0 {
+			fmt.Println("BoxAzEl position below the box: ", m.Key, el)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Catalog hot reload
+package ephemeris
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// default interval between checks of the catalog files
+	defaultReloadInterval = 5 * time.Second
+)
+
+// fileStamp identifies a version of a catalog file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// CatalogManager holds the catalogs added with AddCatalog and reloads them
+// when their files change. A reload is parsed and validated in full before
+// it replaces the current BSC, so readers always see a complete catalog,
+// and a reload that fails leaves the previous good catalog in place.
+type CatalogManager struct {
+	files []catalogFile
+
+	// loadMu serializes reloads from load to swap, so that an older
+	// catalog never replaces a newer one
+	loadMu sync.Mutex
+
+	mu       sync.RWMutex
+	bsc      *BSC
+	stamps   map[string]fileStamp
+	lastErr  error
+	interval time.Duration
+	validate func(*BSC) error
+	onReload func(*BSC)
+	onError  func(error)
+}
+
+// NewCatalogManager loads the catalogs added so far, in search order, and
+// returns a manager for them. Catalogs added later are not managed.
+func NewCatalogManager() (*CatalogManager, error) {
+	cm := &CatalogManager{
+		files:    catalogFiles(),
+		stamps:   make(map[string]fileStamp),
+		interval: defaultReloadInterval,
+	}
+	bsc, stamps, err := cm.load()
+	if err != nil {
+		return nil, err
+	}
+	cm.bsc, cm.stamps = bsc, stamps
+	return cm, nil
+}
+
+// SetInterval sets how often Run checks the catalog files. It takes effect
+// when Run is next called.
+func (cm *CatalogManager) SetInterval(d time.Duration) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.interval = d
+}
+
+// SetValidator sets a check run on a reloaded catalog, after the built in
+// ones, before it replaces the current one.
+func (cm *CatalogManager) SetValidator(f func(*BSC) error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.validate = f
+}
+
+// OnReload sets the function called with each new catalog.
+func (cm *CatalogManager) OnReload(f func(*BSC)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.onReload = f
+}
+
+// OnError sets the function called when a reload fails.
+func (cm *CatalogManager) OnError(f func(error)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.onError = f
+}
+
+// BSC returns the current catalog. It is replaced, never changed, by
+// reloads, so it must not be changed by the caller either.
+func (cm *CatalogManager) BSC() *BSC {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.bsc
+}
+
+// GetSource looks up src in the current catalog.
+func (cm *CatalogManager) GetSource(src string) (BSCdata, error) {
+	return cm.BSC().GetSource(src)
+}
+
+// LastError returns the error of the last reload, nil if it succeeded.
+func (cm *CatalogManager) LastError() error {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.lastErr
+}
+
+// stamp returns the current stamps of the catalog files.
+func (cm *CatalogManager) stamp() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	for _, c := range cm.files {
+		fi, err := os.Stat(c.Filename)
+		if err != nil {
+			return nil, err
+		}
+		stamps[c.Filename] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return stamps, nil
+}
+
+// validateBSC checks that every source has a valid position.
+func validateBSC(bsc *BSC) error {
+	for k, d := range *bsc {
+		ra, dec := d.RA.Radian().Value, d.DEC.Degree().Value
+		if math.IsNaN(ra) || math.IsInf(ra, 0) || math.IsNaN(dec) || dec < -90.0 || dec > 90.0 {
+			emsg := fmt.Sprintf("Source %s from %s has an invalid position", k, d.Provenance)
+			return errors.New(emsg)
+		}
+	}
+	return nil
+}
+
+// load parses and validates the catalogs, returning them with the stamps
+// of the files taken before reading them, which are returned on a parse or
+// validation error too.
+func (cm *CatalogManager) load() (*BSC, map[string]fileStamp, error) {
+	stamps, err := cm.stamp()
+	if err != nil {
+		return nil, nil, err
+	}
+	bsc := make(BSC)
+	err = bsc.loadCatalogs(cm.files)
+	if err != nil {
+		return nil, stamps, err
+	}
+	err = validateBSC(&bsc)
+	if err != nil {
+		return nil, stamps, err
+	}
+	cm.mu.RLock()
+	validate := cm.validate
+	cm.mu.RUnlock()
+	if validate != nil {
+		err = validate(&bsc)
+		if err != nil {
+			return nil, stamps, err
+		}
+	}
+	return &bsc, stamps, nil
+}
+
+// Reload parses the catalogs and, if they are valid, replaces the current
+// catalog. On error the current catalog is kept and the error reported.
+func (cm *CatalogManager) Reload() error {
+	cm.loadMu.Lock()
+	bsc, err := cm.reload()
+	cm.loadMu.Unlock()
+	cm.notify(bsc, err)
+	return err
+}
+
+// reload loads the catalogs and swaps them in if they are valid. The
+// caller must hold loadMu.
+func (cm *CatalogManager) reload() (*BSC, error) {
+	bsc, stamps, err := cm.load()
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.lastErr = err
+	if err == nil {
+		cm.bsc = bsc
+	}
+	if stamps != nil {
+		// a bad file is not read again until it changes
+		cm.stamps = stamps
+	}
+	return bsc, err
+}
+
+// notify calls OnError with err, or OnReload with bsc if err is nil,
+// outside the locks so that they may call back into the manager.
+func (cm *CatalogManager) notify(bsc *BSC, err error) {
+	cm.mu.RLock()
+	onReload, onError := cm.onReload, cm.onError
+	cm.mu.RUnlock()
+	if err != nil {
+		if onError != nil {
+			onError(err)
+		}
+		return
+	}
+	if onReload != nil {
+		onReload(bsc)
+	}
+}
+
+// Check reloads the catalogs if any of their files has changed since the
+// last reload, returning whether it did. A file that cannot be read is an
+// error.
+func (cm *CatalogManager) Check() (bool, error) {
+	cm.loadMu.Lock()
+	stamps, err := cm.stamp()
+	if err != nil {
+		cm.mu.Lock()
+		cm.lastErr = err
+		cm.mu.Unlock()
+		cm.loadMu.Unlock()
+		cm.notify(nil, err)
+		return false, err
+	}
+	cm.mu.RLock()
+	changed := false
+	for fn, s := range stamps {
+		if old, ok := cm.stamps[fn]; !ok || !old.modTime.Equal(s.modTime) || old.size != s.size {
+			changed = true
+		}
+	}
+	cm.mu.RUnlock()
+	if !changed {
+		cm.loadMu.Unlock()
+		return false, nil
+	}
+	bsc, err := cm.reload()
+	cm.loadMu.Unlock()
+	cm.notify(bsc, err)
+	return err == nil, err
+}
+
+// Run checks the catalog files every interval, reloading them when they
+// change, until ctx is cancelled. Errors are reported through OnError.
+func (cm *CatalogManager) Run(ctx context.Context) {
+	cm.mu.RLock()
+	interval := cm.interval
+	cm.mu.RUnlock()
+	tk := time.NewTicker(interval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tk.C:
+			cm.Check()
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package ephemeris
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// rewrite writes content to fn with a modification time of mt, so that
+// the change is seen whatever the file system's time resolution.
+func rewrite(t *testing.T, fn, content string, mt time.Time) {
+	err := os.WriteFile(fn, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(fn, mt, mt)
+}
+
+func TestCatalogManager(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "radio.cat")
+	mt := time.Now().Add(-time.Hour)
+	rewrite(t, fn, "J2000 3C273 12:29:06.70 +02:03:08.6\n", mt)
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"Radio", fn})
+	cm, err := NewCatalogManager()
+	if err != nil {
+		fmt.Println("NewCatalogManager error: ", err)
+		t.Fail()
+		return
+	}
+	var reloads, errs int
+	cm.OnReload(func(*BSC) { reloads++ })
+	cm.OnError(func(error) { errs++ })
+	_, err = cm.GetSource("3C273")
+	if err != nil {
+		fmt.Println("3C273 not loaded")
+		t.Fail()
+	}
+
+	// nothing changed
+	changed, _ := cm.Check()
+	if changed {
+		fmt.Println("Reloaded an unchanged catalog")
+		t.Fail()
+	}
+
+	// an edit is picked up
+	old := cm.BSC()
+	rewrite(t, fn, "J2000 3C273 12:29:06.70 +02:03:08.6\nJ2000 3C279 12:56:11.17 -05:47:21.5\n", mt.Add(time.Minute))
+	changed, err = cm.Check()
+	if !changed || err != nil {
+		fmt.Println("Edit not reloaded: ", err)
+		t.Fail()
+	}
+	_, err = cm.GetSource("3C279")
+	if err != nil {
+		fmt.Println("3C279 not loaded")
+		t.Fail()
+	}
+	th.CheckI(t, len(*old), 1, "Reload changed the previous catalog")
+	th.CheckI(t, reloads, 1, "OnReload count Error")
+
+	// a bad edit keeps the last good catalog, and is not retried
+	rewrite(t, fn, "J2000 3C286 13:31:08.29\n", mt.Add(2*time.Minute))
+	changed, err = cm.Check()
+	th.CheckErrorNil(t, err, "Expected parse error")
+	if changed || cm.LastError() == nil {
+		fmt.Println("Bad edit reported as reloaded")
+		t.Fail()
+	}
+	th.CheckI(t, len(*cm.BSC()), 2, "Bad edit replaced the catalog")
+	th.CheckI(t, errs, 1, "OnError count Error")
+	changed, err = cm.Check()
+	if changed || err != nil {
+		fmt.Println("Bad edit retried: ", err)
+		t.Fail()
+	}
+
+	// as does a catalog failing validation
+	cm.SetValidator(func(bsc *BSC) error {
+		if len(*bsc) > 2 {
+			return errors.New("too many sources")
+		}
+		return nil
+	})
+	rewrite(t, fn, "J2000 3C273 12:29:06.70 +02:03:08.6\nJ2000 3C279 12:56:11.17 -05:47:21.5\nJ2000 3C286 13:31:08.29 +30:30:33.0\n", mt.Add(3*time.Minute))
+	_, err = cm.Check()
+	th.CheckErrorNil(t, err, "Expected validation error")
+	th.CheckI(t, len(*cm.BSC()), 2, "Invalid catalog replaced the catalog")
+
+	cm.SetValidator(nil)
+	rewrite(t, fn, "J2000 3C286 13:31:08.29 +30:30:33.0\n", mt.Add(4*time.Minute))
+	cm.Check()
+	if cm.LastError() != nil {
+		fmt.Println("Fixed catalog still in error: ", cm.LastError())
+		t.Fail()
+	}
+	_, err = cm.GetSource("3C273")
+	th.CheckErrorNil(t, err, "Removed source still found")
+
+	// a missing file is an error too
+	os.Remove(fn)
+	_, err = cm.Check()
+	th.CheckErrorNil(t, err, "Expected missing file error")
+	_, err = cm.GetSource("3C286")
+	if err != nil {
+		fmt.Println("Missing file dropped the catalog")
+		t.Fail()
+	}
+
+	ClearCatalogs()
+	AddCatalog(CatalogSource{"Missing", fn})
+	_, err = NewCatalogManager()
+	th.CheckErrorNil(t, err, "Expected missing catalog error")
+}
+
+func TestCatalogManagerRun(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "radio.cat")
+	mt := time.Now().Add(-time.Hour)
+	rewrite(t, fn, "J2000 3C273 12:29:06.70 +02:03:08.6\n", mt)
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"Radio", fn})
+	cm, err := NewCatalogManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.SetInterval(5 * time.Millisecond)
+	reloaded := make(chan struct{}, 1)
+	cm.OnReload(func(*BSC) {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cm.Run(ctx)
+
+	// readers always find a complete catalog while it is swapped
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := cm.GetSource("3C273"); err != nil {
+					fmt.Println("Reader lost 3C273 during reload")
+					t.Fail()
+					return
+				}
+			}
+		}()
+	}
+	rewrite(t, fn, "J2000 3C273 12:29:06.70 +02:03:08.6\nJ2000 3C279 12:56:11.17 -05:47:21.5\n", mt.Add(time.Minute))
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		fmt.Println("Run did not reload the catalog")
+		t.Fail()
+	}
+	close(stop)
+	wg.Wait()
+	if _, err := cm.GetSource("3C279"); err != nil {
+		fmt.Println("3C279 not loaded by Run")
+		t.Fail()
+	}
+}
+
+func TestCatalogManagerConcurrent(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "radio.cat")
+	mt := time.Now().Add(-time.Hour)
+	rewrite(t, fn, "J2000 3C273 12:29:06.70 +02:03:08.6\n", mt)
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"Radio", fn})
+	cm, err := NewCatalogManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reloads and checks never overlap
+	var active, most int32
+	cm.SetValidator(func(*BSC) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	// and the callbacks may call back into the manager
+	cm.OnReload(func(*BSC) { cm.LastError() })
+
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 10; k++ {
+				cm.Reload()
+			}
+		}()
+		go func(n int) {
+			defer wg.Done()
+			for k := 0; k < 10; k++ {
+				rewrite(t, fn, "J2000 3C273 12:29:06.70 +02:03:08.6\n", mt.Add(time.Duration(10*n+k)*time.Second))
+				cm.Check()
+			}
+		}(n)
+		// the catalog registry is changed while sources are looked up
+		go func(n int) {
+			defer wg.Done()
+			for k := 0; k < 10; k++ {
+				SetSourceCat(fmt.Sprintf("src%d", k), "Radio")
+				AddCatalogFormat(CatalogSource{fmt.Sprintf("Text%d", n), fn}, TextFormat)
+				bsc := make(BSC)
+				bsc.LoadCatalogs()
+				bsc.GetSource("src1")
+			}
+		}(n)
+	}
+	wg.Wait()
+	th.CheckI(t, int(most), 1, "Overlapping reloads Error")
+	if _, err := cm.GetSource("3C273"); err != nil {
+		fmt.Println("3C273 lost: ", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,101 @@
+// Default catalog and site table embedded in the module
+package ephemeris
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultCatalogName is the catalog name of the sources of DefaultBSC.
+	DefaultCatalogName = "default"
+	// file name recorded in the provenance of the default sources
+	defaultCatalogFile = "brightSourceCatalog.yml"
+)
+
+// siteStr is a site as written in sites.yml.
+type siteStr struct {
+	Latitude_deg  float64 `yaml:"Latitude_deg"`
+	Longitude_deg float64 `yaml:"Longitude_deg"`
+	Height_m      float64 `yaml:"Height_m"`
+}
+
+var (
+	//go:embed brightSourceCatalog.yml
+	defaultCatalog []byte
+
+	//go:embed sites.yml
+	defaultSites []byte
+
+	sites map[string]siteStr
+)
+
+func init() {
+	err := yaml.UnmarshalStrict(defaultSites, &sites)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// DefaultBSC returns the bright source catalog embedded in the module, so
+// that it is available whatever the working directory. Each call returns a
+// new BSC, which the caller may change.
+func DefaultBSC() BSC {
+	bsc := make(BSC)
+	err := bsc.parseYaml(defaultCatalog, defaultCatalogFile)
+	if err != nil {
+		panic(err)
+	}
+	for k, d := range bsc {
+		d.Catalog = DefaultCatalogName
+		bsc[k] = d
+	}
+	return bsc
+}
+
+// LoadDefaultBSC returns DefaultBSC overlaid with the added catalogs,
+// loaded as LoadCatalogs. Sources in the added catalogs take priority; the
+// default ones they hide are still found as default:source.
+func LoadDefaultBSC() (BSC, error) {
+	bsc := make(BSC)
+	err := bsc.LoadCatalogs()
+	for k, d := range DefaultBSC() {
+		if _, ok := bsc[k]; ok {
+			k = qualifiedKey(DefaultCatalogName, k)
+		}
+		bsc[k] = d
+	}
+	return bsc, err
+}
+
+// Site returns the Location of the named site from the table embedded in
+// the module, ignoring case.
+func Site(name string) (Location, error) {
+	for k, s := range sites {
+		if strings.EqualFold(k, name) {
+			return Location{
+				Latitude:  au.NewAngle(au.Degree, s.Latitude_deg),
+				Longitude: au.NewAngle(au.Degree, s.Longitude_deg),
+				Height:    au.NewLength(au.Meter, s.Height_m),
+			}, nil
+		}
+	}
+	emsg := fmt.Sprintf("Unknown site: %s", name)
+	return Location{}, errors.New(emsg)
+}
+
+// SiteNames returns the names of the sites in the table, sorted.
+func SiteNames() []string {
+	names := make([]string, 0, len(sites))
+	for k := range sites {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
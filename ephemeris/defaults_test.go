@@ -0,0 +1,86 @@
+package ephemeris
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestDefaultBSC(t *testing.T) {
+	// the embedded catalog does not depend on the working directory
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+	bsc := DefaultBSC()
+	a, err := bsc.GetSource("AlpBoo")
+	if err != nil {
+		fmt.Println("DefaultBSC has no AlpBoo: ", err)
+		t.Fail()
+	}
+	th.CheckS(t, a.Catalog, DefaultCatalogName, "Default catalog name Error")
+	th.CheckS(t, a.Frame, ICRSFrame, "Default catalog frame Error")
+	ClearCatalogs()
+	file, err := LoadDefaultBSC()
+	if err != nil {
+		fmt.Println("LoadDefaultBSC error: ", err)
+		t.Fail()
+	}
+	th.CheckI(t, len(bsc), len(file), "Default catalog size Error")
+	th.CheckFT(t, a.RA.Hour().Value, file["alpboo"].RA.Hour().Value, 0.0, "Default catalog RA Error")
+
+	// callers get their own copy
+	delete(bsc, "alpboo")
+	if _, ok := DefaultBSC()["alpboo"]; !ok {
+		fmt.Println("DefaultBSC shares its map")
+		t.Fail()
+	}
+}
+
+func TestLoadDefaultBSC(t *testing.T) {
+	radio := filepath.Join(t.TempDir(), "radio.cat")
+	os.WriteFile(radio, []byte("J2000 AlpBoo 14:15:40.00 +19:11:00.0\nB1950 3C273 12:26:33.246 +02:19:43.53\n"), 0644)
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"Radio", radio})
+	bsc, err := LoadDefaultBSC()
+	if err != nil {
+		fmt.Println("LoadDefaultBSC error: ", err)
+		t.Fail()
+	}
+	th.CheckI(t, len(bsc), len(DefaultBSC())+2, "Overlay size Error")
+	a, _ := bsc.GetSource("AlpBoo")
+	th.CheckS(t, a.Catalog, "Radio", "Overlay priority Error")
+	d, _ := bsc.GetSource("default:AlpBoo")
+	th.CheckS(t, d.Catalog, DefaultCatalogName, "Hidden default source Error")
+	z, _ := bsc.GetSource("ZetPav")
+	th.CheckS(t, z.Catalog, DefaultCatalogName, "Default source Error")
+	th.CheckI(t, len(bsc.Duplicates()), 1, "Overlay duplicates Error")
+
+	ClearCatalogs()
+	AddCatalog(CatalogSource{"Missing", "missing.yml"})
+	bsc, err = LoadDefaultBSC()
+	th.CheckErrorNil(t, err, "Expected missing catalog error")
+	th.CheckI(t, len(bsc), len(DefaultBSC()), "Default catalog after error Error")
+}
+
+func TestSite(t *testing.T) {
+	loc, err := Site("ovro")
+	if err != nil {
+		fmt.Println("Site error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, loc.Latitude.Degree().Value, 37.2339, 1e-9, "OVRO latitude Error")
+	th.CheckFT(t, loc.Longitude.Degree().Value, -118.2817, 1e-9, "OVRO longitude Error")
+	th.CheckFT(t, loc.Height.Meter().Value, 1222.0, 1e-9, "OVRO height Error")
+	_, err = Site("Arecibo")
+	th.CheckErrorNil(t, err, "Expected unknown site error")
+	for _, n := range SiteNames() {
+		if _, err := Site(n); err != nil {
+			fmt.Println("Site not found: ", n)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Distance, light time and range rate of solar system targets
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	au "github.com/rh-codebase/astrogo/astrounit"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+// rangeRateStep is half the interval over which the topocentric distance
+// is differenced for the range rate.
+const rangeRateStep = 10 * time.Second
+
+// Range is the distance and line of sight motion of a solar system target.
+type Range struct {
+	// Geocentric and Topocentric are the distances from the center of the
+	// Earth and from the observer, in AU; use Kilometer() for km.
+	Geocentric  au.Length
+	Topocentric au.Length
+	// LightTime is the one-way light time from the target to the observer.
+	LightTime time.Duration
+	// RangeRate is the rate of change of the topocentric distance in km/s,
+	// positive when the target recedes.
+	RangeRate float64
+}
+
+// solarSystem tells whether the target is a planet, the Sun, the Moon or a
+// minor body, which have a distance.
+func (tg *target) solarSystem() bool {
+	return tg.planet || tg.minor != nil
+}
+
+// geocentric returns the geocentric distance of the target in AU at ep.
+func (tg *target) geocentric(be Backend, ep Instant) (float64, error) {
+	if tg.minor != nil {
+		g, err := tg.minor.astrometric(ep.JDTT)
+		if err != nil {
+			return 0.0, err
+		}
+		return norm(g), nil
+	}
+	_, _, dis, err := be.Apparent(ep, tg.body())
+	return dis, err
+}
+
+// distances returns the Range of a solar system target at ti as seen from
+// loc, computed by be. The range rate is the central difference of the
+// topocentric distance over 2*rangeRateStep.
+func (tg *target) distances(be Backend, ti time.Time, loc Location) (Range, error) {
+	var rng Range
+	if !tg.solarSystem() {
+		emsg := fmt.Sprintf("%s is not a solar system target", tg.name)
+		return rng, errors.New(emsg)
+	}
+	ep := NewInstant(ti)
+	geo, err := tg.geocentric(be, ep)
+	if err != nil {
+		return rng, err
+	}
+	_, _, topo, err := tg.topo(be, ep, loc)
+	if err != nil {
+		return rng, err
+	}
+	_, _, d0, err := tg.topo(be, NewInstant(ti.Add(-rangeRateStep)), loc)
+	if err != nil {
+		return rng, err
+	}
+	_, _, d1, err := tg.topo(be, NewInstant(ti.Add(rangeRateStep)), loc)
+	if err != nil {
+		return rng, err
+	}
+	rng.Geocentric = au.NewLength(au.AstronomicalUnit, geo)
+	rng.Topocentric = au.NewLength(au.AstronomicalUnit, topo)
+	rng.LightTime = time.Duration(topo / cAUPerDay * at.SecondPerDay * float64(time.Second))
+	rng.RangeRate = au.NewLength(au.AstronomicalUnit, d1-d0).Kilometer().Value / (2.0 * rangeRateStep.Seconds())
+	return rng, nil
+}
+
+// GetRange returns the geocentric and topocentric distance, light time and
+// range rate of a solar system source. Other sources return an error.
+func (e *Ephemeris) GetRange() (Range, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.update()
+	if err != nil {
+		return Range{}, err
+	}
+	if !e.hasRange {
+		rng, err := e.target.distances(e.backend, e.t, e.location)
+		if err != nil {
+			return Range{}, err
+		}
+		e.rng, e.hasRange = rng, true
+	}
+	return e.rng, nil
+}
+
+// RangeTrack returns a function giving the Range of a solar system source
+// from the observer at si for a time, as SimpleTrack does for az/el.
+// sourceName and opts are as in SimpleTrack.
+func RangeTrack(si nov.OnSurface, sourceName string, bsc *BSC, opts ...Option) (func(time.Time) (Range, error), error) {
+	tg, err := resolveTarget(sourceName, bsc)
+	if err != nil {
+		return nil, err
+	}
+	if !tg.solarSystem() {
+		emsg := fmt.Sprintf("%s is not a solar system target", sourceName)
+		return nil, errors.New(emsg)
+	}
+	be := applyOptions(opts).backend
+	loc := siteLocation(si)
+	return func(ti time.Time) (Range, error) {
+		return tg.distances(be, ti, loc)
+	}, nil
+}
+
+// TrackPoint is the position of a source at a time as seen by an observer:
+// its azimuth and elevation in degrees and, for solar system sources, its
+// Range. Range is nil for other sources.
+type TrackPoint struct {
+	Az, El float64
+	Range  *Range
+}
+
+// TrackWithRange returns a function giving the TrackPoint of a source for
+// a time, combining SimpleTrack and, for solar system sources, RangeTrack.
+// sourceName and opts are as in SimpleTrack.
+func TrackWithRange(si nov.OnSurface, sourceName string, bsc *BSC, opts ...Option) (func(time.Time) (TrackPoint, error), error) {
+	tg, err := resolveTarget(sourceName, bsc)
+	if err != nil {
+		return nil, err
+	}
+	be := applyOptions(opts).backend
+	loc := siteLocation(si)
+	return func(ti time.Time) (TrackPoint, error) {
+		var tp TrackPoint
+		ep := NewInstant(ti)
+		ra, dec, _, err := tg.topo(be, ep, loc)
+		if err != nil {
+			return tp, err
+		}
+		az, zd := be.Horizon(ep, loc, ra, dec)
+		tp.Az, tp.El = az, 90.0-zd
+		if tg.solarSystem() {
+			rng, err := tg.distances(be, ti, loc)
+			if err != nil {
+				return tp, err
+			}
+			tp.Range = &rng
+		}
+		return tp, nil
+	}, nil
+}
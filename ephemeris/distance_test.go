@@ -0,0 +1,166 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+func TestRange(t *testing.T) {
+	bsc := DefaultBSC()
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	var si nov.OnSurface
+	nov.MakeOnSurface(37.2339, -118.282, 1222., 0.0, 0.0, &si)
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+
+	// geocentric distance limits, km
+	limits := []struct {
+		name     string
+		min, max float64
+	}{
+		{Moon, 356000.0, 407000.0},
+		{Sun, 1.471e8, 1.521e8},
+		{Jupiter, 5.9e8, 9.7e8},
+	}
+	for _, l := range limits {
+		e, err := NewEphemeris(l.name, loc, &bsc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SetTime(ti)
+		rng, err := e.GetRange()
+		if err != nil {
+			fmt.Println("GetRange error: ", err)
+			t.Fail()
+			continue
+		}
+		th.CheckS(t, rng.Topocentric.UnitString(), "au", l.name+" distance unit Error")
+		geo := rng.Geocentric.Kilometer().Value
+		topo := rng.Topocentric.Kilometer().Value
+		if geo < l.min || geo > l.max {
+			fmt.Println("Unexpected geocentric distance: ", l.name, geo)
+			t.Fail()
+		}
+		// the observer is at most an Earth radius from the geocenter
+		if math.Abs(geo-topo) > 6400.0 {
+			fmt.Println("Unexpected topocentric distance: ", l.name, geo, topo)
+			t.Fail()
+		}
+		th.CheckFT(t, rng.LightTime.Seconds(), topo/299792.458, 1e-6, l.name+" light time Error")
+
+		// the same from RangeTrack, and the range rate against the distance
+		// an hour apart
+		track, err := RangeTrack(si, l.name, &bsc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r0, err := track(ti)
+		if err != nil {
+			t.Fatal(err)
+		}
+		th.CheckFT(t, r0.Topocentric.Value, rng.Topocentric.Value, 1e-15, l.name+" RangeTrack distance Error")
+		th.CheckFT(t, r0.RangeRate, rng.RangeRate, 1e-9, l.name+" RangeTrack range rate Error")
+		r1, _ := track(ti.Add(-30 * time.Minute))
+		r2, _ := track(ti.Add(30 * time.Minute))
+		rate := (r2.Topocentric.Kilometer().Value - r1.Topocentric.Kilometer().Value) / 3600.0
+		th.CheckFT(t, rng.RangeRate, rate, 0.01, l.name+" range rate Error")
+
+		// and from TrackWithRange, with the az/el of SimpleTrack
+		full, err := TrackWithRange(si, l.name, &bsc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tp, err := full(ti)
+		if err != nil || tp.Range == nil {
+			fmt.Println("TrackWithRange error: ", l.name, err)
+			t.Fail()
+			continue
+		}
+		th.CheckFT(t, tp.Range.Topocentric.Value, rng.Topocentric.Value, 1e-15, l.name+" TrackWithRange distance Error")
+		th.CheckFT(t, tp.Range.RangeRate, rng.RangeRate, 1e-9, l.name+" TrackWithRange range rate Error")
+		azel, _ := e.GetAzEl()
+		th.CheckFT(t, tp.Az, azel.Az().Degree().Value, 1e-9, l.name+" TrackWithRange Az Error")
+		th.CheckFT(t, tp.El, azel.El().Degree().Value, 1e-9, l.name+" TrackWithRange El Error")
+	}
+
+	// the geocentric lunar range rate is under 0.1 km/s, and the rotation of
+	// the Earth adds at most 0.37 km/s at the site
+	e, _ := NewEphemeris(Moon, loc, &bsc)
+	e.SetTime(ti)
+	rng, _ := e.GetRange()
+	azel, _ := e.GetAzEl()
+	fmt.Printf("Moon az, el= %6.3f, %5.3f range rate= %.4f km/s\n",
+		azel.Az().Degree().Value, azel.El().Degree().Value, rng.RangeRate)
+	if math.Abs(rng.RangeRate) > 0.5 {
+		fmt.Println("Unexpected lunar range rate: ", rng.RangeRate)
+		t.Fail()
+	}
+
+	// a new time invalidates the cached range
+	e.SetTime(ti.Add(time.Hour))
+	rng2, err := e.GetRange()
+	if err != nil || rng2.Topocentric.Value == rng.Topocentric.Value {
+		fmt.Println("GetRange was not recomputed: ", err)
+		t.Fail()
+	}
+
+	// stars have no distance
+	e, _ = NewEphemeris("alpboo", loc, &bsc)
+	e.SetTime(ti)
+	_, err = e.GetRange()
+	th.CheckErrorNil(t, err, "GetRange expected error for a star")
+	_, err = RangeTrack(si, "alpboo", &bsc)
+	th.CheckErrorNil(t, err, "RangeTrack expected error for a star")
+	full, err := TrackWithRange(si, "alpboo", &bsc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp, err := full(ti)
+	if err != nil || tp.Range != nil {
+		fmt.Println("TrackWithRange gave a star a range: ", err)
+		t.Fail()
+	}
+	track, _ := SimpleTrack(si, "alpboo", &bsc)
+	az, el, _ := track(ti)
+	th.CheckFT(t, tp.Az, az, 1e-9, "TrackWithRange star Az Error")
+	th.CheckFT(t, tp.El, el, 1e-9, "TrackWithRange star El Error")
+}
+
+func TestMinorBodyRange(t *testing.T) {
+	defer ClearMinorBodies()
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	mb, err := NewMinorBody(marsElements(t, ti), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Mars does not perturb its clone
+	mb.Self = 4
+	AddMinorBody(mb)
+	var si nov.OnSurface
+	nov.MakeOnSurface(37.2339, -118.282, 1222., 0.0, 0.0, &si)
+	track, err := RangeTrack(si, "Mars Clone", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtrack, _ := RangeTrack(si, Mars, nil)
+	r1, err := track(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := mtrack(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the minor body distances include the light time, NOVAS does not
+	th.CheckFT(t, r1.Geocentric.Value, r2.Geocentric.Value, 1e-4, "Minor body geocentric distance Error")
+	th.CheckFT(t, r1.Topocentric.Value, r2.Topocentric.Value, 1e-4, "Minor body topocentric distance Error")
+	th.CheckFT(t, r1.RangeRate, r2.RangeRate, 0.05, "Minor body range rate Error")
+}
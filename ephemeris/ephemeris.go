@@ -1,14 +1,20 @@
 /*
  *   Wraps the NOVAS library and any other ephemeris related functions into
  *   a simple class.
- *   For most cases one instantiates this class with a source, and
- *   alternatively a fixed station
- *           e := NewEphemeris("3c273","BIMA-9")
+ *   For most cases one instantiates this class with a source, an observer
+ *   location and the catalog used to resolve source names
+ *           e, err := NewEphemeris("alpboo", loc, &bsc)
  *   after which the time should be set (time needs to be set *after* a source)
- *           e.setMJD()
+ *           e.SetMJD(mjd)  or  e.SetTime(time.Now())
  *   and the RA/DEC or AZ/EL can be retrieved
- *           double ra=e.getRa()
- *           double az=e.getAz()
+ *           ra, err := e.GetRa()
+ *           az, err := e.GetAz()
+ *   as well as, for solar system sources, the distances and range rate
+ *           rng, err := e.GetRange()
+ *   Positions are only recomputed when the time, source, location, weather,
+ *   frequency or refraction setting changes. An Ephemeris is safe for
+ *   concurrent use, so one process can keep several of them (one per
+ *   antenna and source) alive at once.
  *   Caveat: for more accurate observations you need to feed the ephemeris
  *   a more detailed atmosphere description (pressure, temperature, humidity)
  *   as well as an observing frequency.
@@ -29,8 +35,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	at "github.com/rh-codebase/astrogo/astrotime"
 	au "github.com/rh-codebase/astrogo/astrounit"
 	nov "github.com/rh-codebase/novasgo/novas"
 	"gopkg.in/yaml.v2"
@@ -65,6 +73,17 @@ type RaDec struct {
 	Dec_deg float64 `yaml:"dec"`
 }
 
+// FrameLonLat is a serialized position in any astrounit Frame, e.g.
+// "frame: galactic\nlon: 10.0\nlat: -0.5". Lon and Lat are in degrees,
+// including RA for the equatorial frames. Epoch is the equinox of an
+// eclipticofdate position.
+type FrameLonLat struct {
+	Frame   string    `yaml:"frame"`
+	Lon_deg float64   `yaml:"lon"`
+	Lat_deg float64   `yaml:"lat"`
+	Epoch   time.Time `yaml:"epoch,omitempty"`
+}
+
 const (
 	fk5 = "FK5"
 	// solar system body strings
@@ -79,214 +98,586 @@ const (
 	Neptune = "neptune"
 	Uranus  = "uranus"
 	Pluto   = "pluto"
+
+	// accuracy passed to NOVAS. 0 is full accuracy.
+	accuracy = int16(0)
 )
 
+// Ephemeris tracks a single source from a single location. All of its
+// state (source, site, weather, frequency, refraction) is owned by the
+// instance and guarded by a mutex.
+type Ephemeris struct {
+	mu          sync.Mutex
+	sourceName  string
+	target      target
+	hasTarget   bool
+	location    Location
+	wx          Wx
+	observeFreq float64 // Hz
+	doRefract   bool
+	backend     Backend
+	t           time.Time
+	recompute   bool
+
+	// cached results for t
+	ra, dec, dis float64 // hours, degrees, AU
+	az, el       float64 // degrees
+	eop          at.EOP
+	rng          Range // computed on demand by GetRange
+	hasRange     bool
+}
+
+// target is a source resolved into the form a Backend places: a major
+// body by NOVAS number, a minor body or a star.
+type target struct {
+	name   string
+	planet bool
+	number int16
+	star   StarInfo
+	minor  *MinorBody
+}
+
 var (
-	wx                     Wx
-	site                   nov.OnSurface
-	radec                  RaDec
-	location               Location
-	relhum                 float64 // percent
-	recompute              bool
-	doRefract              bool
-	is_cat                 bool
-	use_source             bool
-	observefreq            float64 // Hz
-	tjd                    float64
-	mjd                    float64
-	deltat, xxpole, y_pole float64
-	is_eph                 bool
-	dra, ddec, daz, del    float64
-	doppler                float64
-	offsetMode             int16
-	sourceName             string
-	ra2000                 float64
-	fixed                  bool
+	// std backs the package level Set/Get functions.
+	std = &Ephemeris{recompute: true, backend: defaultBackend}
+	// novasMu serializes calls into NOVAS, which keeps its ephemeris
+	// file buffers in package globals.
+	novasMu sync.Mutex
 )
 
+// NewEphemeris returns an Ephemeris for sourceName as seen from loc.
+// sourceName may be a planet, a minor body added with AddMinorBody, a
+// serialized RaDec or FrameLonLat, or a source in bsc.
+func NewEphemeris(sourceName string, loc Location, bsc *BSC) (*Ephemeris, error) {
+	return NewEphemerisWithBackend(sourceName, loc, bsc, defaultBackend)
+}
+
+// NewEphemerisWithBackend returns an Ephemeris for sourceName as seen from
+// loc whose positions are computed by be, as NOVASBackend() or
+// NewGoBackend().
+func NewEphemerisWithBackend(sourceName string, loc Location, bsc *BSC, be Backend) (*Ephemeris, error) {
+	e := &Ephemeris{recompute: true, backend: be}
+	e.SetLocation(loc)
+	err := e.SetSource(sourceName, bsc)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SetSource changes the source being tracked.
+func (e *Ephemeris) SetSource(sourceName string, bsc *BSC) error {
+	tg, err := resolveTarget(sourceName, bsc)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sourceName = sourceName
+	e.target = tg
+	e.hasTarget = true
+	e.recompute = true
+	return nil
+}
+
+// SetSourceCoord changes the source being tracked to the fixed position
+// fc, which may be in any astrounit Frame. name is reported by GetSource.
+func (e *Ephemeris) SetSourceCoord(name string, fc au.FrameCoord) error {
+	tg, err := coordTarget(name, fc)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sourceName = name
+	e.target = tg
+	e.hasTarget = true
+	e.recompute = true
+	return nil
+}
+
+// Backend returns the backend computing the positions.
+func (e *Ephemeris) Backend() Backend {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.backend
+}
+
+// SetBackend changes the backend computing the positions.
+func (e *Ephemeris) SetBackend(be Backend) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.backend = be
+	e.recompute = true
+}
+
+// GetSource returns the name of the source being tracked.
+func (e *Ephemeris) GetSource() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.sourceName
+}
+
 // SetWeather sets the Wx structure. rh is relative humidity in percent
-func SetWeather(ap au.Pressure, at au.Temperature, rh float64) {
-	recompute = true
+func (e *Ephemeris) SetWeather(ap au.Pressure, at au.Temperature, rh float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recompute = true
 
-	wx.AtmPressure = ap
-	wx.AtmTemperature = at
-	wx.RelHumidityPct = rh
-	site.Pressure = ap.ToMillibar().Value
-	site.Temperature = at.ToCelsius().Value
+	e.wx.AtmPressure = ap
+	e.wx.AtmTemperature = at
 	// percent
 	// @todo warn here if input values were bad
-	relhum = rh
+	e.wx.RelHumidityPct = rh
+}
+
+// GetWeather returns the Wx structure.
+func (e *Ephemeris) GetWeather() Wx {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.wx
+}
+
+// SetFreq sets the observation frequency in Hz. That is, the frequency of the
+// radiation being collected by a sensor.
+func (e *Ephemeris) SetFreq(freq float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recompute = true
+	e.observeFreq = freq // Hz
+}
+
+// GetFreq returns the frequency of observed radiation in Hz.
+func (e *Ephemeris) GetFreq() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.observeFreq // Hz
+}
+
+// SetRefraction controls whether or not to apply refraction to the
+// elevation obtained from NOVAS.
+func (e *Ephemeris) SetRefraction(refract bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recompute = true
+	e.doRefract = refract
+}
+
+// Refract computes and applies the refraction angle given the elevation
+// angle computed by NOVAS using the weather, frequency and location of e.
+func (e *Ephemeris) Refract(el au.Angle) (au.Angle, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.refract(el)
+}
+
+// refract is Refract without locking.
+func (e *Ephemeris) refract(el au.Angle) (au.Angle, error) {
+	if e.doRefract {
+		return applyRefraction(e.wx, e.observeFreq, e.location.Height, el)
+	}
+	return el, nil
+}
+
+// refractor returns a copy of e's refraction settings as a function, for
+// use without holding e.mu. Caller must hold e.mu.
+func (e *Ephemeris) refractor() func(au.Angle) (au.Angle, error) {
+	if !e.doRefract {
+		return nil
+	}
+	wx, freq, height := e.wx, e.observeFreq, e.location.Height
+	return func(el au.Angle) (au.Angle, error) {
+		return applyRefraction(wx, freq, height, el)
+	}
+}
+
+// applyRefraction adds the refraction correction for wx, freq (Hz) and the
+// site height to the elevation el.
+func applyRefraction(wx Wx, freq float64, height au.Length, el au.Angle) (au.Angle, error) {
+	ra, err := au.ComputeRefractionCorrection(wx.AtmTemperature, wx.AtmPressure,
+		wx.RelHumidityPct, el, freq, height)
+	if err != nil {
+		return el, err
+	}
+	corrected := el.Add(ra)
+	return corrected, nil
+}
+
+// SetLocation sets the observer's location.
+func (e *Ephemeris) SetLocation(loc Location) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recompute = true
+	e.location = loc
+}
+
+// GetLocation returns the observer's location.
+func (e *Ephemeris) GetLocation() Location {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.location
+}
+
+// SetTime sets the time at which positions are computed. Nothing is
+// recomputed if t has not changed.
+func (e *Ephemeris) SetTime(t time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !t.Equal(e.t) {
+		e.t = t
+		e.recompute = true
+	}
+}
+
+// SetMJD sets the time at which positions are computed from an MJD (UTC).
+func (e *Ephemeris) SetMJD(mjd float64) {
+	e.SetTime(at.FromMJD(mjd))
+}
+
+// GetTime returns the time at which positions are computed.
+func (e *Ephemeris) GetTime() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.t
+}
+
+// GetEOP returns the Earth orientation parameters used for the current
+// positions. Extrapolated is set when the loaded EOP table does not cover
+// the time, and NoTable as well when no table is loaded (see
+// astrotime.LoadEOP) and UT1 is taken as UTC.
+func (e *Ephemeris) GetEOP() (at.EOP, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.update()
+	return e.eop, err
+}
+
+// GetRa returns the topocentric apparent right ascension.
+func (e *Ephemeris) GetRa() (au.Angle, error) {
+	rd, err := e.GetRaDec()
+	return rd.Ra(), err
+}
+
+// GetDec returns the topocentric apparent declination.
+func (e *Ephemeris) GetDec() (au.Angle, error) {
+	rd, err := e.GetRaDec()
+	return rd.Dec(), err
+}
+
+// GetRaDec returns the topocentric apparent right ascension and declination.
+func (e *Ephemeris) GetRaDec() (au.AngleCoord, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.update()
+	return au.NewRaDecCoord(au.Hour, e.ra, au.Degree, e.dec), err
+}
+
+// GetAz returns the azimuth.
+func (e *Ephemeris) GetAz() (au.Angle, error) {
+	ae, err := e.GetAzEl()
+	return ae.Az(), err
+}
+
+// GetEl returns the elevation, including refraction if enabled.
+func (e *Ephemeris) GetEl() (au.Angle, error) {
+	ae, err := e.GetAzEl()
+	return ae.El(), err
+}
+
+// GetAzEl returns the azimuth and elevation, including refraction if
+// enabled.
+func (e *Ephemeris) GetAzEl() (au.AngleCoord, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.update()
+	return au.NewAzElCoord(au.Degree, e.az, e.el), err
+}
+
+// GetLST returns the local apparent sidereal time at the observer's
+// location.
+func (e *Ephemeris) GetLST() (au.Angle, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.t.IsZero() {
+		return au.Angle{}, errors.New("Ephemeris time has not been set")
+	}
+	return e.location.LST(e.t), nil
+}
+
+// GetHourAngle returns the hour angle of the source from its topocentric
+// apparent right ascension.
+func (e *Ephemeris) GetHourAngle() (au.Angle, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.update()
+	if err != nil {
+		return au.Angle{}, err
+	}
+	return e.location.HourAngle(e.t, au.NewAngle(au.Hour, e.ra)), nil
+}
+
+// update recomputes the cached positions if anything changed since the
+// last computation. Caller must hold e.mu.
+func (e *Ephemeris) update() error {
+	if !e.recompute {
+		return nil
+	}
+	if !e.hasTarget {
+		return errors.New("Ephemeris has no source")
+	}
+	if e.t.IsZero() {
+		return errors.New("Ephemeris time has not been set")
+	}
+	ep := NewInstant(e.t)
+	ra, dec, dis, err := e.target.topo(e.backend, ep, e.location)
+	if err != nil {
+		return err
+	}
+	az, zd := e.backend.Horizon(ep, e.location, ra, dec)
+	el, err := e.refract(au.NewAngle(au.Degree, 90.0-zd))
+	if err != nil {
+		return err
+	}
+	e.ra, e.dec, e.dis = ra, dec, dis
+	e.az, e.el = az, el.Degree().Value
+	e.eop = ep.EOP
+	e.hasRange = false
+	e.recompute = false
+	return nil
+}
+
+// SetWeather sets the Wx structure of the package ephemeris. rh is relative
+// humidity in percent
+func SetWeather(ap au.Pressure, at au.Temperature, rh float64) {
+	std.SetWeather(ap, at, rh)
 }
 
 // SetFreq sets the observation frequency in Hz. That is, the frequency of the
 // radiation being collected by a sensor.
 func SetFreq(freq float64) {
-	recompute = true
-	observefreq = freq // Hz
+	std.SetFreq(freq)
 }
 
 // GetFreq returns the frequency of observed radiation in Hz.
 func GetFreq() float64 {
-	return observefreq // Hz
+	return std.GetFreq()
 }
 
 // SetRefraction controls whether or not to apply refraction to the
 // elevation obtained from NOVAS.
 func SetRefraction(refract bool) {
-	recompute = true
-	doRefract = refract
+	std.SetRefraction(refract)
 }
 
 // Refract computes and applies the refraction angle given the elevation angle
 // computed by NOVAS. Functions: SetWeather(), SetFreq(), SetLocation()
 // and SetRefraction() must be called beforehand
 func Refract(el au.Angle) (au.Angle, error) {
-	if doRefract {
-		ra, err := au.ComputeRefractionCorrection(wx.AtmTemperature, wx.AtmPressure,
-			wx.RelHumidityPct, el, GetFreq(), location.Height)
-		if err != nil {
-			return el, err
-		}
-		corrected := el.Add(ra)
-		return corrected, nil
-	}
-	return el, nil
+	return std.Refract(el)
 }
 
 func getSourceFromCatalog(src string, bsc *BSC) (StarInfo, error) {
-	var starInfo StarInfo
+	if bsc == nil {
+		emsg := fmt.Sprintf("Source %s not found: no catalog", src)
+		return StarInfo{}, errors.New(emsg)
+	}
 	star, err := bsc.GetSource(src)
 	if err != nil {
-		return starInfo, err
+		return StarInfo{}, err
 	}
-	starInfo.Name = src
+	return starInfo(src, star), nil
+}
+
+// starInfo returns the NOVAS catalog entry fields for star.
+func starInfo(name string, star BSCdata) StarInfo {
+	var starInfo StarInfo
+	starInfo.Name = name
 	starInfo.Catalog = "BSC"
 	starInfo.StarNum = 1
 	starInfo.Ra_hr = star.RA.Hour().Value
 	starInfo.Dec_deg = star.DEC.Degree().Value
 	starInfo.PMRA_masPerYr = star.PMRA.MilliArcSecond().Value
 	starInfo.PMDEC_masPerYr = star.PMDEC.MilliArcSecond().Value
-	starInfo.Parallax_mas = 0.0
-	starInfo.RadVel_kmPerSec = 0.0
+	starInfo.Parallax_mas = star.Parallax.MilliArcSecond().Value
+	starInfo.RadVel_kmPerSec = star.RadVel
+	return starInfo
+}
 
-	return starInfo, nil
+// starTarget returns the target for a catalog star.
+func starTarget(starInfo StarInfo) target {
+	var tg target
+	tg.name = starInfo.Name
+	tg.star = starInfo
+	return tg
+}
+
+// planetNumbers are the NOVAS numbers of the major bodies.
+var planetNumbers = map[string]int16{
+	Mercury: 1, Venus: 2, Mars: 4, Jupiter: 5, Saturn: 6,
+	Uranus: 7, Neptune: 8, Pluto: 9, Sun: 10, Moon: 11,
 }
 
 func isPlanet(name string) bool {
 	switch strings.ToLower(name) {
-	case Sun, Moon, Mercury, Venus, Mars, Jupiter, Neptune, Uranus, Pluto:
+	case Sun, Moon, Mercury, Venus, Mars, Jupiter, Saturn, Neptune, Uranus, Pluto:
 		return true
 	default:
 		return false
 	}
 }
 
-// isRaDec is a helper to determine if the string value came from
+// parseRaDec is a helper to determine if the string value came from
 // a serilazied radec structure
-func isRaDec(rd string) bool {
+func parseRaDec(rd string) (RaDec, bool) {
+	var radec RaDec
 	err := yaml.UnmarshalStrict([]byte(rd), &radec)
+	if err != nil {
+		return radec, false
+	}
 	if radec.Ra_hr < 0.0 || radec.Ra_hr > 24.0 {
 		fmt.Printf("Invalid Ra value. Must be in hours: %f", radec.Ra_hr)
-		return false
+		return radec, false
 	}
+	return radec, true
+}
+
+// parseFrameLonLat is a helper to determine if the string value came from
+// a serialized FrameLonLat structure. The error is for a FrameLonLat with
+// an unknown frame.
+func parseFrameLonLat(fl string) (au.FrameCoord, bool, error) {
+	var fll FrameLonLat
+	err := yaml.UnmarshalStrict([]byte(fl), &fll)
 	if err != nil {
-		return false
-	} else {
-		return true
+		return au.FrameCoord{}, false, nil
 	}
+	f, err := au.ParseFrame(fll.Frame)
+	if err != nil {
+		return au.FrameCoord{}, true, err
+	}
+	ac := au.NewRaDecCoord(au.Degree, fll.Lon_deg, au.Degree, fll.Lat_deg)
+	return au.NewFrameCoordEpoch(f, ac, fll.Epoch), true, nil
 }
 
-// SImpleTrack returns a function to allow updating a source's position in
-// az.el coordiantes based on time. OnSurface represents the observer's location
-// on Earth and the sourcename must be in the BSC catalog.
-func SimpleTrack(si nov.OnSurface, sourceName string, bsc *BSC) (func(time.Time) (float64, float64, error), error) {
+// coordTarget returns the target for the fixed position fc.
+func coordTarget(name string, fc au.FrameCoord) (target, error) {
+	var tg target
+	tg.name = name
+	rd, err := fc.ICRS()
+	if err != nil {
+		return tg, err
+	}
+	tg.star = fixedStar("RaDec", rd.Ra().Hour().Value, rd.Dec().Degree().Value)
+	return tg, nil
+}
 
+// fixedStar returns the catalog entry of the fixed ICRS position ra, in
+// hours, and dec, in degrees, with no motion or distance.
+func fixedStar(name string, ra, dec float64) StarInfo {
+	return StarInfo{Name: name, Catalog: "BSC", StarNum: 1, Ra_hr: ra, Dec_deg: dec}
+}
+
+// resolveTarget turns a source name into a target. The name may be a
+// planet, a minor body added with AddMinorBody, a serialized RaDec or
+// FrameLonLat, or a source in bsc.
+func resolveTarget(sourceName string, bsc *BSC) (target, error) {
+	var tg target
+	tg.name = sourceName
 	src := strings.ToLower(sourceName)
-	// not really number of leapseconds. this should just be called 'leap'
-	// since it represents the number of seconds TAI is ahead of UTC.
-	var Leap int16 = 37
-	var ut1Utc float64 = -0.17442
-
-	var catEntry nov.CatEntry
-	var source nov.Object
-	planet := isPlanet(src)
-	if planet {
-		switch strings.ToLower(src) {
-		case Sun:
-			nov.MakeObject(0, 10, Sun, &catEntry, &source)
-		case Moon:
-			nov.MakeObject(0, 11, Moon, &catEntry, &source)
-		case Mercury:
-			nov.MakeObject(0, 1, Mercury, &catEntry, &source)
-		case Venus:
-			nov.MakeObject(0, 2, Venus, &catEntry, &source)
-		case Mars:
-			nov.MakeObject(0, 4, Mars, &catEntry, &source)
-		case Jupiter:
-			nov.MakeObject(0, 5, Jupiter, &catEntry, &source)
-		case Saturn:
-			nov.MakeObject(0, 6, Saturn, &catEntry, &source)
-		case Uranus:
-			nov.MakeObject(0, 7, Uranus, &catEntry, &source)
-		case Neptune:
-			nov.MakeObject(0, 8, Neptune, &catEntry, &source)
-		case Pluto:
-			nov.MakeObject(0, 9, Pluto, &catEntry, &source)
-		default:
-			emsg := fmt.Sprintf("Unknown Planet name: %s", sourceName)
-			return nil, errors.New(emsg)
+	if isPlanet(src) {
+		tg.planet = true
+		tg.number = planetNumbers[src]
+	} else if mb, ok := GetMinorBody(src); ok {
+		tg.minor = mb
+	} else if radec, ok := parseRaDec(src); ok {
+		tg.star = fixedStar("RaDec", radec.Ra_hr, radec.Dec_deg)
+	} else if fc, ok, err := parseFrameLonLat(sourceName); ok {
+		if err != nil {
+			return tg, err
 		}
-	} else if isRaDec(src) {
-		fmt.Println("SimpleTrack: Making RaDec source. Ra_hr= ", radec.Ra_hr, " Dec_deg= ", radec.Dec_deg)
-		// NOTE: 2nd field must be "BSC".
-		nov.MakeCatEntry("RaDec", "BSC", 1, radec.Ra_hr, radec.Dec_deg,
-			0.0, 0.0, 0.0, 0.0, &catEntry)
+		return coordTarget(sourceName, fc)
 	} else { // last gasp to see if src is in a catalog
-		//var catEntry nov.CatEntry
 		// J2000 AlpBoo    14:15:39.70 +19:10:57.0 -0:0:00.0729 -0:0:01.998 # -0.0
 		starInfo, err := getSourceFromCatalog(sourceName, bsc)
 		if err != nil {
-			return nil, err
+			return tg, err
 		}
-		nov.MakeCatEntry(starInfo.Name, starInfo.Catalog, starInfo.StarNum,
-			starInfo.Ra_hr, starInfo.Dec_deg,
-			starInfo.PMRA_masPerYr, starInfo.PMDEC_masPerYr,
-			starInfo.Parallax_mas, starInfo.RadVel_kmPerSec, &catEntry)
+		return starTarget(starInfo), nil
 	}
+	return tg, nil
+}
+
+// Instant holds the Julian dates and Earth orientation used by a Backend
+// for a single moment.
+type Instant struct {
+	JDTT   float64
+	JDUT1  float64
+	DeltaT float64 // TT - UT1, seconds
+	EOP    at.EOP
+}
 
+// NewInstant returns the TT and UT1 Julian dates for ti along with
+// DeltaT = TT - UT1 in seconds. TT-UTC comes from the astrotime leap second
+// table, UT1-UTC, polar motion and the celestial pole offsets from the
+// astrotime EOP table.
+func NewInstant(ti time.Time) Instant {
+	var ep Instant
+	ti = ti.UTC()
+	year := int16(ti.Year())
+	month := int16(ti.Month())
+	day := int16(ti.Day())
+	hr := ti.Hour()
+	min := ti.Minute()
+	sec := ti.Second()
+	ns := ti.Nanosecond()
+	hour := float64(hr) + float64(min)/60. + (float64(sec)+float64(ns)/1e9)/3600.
+	jdUTC := nov.JulianDate(year, month, day, hour)
+	ep.EOP = at.GetEOP(ti)
+	ttUtc := at.TTminusUTC(ti)
+	ep.JDTT = jdUTC + ttUtc/at.SecondPerDay
+	ep.JDUT1 = jdUTC + ep.EOP.UT1UTC/at.SecondPerDay
+	ep.DeltaT = ttUtc - ep.EOP.UT1UTC
+	return ep
+}
+
+// body returns the Backend form of a planet or star target.
+func (tg *target) body() Body {
+	if tg.planet {
+		return Body{Name: tg.name, Number: tg.number}
+	}
+	return Body{Name: tg.name, Star: tg.star}
+}
+
+// topo returns the topocentric apparent RA (hours), Dec (degrees) of the
+// target at ep as seen from loc, computed by be. dis is the distance in AU
+// for planets and minor bodies and 0 otherwise.
+func (tg *target) topo(be Backend, ep Instant, loc Location) (ra, dec, dis float64, err error) {
+	if tg.minor != nil {
+		return tg.minor.topo(be, ep, loc)
+	}
+	return be.Topocentric(ep, tg.body(), loc)
+}
+
+// SImpleTrack returns a function to allow updating a source's position in
+// az.el coordiantes based on time. OnSurface represents the observer's location
+// on Earth and the sourcename must be in the BSC catalog. WithBackend
+// selects the Backend. TrackWithRange also gives the distance of solar
+// system sources.
+func SimpleTrack(si nov.OnSurface, sourceName string, bsc *BSC, opts ...Option) (func(time.Time) (float64, float64, error), error) {
+
+	tg, err := resolveTarget(sourceName, bsc)
+	if err != nil {
+		return nil, err
+	}
+
+	be := applyOptions(opts).backend
+	loc := siteLocation(si)
 	return func(ti time.Time) (az float64, el float64, err error) {
-		year := int16(ti.Year())
-		month := int16(ti.Month())
-		day := int16(ti.Day())
-		hr := ti.Hour()
-		min := ti.Minute()
-		sec := ti.Second()
-		ns := ti.Nanosecond()
-		hour := float64(hr) + float64(min)/60. + (float64(sec)+float64(ns)/1e9)/3600.
-		jdUTC := nov.JulianDate(year, month, day, hour)
-		//		fmt.Println(jdUTC)
-		jdTT := jdUTC + (float64(Leap)+32.184)/86400.
-		jdUT1 := jdUTC + ut1Utc/86400.0
-		deltaT := 32.184 + float64(Leap) - ut1Utc
-		var ra, dec, dis float64
-		var accuracy int16 = 0
-		if planet {
-			err = nov.TopoPlanet(jdTT, &source, deltaT, &si, accuracy, &ra, &dec, &dis)
-			if err != nil {
-				return az, el, err
-			}
-		} else {
-			fmt.Println("SimpleTrack: catentry: ", catEntry)
-			err = nov.TopoStar(jdTT, deltaT, &catEntry, &si, accuracy, &ra, &dec)
-			if err != nil {
-				return az, el, err
-			}
+		ep := NewInstant(ti)
+		ra, dec, _, err := tg.topo(be, ep, loc)
+		if err != nil {
+			return az, el, err
 		}
-		var zd, rar, decr float64
-		doRefraction := int16(0)
-		//fmt.Println("jdUT1, deltaT, ra, dec: ", jdUT1, deltaT, ra, dec)
-		nov.Equ2hor(jdUT1, deltaT, accuracy, 0.0, 0.0, &si, ra, dec, doRefraction, &zd, &az, &rar, &decr)
-
+		az, zd := be.Horizon(ep, loc, ra, dec)
 		return az, 90.0 - zd, nil
 	}, nil
 
@@ -308,21 +699,29 @@ func (l *Location) GetHeight() au.Length {
   }
 */
 
-// TODO: Implement or remove
-func SetSourceCat(sourceName, catalogName string) {
+// LST returns the local apparent sidereal time at the location for the
+// time t.
+func (l *Location) LST(t time.Time) au.Angle {
+	return au.LST(t, l.Longitude)
+}
 
+// HourAngle returns the hour angle at the location of a right ascension of
+// date ra for the time t.
+func (l *Location) HourAngle(t time.Time, ra au.Angle) au.Angle {
+	return au.HourAngle(t, l.Longitude, ra)
 }
 
 // SetLocation sets a Location structure.
 func SetLocation(loc Location) {
-	recompute = true
-	site.Latitude = loc.Latitude.Degree().Value
-	site.Longitude = loc.Longitude.Degree().Value
-	site.Height = loc.Height.Meter().Value
-	location = loc
+	std.SetLocation(loc)
+}
+
+// SetBackend sets the backend of the package ephemeris.
+func SetBackend(be Backend) {
+	std.SetBackend(be)
 }
 
 // GetLocations returns the location structure
 func GetLocation() Location {
-	return location
+	return std.GetLocation()
 }
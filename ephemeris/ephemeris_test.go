@@ -2,10 +2,15 @@ package ephemeris
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	at "github.com/rh-codebase/astrogo/astrotime"
 	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
 	nov "github.com/rh-codebase/novasgo/novas"
 )
 
@@ -22,11 +27,7 @@ func TestSimpleTrack(t *testing.T) {
 	//var a au.Angle
 	//fmt.Println("a: ", a.Degree().Value)
 
-	bsc := make(BSC)
-	cs := CatalogSource{"BSC", "brightSourceCatalog.yml"}
-
-	AddCatalog(cs)
-	bsc.LoadCatalogs()
+	bsc := DefaultBSC()
 	fmt.Println("bsc[AlpBoo]: ", bsc["alpboo"])
 
 	var si nov.OnSurface
@@ -127,13 +128,273 @@ func TestRefract(t *testing.T) {
 }
 
 func TestCatalog(t *testing.T) {
-	bsc := make(BSC)
-	cs := CatalogSource{"BSC", "brightSourceCatalog.yml"}
-
-	AddCatalog(cs)
-	bsc.LoadCatalogs()
+	ClearCatalogs()
+	bsc, err := LoadDefaultBSC()
+	if err != nil {
+		fmt.Println("LoadDefaultBSC returned err: ", err)
+		t.Fail()
+	}
 	fmt.Println("bsc[AlpBoo]: ", bsc["alpboo"])
 	ra := bsc["alpboo"].RA
 	fmt.Println("bsc[AlpBoo] ra: ", ra.Hour().Value, ra.SexagesimalHMS())
 
 }
+
+func TestNewEphemeris(t *testing.T) {
+	bsc := DefaultBSC()
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+
+	_, err := NewEphemeris("NotASource", loc, &bsc)
+	if err == nil {
+		fmt.Println("NewEphemeris expected error for unknown source")
+		t.Fail()
+	}
+
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	var si nov.OnSurface
+	nov.MakeOnSurface(37.2339, -118.282, 1222., 0.0, 0.0, &si)
+	for _, src := range []string{"alpboo", "Jupiter", "Saturn"} {
+		e, err := NewEphemeris(src, loc, &bsc)
+		if err != nil {
+			fmt.Println("NewEphemeris error: ", err)
+			t.Fail()
+			continue
+		}
+		_, err = e.GetAz()
+		if err == nil {
+			fmt.Println("GetAz expected error before time is set")
+			t.Fail()
+		}
+		e.SetTime(ti)
+		azel, err := e.GetAzEl()
+		if err != nil {
+			fmt.Println("GetAzEl error: ", err)
+			t.Fail()
+		}
+		track, _ := SimpleTrack(si, src, &bsc)
+		az, el, _ := track(ti)
+		th.CheckFT(t, azel.Az().Degree().Value, az, 1e-9, src+" Az Error")
+		th.CheckFT(t, azel.El().Degree().Value, el, 1e-9, src+" El Error")
+
+		ra, err := e.GetRa()
+		if err != nil || ra.Hour().Value < 0.0 || ra.Hour().Value > 24.0 {
+			fmt.Println("GetRa error: ", err, ra.Hour().Value)
+			t.Fail()
+		}
+		fmt.Printf("%s: ra= %s az, el= %6.3f, %5.3f\n", src, ra.SexagesimalHMS(), az, el)
+	}
+}
+
+func TestEphemerisRefraction(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	e, err := NewEphemeris("ra: 12.0\ndec: 20.0", loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	e.SetMJD(60766.0)
+	el, _ := e.GetEl()
+
+	airT, _ := au.NewTemperature(au.Celsius, 10.0)
+	e.SetWeather(au.NewPressure(au.Millibar, 1013.0), airT, 50.0)
+	e.SetFreq(1.e9)
+	e.SetRefraction(true)
+	elr, err := e.GetEl()
+	if err != nil {
+		fmt.Println("GetEl error: ", err)
+		t.Fail()
+	}
+	corr, _ := e.Refract(el)
+	th.CheckFT(t, elr.Degree().Value, corr.Degree().Value, 1e-12, "Refraction Error")
+	if el.Degree().Value > 0.0 && !elr.GreaterThan(el) {
+		fmt.Println("Refraction should raise the source: ", el.Degree().Value, elr.Degree().Value)
+		t.Fail()
+	}
+}
+
+func TestEphemerisConcurrent(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 40.8177)
+	loc.Longitude = au.NewAngle(au.Degree, -121.4733)
+	e, err := NewEphemeris("Moon", loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			e.SetTime(ti.Add(time.Duration(idx%2) * time.Minute))
+			if _, err := e.GetAzEl(); err != nil {
+				fmt.Println("GetAzEl error: ", err)
+				t.Fail()
+			}
+		}(idx)
+	}
+	wg.Wait()
+}
+
+func TestEphemerisMultiple(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 40.8177)
+	loc.Longitude = au.NewAngle(au.Degree, -121.4733)
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for _, src := range []string{"Sun", "Moon", "Mars", "Venus"} {
+		e, err := NewEphemeris(src, loc, nil)
+		if err != nil {
+			fmt.Println("NewEphemeris error: ", err)
+			t.Fail()
+			continue
+		}
+		wg.Add(1)
+		go func(e *Ephemeris) {
+			defer wg.Done()
+			for idx := 0; idx < 10; idx++ {
+				e.SetTime(ti.Add(time.Duration(idx) * time.Minute))
+				if _, err := e.GetRaDec(); err != nil {
+					fmt.Println("GetRaDec error: ", err)
+					t.Fail()
+				}
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+func TestEphemerisEOP(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	e, err := NewEphemeris("Jupiter", loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	ti := time.Date(2025, 4, 1, 3, 0, 0, 0, time.UTC)
+	at.SetEOPTable(nil)
+	e.SetTime(ti)
+	azel0, _ := e.GetAzEl()
+	eop, _ := e.GetEOP()
+	if !eop.Extrapolated || !eop.NoTable {
+		fmt.Println("Expected Extrapolated EOP with no table: ", eop)
+		t.Fail()
+	}
+
+	at.SetEOPTable(at.NewEOPTable([]at.EOP{
+		{MJD: 60765, UT1UTC: 0.5, Xp: 0.12, Yp: 0.40},
+		{MJD: 60766, UT1UTC: 0.5, Xp: 0.12, Yp: 0.40},
+		{MJD: 60767, UT1UTC: 0.5, Xp: 0.12, Yp: 0.40},
+	}))
+	defer at.SetEOPTable(nil)
+	// force a recompute with the new table
+	e.SetTime(ti.Add(time.Nanosecond))
+	azel1, _ := e.GetAzEl()
+	eop, _ = e.GetEOP()
+	if eop.Extrapolated {
+		fmt.Println("Unexpected Extrapolated EOP: ", eop)
+		t.Fail()
+	}
+	th.CheckFT(t, eop.UT1UTC, 0.5, 1e-12, "UT1UTC Error")
+	// half a second of UT1 moves the source about 7.5 arcsec in hour angle
+	dAz := azel1.Az().Sub(azel0.Az()).ArcSecond().Value
+	if dAz == 0.0 || dAz > 15.0 || dAz < -15.0 {
+		fmt.Println("Unexpected Az change from EOP (arcsec): ", dAz)
+		t.Fail()
+	}
+}
+
+func TestSiderealTime(t *testing.T) {
+	at.SetEOPTable(nil)
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+
+	// astrotime's sidereal time against NOVAS
+	ep := NewInstant(ti)
+	var gst float64
+	nov.SiderealTime(ep.JDUT1, 0.0, ep.DeltaT, 1, 1, accuracy, &gst)
+	th.CheckFT(t, at.GAST(ti), gst, 1e-7, "GAST Error")
+	lst := loc.LST(ti)
+	th.CheckFT(t, lst.Hour().Value, math.Mod(gst-118.282/15.0+24.0, 24.0), 1e-6, "LST Error")
+
+	e, err := NewEphemeris(Jupiter, loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	e.SetTime(ti)
+	ha, err := e.GetHourAngle()
+	if err != nil {
+		fmt.Println("GetHourAngle error: ", err)
+		t.Fail()
+	}
+	ra, _ := e.GetRa()
+	elst, _ := e.GetLST()
+	th.CheckFT(t, elst.Hour().Value, lst.Hour().Value, 1e-12, "GetLST Error")
+	th.CheckFT(t, ha.Hour().Value, math.Mod(lst.Hour().Value-ra.Hour().Value+36.0, 24.0)-12.0, 1e-9, "GetHourAngle Error")
+}
+
+func TestFrameSource(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	gc := au.NewGalacticCoord(au.Degree, 0.0, 0.0)
+	icrs, _ := gc.ICRS()
+	src := fmt.Sprintf("ra: %.10f\ndec: %.10f", icrs.Ra().Hour().Value, icrs.Dec().Degree().Value)
+	ref, err := NewEphemeris(src, loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	ti := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	ref.SetTime(ti)
+	want, _ := ref.GetAzEl()
+
+	// serialized and AngleCoord galactic sources track the same position
+	e, err := NewEphemeris("frame: galactic\nlon: 0.0\nlat: 0.0", loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	e.SetTime(ti)
+	got, _ := e.GetAzEl()
+	th.CheckFT(t, got.Separation(want).ArcSecond().Value, 0.0, 1e-3, "Galactic source Error")
+
+	fk4, _ := gc.To(au.FK4)
+	err = e.SetSourceCoord("GC", fk4)
+	if err != nil {
+		fmt.Println("SetSourceCoord error: ", err)
+		t.Fail()
+	}
+	e.SetTime(ti)
+	got, _ = e.GetAzEl()
+	th.CheckFT(t, got.Separation(want).ArcSecond().Value, 0.0, 1e-3, "FK4 source Error")
+	th.CheckS(t, e.GetSource(), "GC", "Source name Error")
+
+	err = e.SetSourceCoord("NoEpoch", au.NewFrameCoord(au.EclipticOfDate, au.Degree, 1.0, au.Degree, 1.0))
+	th.CheckErrorNil(t, err, "Expected missing epoch error")
+	_, err = NewEphemeris("frame: altaz\nlon: 0.0\nlat: 0.0", loc, nil)
+	th.CheckErrorNil(t, err, "Expected unknown frame error")
+	if err != nil && !strings.Contains(err.Error(), "Unknown frame") {
+		fmt.Println("Unknown frame error should name the frame: ", err)
+		t.Fail()
+	}
+}
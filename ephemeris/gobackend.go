@@ -0,0 +1,421 @@
+// Pure Go ephemeris backend
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+const (
+	// Earth rotation rate, rad/s
+	earthRot = 7.2921150e-5
+	// GM of the Sun over c^2, AU
+	sunGMc2 = 1.32712440017987e20 / (299792458.0 * 299792458.0) / au.MeterPerAstronomicalUnit
+	// equatorial radius of the Earth, AU
+	earthRadius = 6378136.6 / au.MeterPerAstronomicalUnit
+	// Sun mass over Earth mass
+	earthRMass = 332946.050895
+)
+
+// deflectors are the NOVAS body numbers and Sun mass over body mass of the
+// bodies deflecting light, besides the Earth for topocentric places.
+var deflectors = []struct {
+	body  int16
+	rmass float64
+}{
+	{10, 1.0},
+	{5, 1047.3486},
+	{6, 3497.898},
+}
+
+// goBackend computes places in Go with the IAU 2006 precession, the IAU
+// 2000B nutation, light deflection by the Sun, Jupiter, Saturn and, for
+// topocentric places, the Earth, and relativistic aberration, following
+// the NOVAS place algorithm.
+type goBackend struct {
+	// eph, when not nil, is used in place of PlanetaryEphemeris(), to
+	// check the place algorithm against NOVAS reading the same file
+	eph *JPLEphemeris
+}
+
+// NewGoBackend returns the Backend computing places in Go. The Sun, Moon
+// and planets come from the ephemeris set with SetPlanetaryEphemeris or,
+// when none is, from mean orbital elements and the principal terms of the
+// lunar theory, good to about an arcminute for the Sun, the Moon and the
+// inner planets, two for Uranus to Pluto and several for Jupiter and
+// Saturn, independently of NOVAS.
+func NewGoBackend() Backend {
+	return &goBackend{}
+}
+
+// ephemeris returns the planetary ephemeris used, nil for the mean
+// elements.
+func (be *goBackend) ephemeris() *JPLEphemeris {
+	if be.eph != nil {
+		return be.eph
+	}
+	return PlanetaryEphemeris()
+}
+
+// Name is "go", or "go <ephemeris>" when the planets come from an
+// ephemeris.
+func (be *goBackend) Name() string {
+	if e := be.ephemeris(); e != nil {
+		return "go " + e.Name
+	}
+	return "go"
+}
+
+func (be *goBackend) Apparent(in Instant, b Body) (ra, dec, dis float64, err error) {
+	return be.place(in, b, nil)
+}
+
+func (be *goBackend) Topocentric(in Instant, b Body, loc Location) (ra, dec, dis float64, err error) {
+	return be.place(in, b, &loc)
+}
+
+// Horizon rotates the local zenith, north and west of loc from the ITRS to
+// the true equator of date with polar motion and the apparent sidereal
+// time, as NOVAS Equ2hor does.
+func (be *goBackend) Horizon(in Instant, loc Location, ra, dec float64) (az, zd float64) {
+	m := mat3(at.MatMul(spin(gast(in)), polarMotion(in, in.EOP.Xp, in.EOP.Yp)))
+	sl, cl := math.Sincos(loc.Latitude.Radian().Value)
+	so, co := math.Sincos(loc.Longitude.Radian().Value)
+	uz := m.apply([3]float64{cl * co, cl * so, sl})
+	un := m.apply([3]float64{-sl * co, -sl * so, cl})
+	uw := m.apply([3]float64{so, -co, 0.0})
+	sd, cd := math.Sincos(dec * math.Pi / 180.0)
+	sr, cr := math.Sincos(ra * math.Pi / 12.0)
+	p := [3]float64{cd * cr, cd * sr, sd}
+	pz, pn, pw := dot(p, uz), dot(p, un), dot(p, uw)
+	proj := math.Hypot(pn, pw)
+	if proj > 0.0 {
+		az = -math.Atan2(pw, pn) * 180.0 / math.Pi
+		if az < 0.0 {
+			az += 360.0
+		}
+		if az >= 360.0 {
+			az -= 360.0
+		}
+	}
+	zd = math.Atan2(proj, pz) * 180.0 / math.Pi
+	return az, zd
+}
+
+// state returns the barycentric ICRS position, AU, and velocity, AU/day,
+// of the NOVAS body at jd, TDB.
+func (be *goBackend) state(jd float64, body int16) (pos, vel [3]float64, err error) {
+	if e := be.ephemeris(); e != nil {
+		return e.State(jd, body, 0)
+	}
+	return approxState(jd, body)
+}
+
+// place returns the apparent place of b at in, in the true equator and
+// equinox of date, seen from the geocenter or, when loc is not nil, from
+// loc. dis is the geometric distance of a solar system body.
+func (be *goBackend) place(in Instant, b Body, loc *Location) (ra, dec, dis float64, err error) {
+	if b.Number < 0 || b.Number > 11 || b.Number == 3 {
+		emsg := fmt.Sprintf("Invalid body %d for %s", b.Number, b.Name)
+		return ra, dec, dis, errors.New(emsg)
+	}
+	jd := instantTDB(in)
+	ct := celestialToTrue(in)
+	peb, veb, err := be.state(jd, 3)
+	if err != nil {
+		return ra, dec, dis, err
+	}
+	var pog, vog [3]float64
+	if loc != nil {
+		pog, vog = siteGCRS(ct, gast(in), *loc)
+	}
+	var pob, vob [3]float64
+	for idx := range pob {
+		pob[idx] = peb[idx] + pog[idx]
+		vob[idx] = veb[idx] + vog[idx]
+	}
+
+	var pos [3]float64
+	var tlight float64
+	if b.Number == 0 {
+		p, v := starVectors(b.Star)
+		dt := lightDiff(p, pob)
+		for idx := range pos {
+			pos[idx] = p[idx] + v[idx]*(jd+dt-at.J2000) - pob[idx]
+		}
+		tlight = norm(pos) / cAUPerDay
+	} else {
+		p, _, err := be.state(jd, b.Number)
+		if err != nil {
+			return ra, dec, dis, err
+		}
+		for idx := range pos {
+			pos[idx] = p[idx] - pob[idx]
+		}
+		dis = norm(pos)
+		// antedate the body for the light time
+		tlight = dis / cAUPerDay
+		for iter := 0; iter < 10; iter++ {
+			p, _, err = be.state(jd-tlight, b.Number)
+			if err != nil {
+				return ra, dec, dis, err
+			}
+			for idx := range pos {
+				pos[idx] = p[idx] - pob[idx]
+			}
+			t := norm(pos) / cAUPerDay
+			done := math.Abs(t-tlight) < 1e-12
+			tlight = t
+			if done {
+				break
+			}
+		}
+	}
+
+	pos, err = be.deflect(jd, pos, pob, peb, loc != nil && earthDeflects(pos, pog))
+	if err != nil {
+		return ra, dec, dis, err
+	}
+	pos = aberration(pos, vob, tlight)
+	ra, dec, _ = vectorRaDec(ct.apply(pos))
+	return ra, dec, dis, nil
+}
+
+// deflect applies the gravitational deflection of light to pos, the
+// position from the observer at pob, by the Sun, Jupiter and Saturn where
+// they were nearest the ray, and by the Earth at peb if earth is set.
+func (be *goBackend) deflect(jd float64, pos, pob, peb [3]float64, earth bool) ([3]float64, error) {
+	tlt := norm(pos) / cAUPerDay
+	for _, d := range deflectors {
+		pb, _, err := be.state(jd, d.body)
+		if err != nil {
+			return pos, err
+		}
+		for idx := range pb {
+			pb[idx] -= pob[idx]
+		}
+		dlt := lightDiff(pos, pb)
+		tclose := jd
+		if dlt > 0.0 {
+			tclose = jd - dlt
+		}
+		if tlt < dlt {
+			tclose = jd - tlt
+		}
+		pb, _, err = be.state(tclose, d.body)
+		if err != nil {
+			return pos, err
+		}
+		pos = gravVec(pos, pob, pb, d.rmass)
+	}
+	if earth {
+		pos = gravVec(pos, pob, peb, earthRMass)
+	}
+	return pos, nil
+}
+
+// earthDeflects tells whether the Earth deflects the light from pos to an
+// observer at the geocentric position pog: unless pos is near the nadir,
+// the NOVAS limb test.
+func earthDeflects(pos, pog [3]float64) bool {
+	d := norm(pog)
+	aprad := math.Pi / 2.0
+	if d >= earthRadius {
+		aprad = math.Asin(earthRadius / d)
+	}
+	cz := dot(pos, pog) / (norm(pos) * d)
+	zd := math.Acos(math.Max(-1.0, math.Min(1.0, cz)))
+	return (math.Pi-zd)/aprad >= 0.8
+}
+
+// gravVec returns pos, the position of an object from the observer at pob,
+// deflected by the body at pb of Sun mass over body mass rmass.
+func gravVec(pos, pob, pb [3]float64, rmass float64) [3]float64 {
+	var pq, pe [3]float64
+	for idx := range pq {
+		pq[idx] = pob[idx] + pos[idx] - pb[idx]
+		pe[idx] = pob[idx] - pb[idx]
+	}
+	pmag, emag, qmag := norm(pos), norm(pe), norm(pq)
+	var phat, ehat, qhat [3]float64
+	for idx := range phat {
+		phat[idx] = pos[idx] / pmag
+		ehat[idx] = pe[idx] / emag
+		qhat[idx] = pq[idx] / qmag
+	}
+	pdotq, edotp, qdote := dot(phat, qhat), dot(ehat, phat), dot(qhat, ehat)
+	if math.Abs(edotp) > 0.99999999999 {
+		// the observer is on the line from the body to the object
+		return pos
+	}
+	fac1 := 2.0 * sunGMc2 / (emag * rmass)
+	fac2 := 1.0 + qdote
+	var p [3]float64
+	for idx := range p {
+		p[idx] = (phat[idx] + fac1*(pdotq*ehat[idx]-edotp*qhat[idx])/fac2) * pmag
+	}
+	return p
+}
+
+// aberration returns pos, the position from an observer moving at ve,
+// AU/day, with the light time tlight, corrected for the relativistic
+// aberration of light.
+func aberration(pos, ve [3]float64, tlight float64) [3]float64 {
+	p1mag := tlight * cAUPerDay
+	vemag := norm(ve)
+	beta := vemag / cAUPerDay
+	cosd := dot(pos, ve) / (p1mag * vemag)
+	gammai := math.Sqrt(1.0 - beta*beta)
+	p := beta * cosd
+	q := (1.0 + p/(1.0+gammai)) * tlight
+	r := 1.0 + p
+	return [3]float64{
+		(gammai*pos[0] + q*ve[0]) / r,
+		(gammai*pos[1] + q*ve[1]) / r,
+		(gammai*pos[2] + q*ve[2]) / r,
+	}
+}
+
+// starVectors returns the barycentric ICRS position, AU, and space
+// motion, AU/day, of star at J2000. A star without parallax is put at 1
+// Gpc.
+func starVectors(star StarInfo) (pos, vel [3]float64) {
+	paralx := star.Parallax_mas
+	if paralx <= 0.0 {
+		paralx = 1.0e-6
+	}
+	dist := 1.0 / math.Sin(paralx*1.0e-3*at.ArcsecondToRadian)
+	sr, cr := math.Sincos(star.Ra_hr * math.Pi / 12.0)
+	sd, cd := math.Sincos(star.Dec_deg * math.Pi / 180.0)
+	pos = [3]float64{dist * cd * cr, dist * cd * sr, dist * sd}
+	// the relativistic factor of the radial velocity, km/s
+	k := 1.0 / (1.0 - star.RadVel_kmPerSec/299792.458)
+	pmr := star.PMRA_masPerYr / (paralx * 365.25) * k
+	pmd := star.PMDEC_masPerYr / (paralx * 365.25) * k
+	rvl := au.NewLength(au.Kilometer, star.RadVel_kmPerSec*at.SecondPerDay).AstronomicalUnit().Value * k
+	vel = [3]float64{
+		-pmr*sr - pmd*sd*cr + rvl*cd*cr,
+		pmr*cr - pmd*sd*sr + rvl*cd*sr,
+		pmd*cd + rvl*sd,
+	}
+	return pos, vel
+}
+
+// siteGCRS returns the geocentric ICRS position, AU, and velocity, AU/day,
+// of loc at the apparent sidereal time gst, radians, where ct rotates from
+// the ICRS to the true equator of date. Polar motion is neglected here,
+// as in NOVAS.
+func siteGCRS(ct mat3, gst float64, loc Location) (pos, vel [3]float64) {
+	r := mat3(spin(gst)).apply(siteECEF(loc))
+	v := [3]float64{-earthRot * r[1], earthRot * r[0], 0.0}
+	for idx := range r {
+		r[idx] = au.NewLength(au.Kilometer, r[idx]).AstronomicalUnit().Value
+		v[idx] = au.NewLength(au.Kilometer, v[idx]*at.SecondPerDay).AstronomicalUnit().Value
+	}
+	return ct.applyT(r), ct.applyT(v)
+}
+
+// mat3 is a rotation matrix.
+type mat3 [3][3]float64
+
+// apply returns m v.
+func (m mat3) apply(v [3]float64) [3]float64 {
+	var r [3]float64
+	for idx := range r {
+		r[idx] = m[idx][0]*v[0] + m[idx][1]*v[1] + m[idx][2]*v[2]
+	}
+	return r
+}
+
+// applyT returns the transpose of m times v, the inverse rotation.
+func (m mat3) applyT(v [3]float64) [3]float64 {
+	var r [3]float64
+	for idx := range r {
+		r[idx] = m[0][idx]*v[0] + m[1][idx]*v[1] + m[2][idx]*v[2]
+	}
+	return r
+}
+
+// instantTT and instantUT1 return the TT and UT1 dates of in.
+func instantTT(in Instant) at.JD {
+	return at.NewJD(math.Floor(in.JDTT), in.JDTT-math.Floor(in.JDTT))
+}
+
+func instantUT1(in Instant) at.JD {
+	return at.NewJD(math.Floor(in.JDUT1), in.JDUT1-math.Floor(in.JDUT1))
+}
+
+// instantTDB returns the TDB Julian date of in.
+func instantTDB(in Instant) float64 {
+	return in.JDTT + at.TDBminusTT(in.JDTT)/at.SecondPerDay
+}
+
+// celestialToTrue returns the rotation from the ICRS to the true equator
+// and equinox of in: the IAU 2006 bias and precession and the IAU 2000B
+// nutation with the celestial pole offsets of the EOP.
+func celestialToTrue(in Instant) mat3 {
+	tt := instantTT(in)
+	dpsi, deps := at.Nutation(tt)
+	ddpsi, ddeps := in.EOP.PoleOffsets(tt)
+	dpsi += ddpsi
+	deps += ddeps
+	eps := at.MeanObliquity(tt)
+	sm, cm := math.Sincos(eps)
+	st, ct := math.Sincos(eps + deps)
+	sp, cp := math.Sincos(dpsi)
+	n := [3][3]float64{
+		{cp, -sp * cm, -sp * sm},
+		{sp * ct, cp*cm*ct + sm*st, cp*sm*ct - cm*st},
+		{sp * st, cp*cm*st - sm*ct, cp*sm*st + cm*ct},
+	}
+	return mat3(at.MatMul(n, at.BiasPrecessionMatrix(tt)))
+}
+
+// gast returns the Greenwich apparent sidereal time of in, radians,
+// including the celestial pole offsets of the EOP.
+func gast(in Instant) float64 {
+	tt := instantTT(in)
+	ddpsi, _ := in.EOP.PoleOffsets(tt)
+	return at.GAST06(instantUT1(in), tt) + ddpsi*math.Cos(at.MeanObliquity(tt))
+}
+
+// polarMotion returns the rotation from the ITRS to the terrestrial
+// intermediate system for the pole offsets xp, yp in arcsec at in,
+// including the TIO locator s'.
+func polarMotion(in Instant, xp, yp float64) [3][3]float64 {
+	t := (in.JDTT - at.J2000) / at.JulianCentury
+	sp := -47e-6 * t * at.ArcsecondToRadian
+	sx, cx := math.Sincos(xp * at.ArcsecondToRadian)
+	sy, cy := math.Sincos(yp * at.ArcsecondToRadian)
+	sl, cl := math.Sincos(-sp)
+	return [3][3]float64{
+		{cx * cl, sx*sy*cl + cy*sl, -sx*cy*cl + sy*sl},
+		{-cx * sl, -sx*sy*sl + cy*cl, sx*cy*sl + sy*cl},
+		{sx, -cx * sy, cx * cy},
+	}
+}
+
+// spin returns the rotation by theta, radians, about the z axis taking
+// Earth fixed to celestial coordinates.
+func spin(theta float64) [3][3]float64 {
+	s, c := math.Sincos(theta)
+	return [3][3]float64{{c, -s, 0.0}, {s, c, 0.0}, {0.0, 0.0, 1.0}}
+}
+
+// lightDiff returns the difference in light time, days, between the
+// barycenter and pob along the direction of pos.
+func lightDiff(pos, pob [3]float64) float64 {
+	return dot(pos, pob) / norm(pos) / cAUPerDay
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func norm(a [3]float64) float64 {
+	return math.Sqrt(dot(a, a))
+}
@@ -0,0 +1,333 @@
+// JPL planetary ephemerides
+package ephemeris
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+// JPLEphemeris is a JPL planetary ephemeris, as DE405, DE440 or DE441, read
+// from a local file in either the JPL binary format made by asc2eph or the
+// SPK kernel format of the .bsp files. It gives the positions of the
+// planets, the Sun and the Moon by NOVAS body number: 1-9 Mercury to
+// Pluto, 10 the Sun and 11 the Moon. The file stays open until Close. It
+// is safe for concurrent use.
+type JPLEphemeris struct {
+	Name    string  // as DE440, or the SPK internal file name
+	StartJD float64 // Julian date, TDB, of the start of coverage
+	EndJD   float64 // and of the end
+
+	f      *os.File
+	auKm   float64
+	reader jplReader
+}
+
+// jplReader evaluates an ephemeris file.
+type jplReader interface {
+	// barycentric returns the position, km, and velocity, km/day, of the
+	// NOVAS body from the solar system barycenter at jd, TDB.
+	barycentric(jd float64, body int16) (pos, vel [3]float64, err error)
+}
+
+// OpenJPLEphemeris opens the JPL ephemeris file fn, in the JPL binary or
+// the SPK format.
+func OpenJPLEphemeris(fn string) (*JPLEphemeris, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	_, err = f.ReadAt(id, 0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	e := &JPLEphemeris{f: f, auKm: au.MeterPerAstronomicalUnit / 1000.0}
+	if string(id) == "DAF/SPK " || string(id) == "NAIF/DAF" {
+		err = openSPK(e)
+	} else {
+		err = openDEBinary(e)
+	}
+	if err != nil {
+		f.Close()
+		emsg := fmt.Sprintf("%s: %v", fn, err)
+		return nil, errors.New(emsg)
+	}
+	return e, nil
+}
+
+// Close closes the ephemeris file.
+func (e *JPLEphemeris) Close() error {
+	return e.f.Close()
+}
+
+// State returns the position, AU, and velocity, AU/day, in the ICRS of the
+// NOVAS body at jd, TDB, from the solar system barycenter (origin 0) or the
+// center of the Sun (origin 1), as NOVAS Solarsystem does.
+func (e *JPLEphemeris) State(jd float64, body, origin int16) (pos, vel [3]float64, err error) {
+	if jd < e.StartJD || jd > e.EndJD {
+		emsg := fmt.Sprintf("JD %.5f is outside %s, %.1f to %.1f", jd, e.Name, e.StartJD, e.EndJD)
+		return pos, vel, errors.New(emsg)
+	}
+	if body < 1 || body > 11 {
+		emsg := fmt.Sprintf("Invalid body %d for %s", body, e.Name)
+		return pos, vel, errors.New(emsg)
+	}
+	if origin != 0 && origin != 1 {
+		emsg := fmt.Sprintf("Invalid origin %d for %s", origin, e.Name)
+		return pos, vel, errors.New(emsg)
+	}
+	pos, vel, err = e.reader.barycentric(jd, body)
+	if err != nil {
+		return pos, vel, err
+	}
+	if origin == 1 {
+		sp, sv, err := e.reader.barycentric(jd, 10)
+		if err != nil {
+			return pos, vel, err
+		}
+		for idx := range pos {
+			pos[idx] -= sp[idx]
+			vel[idx] -= sv[idx]
+		}
+	}
+	for idx := range pos {
+		pos[idx] /= e.auKm
+		vel[idx] /= e.auKm
+	}
+	return pos, vel, nil
+}
+
+var (
+	// planetEphMu guards planetEph.
+	planetEphMu sync.RWMutex
+	// planetEph, when set, replaces the NOVAS ephemeris file for the
+	// solar system positions.
+	planetEph *JPLEphemeris
+)
+
+// SetPlanetaryEphemeris makes e the source of the positions of the Sun,
+// Moon and planets, both for planet sources, with either backend, and for
+// minor bodies, their light time and perturbations, in place of the
+// ephemeris file read by NOVAS. nil restores NOVAS.
+func SetPlanetaryEphemeris(e *JPLEphemeris) {
+	planetEphMu.Lock()
+	defer planetEphMu.Unlock()
+	planetEph = e
+}
+
+// PlanetaryEphemeris returns the ephemeris set with SetPlanetaryEphemeris,
+// or nil when NOVAS is used.
+func PlanetaryEphemeris() *JPLEphemeris {
+	planetEphMu.RLock()
+	defer planetEphMu.RUnlock()
+	return planetEph
+}
+
+// chebyshev returns the sum of the Chebyshev series c at x in [-1, 1] and
+// its derivative with respect to x.
+func chebyshev(c []float64, x float64) (p, dp float64) {
+	t0, t1 := 1.0, x
+	d0, d1 := 0.0, 1.0
+	p = c[0]
+	if len(c) > 1 {
+		p += c[1] * x
+		dp = c[1]
+	}
+	for k := 2; k < len(c); k++ {
+		t2 := 2.0*x*t1 - t0
+		d2 := 2.0*t1 + 2.0*x*d1 - d0
+		p += c[k] * t2
+		dp += c[k] * d2
+		t0, t1 = t1, t2
+		d0, d1 = d1, d2
+	}
+	return p, dp
+}
+
+// readFloats reads n floats of byte order bo at byte offset off of f.
+func readFloats(f io.ReaderAt, bo binary.ByteOrder, off int64, n int) ([]float64, error) {
+	b := make([]byte, 8*n)
+	_, err := f.ReadAt(b, off)
+	if err != nil {
+		return nil, err
+	}
+	vs := make([]float64, n)
+	for idx := range vs {
+		vs[idx] = math.Float64frombits(bo.Uint64(b[8*idx:]))
+	}
+	return vs, nil
+}
+
+// deBinary reads the JPL binary format: a header record, a record of
+// constants and then fixed length records of Chebyshev coefficients each
+// covering the same number of days.
+type deBinary struct {
+	f     *os.File
+	order binary.ByteOrder
+	// coefficient pointers, offset (1 based), count and subintervals, of
+	// Mercury to Pluto, the geocentric Moon and the Sun, the nutations,
+	// librations, lunar mantle and TT-TDB.
+	ipt    [15][3]int
+	ncoeff int
+	start  float64
+	end    float64
+	step   float64
+	emrat  float64
+
+	// last record read
+	mu     sync.Mutex
+	recIdx int
+	rec    []float64
+}
+
+// deHeaderLen is the length of the fixed part of the first record, up to
+// and including the libration pointers.
+const deHeaderLen = 2856
+
+// openDEBinary sets e to read the JPL binary format.
+func openDEBinary(e *JPLEphemeris) error {
+	h := make([]byte, deHeaderLen)
+	_, err := e.f.ReadAt(h, 0)
+	if err != nil {
+		return errors.New("Not a JPL ephemeris file")
+	}
+	d := &deBinary{f: e.f, order: binary.LittleEndian, recIdx: -1}
+	if n := d.order.Uint32(h[2840:]); n == 0 || n > 10000 {
+		d.order = binary.BigEndian
+	}
+	numde := int(d.order.Uint32(h[2840:]))
+	if numde == 0 || numde > 10000 {
+		return errors.New("Not a JPL ephemeris file")
+	}
+	f64 := func(off int) float64 {
+		return math.Float64frombits(d.order.Uint64(h[off:]))
+	}
+	d.start, d.end, d.step = f64(2652), f64(2660), f64(2668)
+	ncon := int(d.order.Uint32(h[2676:]))
+	e.auKm = f64(2680)
+	d.emrat = f64(2688)
+	for idx := 0; idx < 12; idx++ {
+		for k := 0; k < 3; k++ {
+			d.ipt[idx][k] = int(d.order.Uint32(h[2696+12*idx+4*k:]))
+		}
+	}
+	for k := 0; k < 3; k++ {
+		d.ipt[12][k] = int(d.order.Uint32(h[2844+4*k:]))
+	}
+	if ncon > 400 {
+		// the names of the constants past 400 and then the pointers of
+		// the lunar mantle and TT-TDB
+		b := make([]byte, 24)
+		_, err = e.f.ReadAt(b, int64(deHeaderLen+6*(ncon-400)))
+		if err != nil {
+			return err
+		}
+		for idx := 0; idx < 6; idx++ {
+			d.ipt[13+idx/3][idx%3] = int(d.order.Uint32(b[4*idx:]))
+		}
+	}
+	for idx, p := range d.ipt {
+		ncomp := 3
+		switch idx {
+		case 11:
+			ncomp = 2
+		case 14:
+			ncomp = 1
+		}
+		if n := p[0] - 1 + p[1]*p[2]*ncomp; p[0] > 0 && n > d.ncoeff {
+			d.ncoeff = n
+		}
+	}
+	if d.step <= 0.0 || d.end <= d.start || d.ncoeff < 2 || e.auKm <= 0.0 || d.emrat <= 0.0 {
+		return errors.New("Invalid JPL ephemeris header")
+	}
+	// the first data record must start at the start of the ephemeris
+	first, err := readFloats(e.f, d.order, int64(2*8*d.ncoeff), 2)
+	if err != nil || first[0] != d.start || first[1] != d.start+d.step {
+		return errors.New("Unrecognized JPL ephemeris record layout")
+	}
+	e.Name = fmt.Sprintf("DE%d", numde)
+	e.StartJD, e.EndJD = d.start, d.end
+	e.reader = d
+	return nil
+}
+
+// record returns the coefficient record covering jd. The caller must hold
+// d.mu.
+func (d *deBinary) record(jd float64) ([]float64, error) {
+	idx := int((jd - d.start) / d.step)
+	if n := int(math.Round((d.end - d.start) / d.step)); idx >= n {
+		idx = n - 1
+	}
+	if idx == d.recIdx {
+		return d.rec, nil
+	}
+	rec, err := readFloats(d.f, d.order, int64(8*d.ncoeff*(idx+2)), d.ncoeff)
+	if err != nil {
+		return nil, err
+	}
+	if jd < rec[0] || jd > rec[1] {
+		emsg := fmt.Sprintf("Record %d covers %.1f to %.1f, not JD %.5f", idx, rec[0], rec[1], jd)
+		return nil, errors.New(emsg)
+	}
+	d.recIdx, d.rec = idx, rec
+	return rec, nil
+}
+
+// interpolate returns the position and velocity of item, an index of ipt,
+// from rec at jd.
+func (d *deBinary) interpolate(rec []float64, item int, jd float64) (pos, vel [3]float64) {
+	off, ncf, nsub := d.ipt[item][0]-1, d.ipt[item][1], d.ipt[item][2]
+	span := (rec[1] - rec[0]) / float64(nsub)
+	sub := int((jd - rec[0]) / span)
+	if sub >= nsub {
+		sub = nsub - 1
+	}
+	x := 2.0*(jd-rec[0]-float64(sub)*span)/span - 1.0
+	base := off + sub*ncf*3
+	for idx := 0; idx < 3; idx++ {
+		p, dp := chebyshev(rec[base+idx*ncf:base+(idx+1)*ncf], x)
+		pos[idx], vel[idx] = p, dp*2.0/span
+	}
+	return pos, vel
+}
+
+func (d *deBinary) barycentric(jd float64, body int16) (pos, vel [3]float64, err error) {
+	if body < 1 || body > 11 {
+		emsg := fmt.Sprintf("Invalid body %d for the DE binary", body)
+		return pos, vel, errors.New(emsg)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	rec, err := d.record(jd)
+	if err != nil {
+		return pos, vel, err
+	}
+	switch body {
+	case 3, 11:
+		// from the Earth-Moon barycenter and the geocentric Moon
+		pos, vel = d.interpolate(rec, 2, jd)
+		mp, mv := d.interpolate(rec, 9, jd)
+		f := -1.0 / (1.0 + d.emrat)
+		if body == 11 {
+			f = d.emrat / (1.0 + d.emrat)
+		}
+		for idx := range pos {
+			pos[idx] += f * mp[idx]
+			vel[idx] += f * mv[idx]
+		}
+	case 10:
+		pos, vel = d.interpolate(rec, 10, jd)
+	default:
+		pos, vel = d.interpolate(rec, int(body)-1, jd)
+	}
+	return pos, vel, nil
+}
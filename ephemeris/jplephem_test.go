@@ -0,0 +1,201 @@
+package ephemeris
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestChebyshev(t *testing.T) {
+	// T0 + 2 T1 + 3 T2 = 6x^2 + 2x - 2
+	c := []float64{1.0, 2.0, 3.0}
+	for _, x := range []float64{-1.0, -0.3, 0.0, 0.5, 1.0} {
+		p, dp := chebyshev(c, x)
+		th.CheckFT(t, p, 6.0*x*x+2.0*x-2.0, 1e-14, "Chebyshev sum Error")
+		th.CheckFT(t, dp, 12.0*x+2.0, 1e-14, "Chebyshev derivative Error")
+	}
+	p, dp := chebyshev([]float64{4.0}, 0.7)
+	th.CheckF(t, p, 4.0, "Chebyshev constant Error")
+	th.CheckF(t, dp, 0.0, "Chebyshev constant derivative Error")
+}
+
+func TestJPLEphemeris(t *testing.T) {
+	e, err := OpenJPLEphemeris("JPLEPH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	th.CheckS(t, e.Name, "DE405", "Name Error")
+	th.CheckF(t, e.StartJD, 2305424.5, "Start Error")
+	th.CheckF(t, e.EndJD, 2525008.5, "End Error")
+
+	// NOVAS reads the same file
+	for _, jd := range []float64{2451545.0, 2460389.5, 2460389.5 + 31.99, 2415020.3} {
+		for body := int16(1); body <= 11; body++ {
+			for origin := int16(0); origin <= 1; origin++ {
+				novasMu.Lock()
+				np, nv, err := solarSystem(jd, body, origin)
+				novasMu.Unlock()
+				if err != nil {
+					t.Fatal(err)
+				}
+				p, v, err := e.State(jd, body, origin)
+				if err != nil {
+					fmt.Println("State error: ", err)
+					t.Fail()
+					continue
+				}
+				msg := fmt.Sprintf("JD %.2f body %d origin %d", jd, body, origin)
+				th.CheckFT(t, separation(p, np), 0.0, 1e-13, msg+" position Error")
+				th.CheckFT(t, separation(v, nv), 0.0, 1e-15, msg+" velocity Error")
+			}
+		}
+	}
+
+	// the velocity is the derivative of the position, to the resolution
+	// of a Julian date
+	jd := 2460380.25
+	p0, _, _ := e.State(jd-0.01, 4, 0)
+	_, v, _ := e.State(jd, 4, 0)
+	p2, _, _ := e.State(jd+0.01, 4, 0)
+	for idx := range v {
+		th.CheckFT(t, v[idx], (p2[idx]-p0[idx])/0.02, 1e-9, "Mars velocity Error")
+	}
+	_, _, err = e.State(e.EndJD, 3, 0)
+	if err != nil {
+		fmt.Println("State error at end: ", err)
+		t.Fail()
+	}
+
+	_, _, err = e.State(e.StartJD-1.0, 3, 0)
+	th.CheckErrorNil(t, err, "State expected error before start")
+	_, _, err = e.State(jd, 12, 0)
+	th.CheckErrorNil(t, err, "State expected error for bad body")
+	_, _, err = e.State(jd, 3, 2)
+	th.CheckErrorNil(t, err, "State expected error for bad origin")
+
+	_, err = OpenJPLEphemeris("nosuchfile")
+	th.CheckErrorNil(t, err, "OpenJPLEphemeris expected error for missing file")
+	_, err = OpenJPLEphemeris("sites.yml")
+	th.CheckErrorNil(t, err, "OpenJPLEphemeris expected error for text file")
+}
+
+func TestSetPlanetaryEphemeris(t *testing.T) {
+	e, err := OpenJPLEphemeris("JPLEPH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	mb, err := NewMinorBody(marsElements(t, ti), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd1, dis1, err := mb.Astrometric(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetPlanetaryEphemeris(e)
+	defer SetPlanetaryEphemeris(nil)
+	if PlanetaryEphemeris() != e {
+		fmt.Println("PlanetaryEphemeris Error")
+		t.Fail()
+	}
+	// the backends report where the planets come from
+	th.CheckS(t, NOVASBackend().Name(), "novas, planets go "+e.Name, "NOVAS backend name Error")
+	th.CheckS(t, NewGoBackend().Name(), "go "+e.Name, "Go backend name Error")
+	rd2, dis2, err := mb.Astrometric(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckFT(t, rd2.Ra().Hour().Value, rd1.Ra().Hour().Value, 1e-12, "Astrometric RA Error")
+	th.CheckFT(t, rd2.Dec().Degree().Value, rd1.Dec().Degree().Value, 1e-12, "Astrometric Dec Error")
+	th.CheckFT(t, dis2, dis1, 1e-13, "Astrometric distance Error")
+
+	// outside the ephemeris
+	_, _, err = mb.Astrometric(time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC))
+	th.CheckErrorNil(t, err, "Astrometric expected error outside the ephemeris")
+	_, _, err = e.reader.barycentric(2460766.5, 0)
+	th.CheckErrorNil(t, err, "DE binary expected error for a bad body")
+}
+
+func TestPlanetaryEphemerisPlanets(t *testing.T) {
+	de, err := OpenJPLEphemeris("JPLEPH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer de.Close()
+	// a DE405 SPK kernel covering 2024-03-01 to 2024-05-03
+	start := 2460368.5
+	fn := filepath.Join(t.TempDir(), "de405.bsp")
+	writeSPK(t, fn, binary.LittleEndian, deSegments(t, de, start, 64.0))
+	e, err := OpenJPLEphemeris(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	ti := time.Date(2024, 3, 20, 6, 0, 0, 0, time.UTC)
+	for _, src := range []string{Jupiter, Moon} {
+		eph, err := NewEphemeris(src, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eph.SetTime(ti)
+		azel1, err := eph.GetAzEl()
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetPlanetaryEphemeris(e)
+		eph.SetTime(ti.Add(time.Nanosecond))
+		azel2, err := eph.GetAzEl()
+		if err != nil {
+			fmt.Println("GetAzEl error: ", err)
+			t.Fail()
+		}
+		// the same DE405 through the kernel
+		th.CheckFT(t, azel2.Az().Degree().Value, azel1.Az().Degree().Value, 1e-6, src+" Az Error")
+		th.CheckFT(t, azel2.El().Degree().Value, azel1.El().Degree().Value, 1e-6, src+" El Error")
+		// and nothing outside the kernel
+		eph.SetTime(time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC))
+		_, err = eph.GetAzEl()
+		th.CheckErrorNil(t, err, src+" expected error outside the planetary ephemeris")
+		SetPlanetaryEphemeris(nil)
+	}
+
+	// the Go backend places every body from the kernel as from the NOVAS
+	// file
+	gb := novasFileBackend(t)
+	kb := NewGoBackend()
+	in := NewInstant(ti)
+	for n := int16(1); n <= 11; n++ {
+		if n == 3 {
+			continue
+		}
+		b := Body{Name: fmt.Sprintf("body %d", n), Number: n}
+		ra1, dec1, dis1, err := gb.Apparent(in, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetPlanetaryEphemeris(e)
+		ra2, dec2, dis2, err := kb.Apparent(in, b)
+		SetPlanetaryEphemeris(nil)
+		if err != nil {
+			fmt.Println("Apparent error: ", err)
+			t.Fail()
+			continue
+		}
+		checkPlace(t, ra2, dec2, ra1, dec1, 1e-6, b.Name)
+		// the kernel is in km, converted with the IAU 2012 AU
+		th.CheckFT(t, (dis2-dis1)/dis1, 0.0, 1e-10, b.Name+" distance Error")
+	}
+}
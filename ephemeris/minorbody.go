@@ -0,0 +1,260 @@
+// Comets and minor planets
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+// MinorBody is a comet or minor planet following an orbit, either the two
+// body orbit of its elements or, when Perturbed, that orbit integrated
+// with the perturbations of the planets. It is safe for concurrent use.
+type MinorBody struct {
+	Elements  OrbitalElements
+	Perturbed bool
+	// Self is the NOVAS number of the major body, if any, that the orbit
+	// follows, which then does not perturb it. It must be set before the
+	// body is first used.
+	Self int16
+
+	// integrated states every orbitCheckpoint days from the epoch, by
+	// signed index
+	mu          sync.Mutex
+	checkpoints map[int]orbitState
+}
+
+// orbitState is a heliocentric position, AU, and velocity, AU/day.
+type orbitState struct {
+	pos, vel [3]float64
+}
+
+const (
+	// days between the integrated states kept by a perturbed MinorBody
+	orbitCheckpoint = 10.0
+)
+
+var (
+	// minorBodiesMu guards minorBodies, which is read by every target
+	// resolution.
+	minorBodiesMu sync.RWMutex
+	// minorBodies holds the bodies known to the ephemeris, by lower case
+	// name and designation.
+	minorBodies = make(map[string]*MinorBody)
+)
+
+// NewMinorBody returns the MinorBody of the elements el.
+func NewMinorBody(el OrbitalElements, perturbed bool) (*MinorBody, error) {
+	err := el.validate()
+	if err != nil {
+		return nil, err
+	}
+	mb := &MinorBody{Elements: el, Perturbed: perturbed}
+	if perturbed {
+		if el.Epoch == 0.0 {
+			emsg := fmt.Sprintf("Perturbed orbit for %s needs an epoch of osculation", el.Name)
+			return nil, errors.New(emsg)
+		}
+		pos, vel := el.twoBody(el.Epoch)
+		mb.checkpoints = map[int]orbitState{0: {pos, vel}}
+	}
+	return mb, nil
+}
+
+// AddMinorBody makes mb known by its name and designation, ignoring case,
+// to NewEphemeris, SimpleTrack and the other functions resolving source
+// names. Planet names are never resolved to minor bodies.
+func AddMinorBody(mb *MinorBody) {
+	minorBodiesMu.Lock()
+	defer minorBodiesMu.Unlock()
+	for _, k := range []string{mb.Elements.Name, mb.Elements.Designation} {
+		if k != "" {
+			minorBodies[strings.ToLower(k)] = mb
+		}
+	}
+}
+
+// LoadMinorBodies reads an MPC element file, as ReadMPC, and adds each
+// body to the ephemeris.
+func LoadMinorBodies(fn string, perturbed bool) error {
+	els, err := ReadMPC(fn)
+	if err != nil {
+		return err
+	}
+	for _, el := range els {
+		mb, err := NewMinorBody(el, perturbed)
+		if err != nil {
+			return err
+		}
+		AddMinorBody(mb)
+	}
+	return nil
+}
+
+// ClearMinorBodies forgets the minor bodies added.
+func ClearMinorBodies() {
+	minorBodiesMu.Lock()
+	defer minorBodiesMu.Unlock()
+	minorBodies = make(map[string]*MinorBody)
+}
+
+// GetMinorBody returns the minor body added with the name or designation,
+// ignoring case.
+func GetMinorBody(name string) (*MinorBody, bool) {
+	minorBodiesMu.RLock()
+	defer minorBodiesMu.RUnlock()
+	mb, ok := minorBodies[strings.ToLower(strings.TrimSpace(name))]
+	return mb, ok
+}
+
+// Heliocentric returns the heliocentric ICRS position, AU, and velocity,
+// AU/day, of the body at t.
+func (mb *MinorBody) Heliocentric(t time.Time) (pos, vel [3]float64, err error) {
+	return mb.heliocentric(NewInstant(t).JDTT)
+}
+
+// heliocentric returns the heliocentric state at jd, TT.
+func (mb *MinorBody) heliocentric(jd float64) (pos, vel [3]float64, err error) {
+	if !mb.Perturbed {
+		pos, vel = mb.Elements.twoBody(jd)
+		return pos, vel, nil
+	}
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	// integrate from the last checkpoint between the epoch and jd, so that
+	// the state does not depend on the times asked for before
+	n := int((jd - mb.Elements.Epoch) / orbitCheckpoint)
+	s, err := mb.checkpoint(n)
+	if err != nil {
+		return pos, vel, err
+	}
+	return integrate(mb.checkpointJD(n), s.pos, s.vel, jd, mb.Self)
+}
+
+// checkpointJD returns the time, JD TT, of checkpoint n.
+func (mb *MinorBody) checkpointJD(n int) float64 {
+	return mb.Elements.Epoch + float64(n)*orbitCheckpoint
+}
+
+// checkpoint returns the state at checkpoint n, integrating out to it from
+// the epoch through the checkpoints not yet kept. The caller must hold mu.
+func (mb *MinorBody) checkpoint(n int) (orbitState, error) {
+	dir := 1
+	if n < 0 {
+		dir = -1
+	}
+	// the checkpoints kept run without gaps from the epoch
+	k := n
+	for k != 0 {
+		if _, ok := mb.checkpoints[k]; ok {
+			break
+		}
+		k -= dir
+	}
+	s := mb.checkpoints[k]
+	for k != n {
+		pos, vel, err := integrate(mb.checkpointJD(k), s.pos, s.vel, mb.checkpointJD(k+dir), mb.Self)
+		if err != nil {
+			return s, err
+		}
+		k += dir
+		s = orbitState{pos, vel}
+		mb.checkpoints[k] = s
+	}
+	return s, nil
+}
+
+// astrometric returns the geocentric astrometric ICRS position of the body
+// at jd, TT, in AU: its barycentric position at the time light left it
+// less the barycentric position of the Earth.
+func (mb *MinorBody) astrometric(jd float64) ([3]float64, error) {
+	var g [3]float64
+	novasMu.Lock()
+	earth, _, err := solarSystem(jd, 3, 0)
+	novasMu.Unlock()
+	if err != nil {
+		return g, err
+	}
+	tau := 0.0
+	for idx := 0; idx < 10; idx++ {
+		pos, _, err := mb.heliocentric(jd - tau)
+		if err != nil {
+			return g, err
+		}
+		novasMu.Lock()
+		sun, _, err := solarSystem(jd-tau, 10, 0)
+		novasMu.Unlock()
+		if err != nil {
+			return g, err
+		}
+		for k := range g {
+			g[k] = pos[k] + sun[k] - earth[k]
+		}
+		d := math.Sqrt(g[0]*g[0] + g[1]*g[1] + g[2]*g[2])
+		if math.Abs(d/cAUPerDay-tau) < 1e-12 {
+			break
+		}
+		tau = d / cAUPerDay
+	}
+	return g, nil
+}
+
+// vectorRaDec returns the RA, hours, Dec, degrees, and length of v.
+func vectorRaDec(v [3]float64) (ra, dec, dist float64) {
+	dist = math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	ra = math.Atan2(v[1], v[0]) * 12.0 / math.Pi
+	if ra < 0.0 {
+		ra += 24.0
+	}
+	return ra, math.Asin(v[2]/dist) * 180.0 / math.Pi, dist
+}
+
+// Astrometric returns the geocentric astrometric ICRS position of the body
+// at t and its distance in AU.
+func (mb *MinorBody) Astrometric(t time.Time) (au.AngleCoord, float64, error) {
+	g, err := mb.astrometric(NewInstant(t).JDTT)
+	if err != nil {
+		return au.AngleCoord{}, 0.0, err
+	}
+	ra, dec, dist := vectorRaDec(g)
+	return au.NewRaDecCoord(au.Hour, ra, au.Degree, dec), dist, nil
+}
+
+// topo returns the topocentric apparent RA (hours), Dec (degrees) and
+// distance (AU) of the body at ep as seen from loc. It is an
+// approximation: be places the geocentric astrometric direction, which
+// already has the light time applied, as a star at infinite distance, so
+// the aberration is right but the light deflection is that of a source
+// beyond the body, off by milliarcseconds except close to the Sun, and
+// the diurnal parallax, which be does not apply to a star, is added
+// afterwards in the true equator of date.
+func (mb *MinorBody) topo(be Backend, ep Instant, loc Location) (ra, dec, dis float64, err error) {
+	g, err := mb.astrometric(ep.JDTT)
+	if err != nil {
+		return ra, dec, dis, err
+	}
+	gra, gdec, gdis := vectorRaDec(g)
+	b := Body{Name: mb.Elements.Name, Star: fixedStar(mb.Elements.Name, gra, gdec)}
+	ra, dec, _, err = be.Topocentric(ep, b, loc)
+	if err != nil {
+		return ra, dec, dis, err
+	}
+	site := siteECEF(loc)
+	for idx := range site {
+		site[idx] = au.NewLength(au.Kilometer, site[idx]).AstronomicalUnit().Value
+	}
+	st, ct := math.Sincos(gast(ep))
+	rar, decr := ra*math.Pi/12.0, dec*math.Pi/180.0
+	v := [3]float64{
+		gdis*math.Cos(decr)*math.Cos(rar) - (ct*site[0] - st*site[1]),
+		gdis*math.Cos(decr)*math.Sin(rar) - (st*site[0] + ct*site[1]),
+		gdis*math.Sin(decr) - site[2],
+	}
+	ra, dec, dis = vectorRaDec(v)
+	return ra, dec, dis, nil
+}
@@ -0,0 +1,170 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+// marsElements returns the osculating elements of Mars at ti, named as a
+// minor body.
+func marsElements(t *testing.T, ti time.Time) OrbitalElements {
+	jd := NewInstant(ti).JDTT
+	novasMu.Lock()
+	pos, vel, err := solarSystem(jd, 4, 1)
+	novasMu.Unlock()
+	if err != nil {
+		fmt.Println("solarSystem error: ", err)
+		t.FailNow()
+	}
+	el := ElementsFromState("Mars Clone", jd, pos, vel)
+	el.Designation = "M4"
+	return el
+}
+
+func TestMinorBodyTopo(t *testing.T) {
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	mb, err := NewMinorBody(marsElements(t, ti), false)
+	if err != nil {
+		fmt.Println("NewMinorBody error: ", err)
+		t.FailNow()
+	}
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	mars := target{name: Mars, planet: true, number: 4}
+	minor := target{minor: mb}
+
+	// the clone follows Mars closely near the epoch of its elements
+	for _, dt := range []time.Duration{0, 6 * time.Hour, -24 * time.Hour} {
+		ep := NewInstant(ti.Add(dt))
+		ra1, dec1, dis1, err := mars.topo(defaultBackend, ep, loc)
+		if err != nil {
+			fmt.Println("Mars topo error: ", err)
+			t.Fail()
+		}
+		ra2, dec2, dis2, err := minor.topo(defaultBackend, ep, loc)
+		if err != nil {
+			fmt.Println("Minor body topo error: ", err)
+			t.Fail()
+		}
+		dra := (ra2 - ra1) * 15.0 * 3600.0 * math.Cos(dec1*math.Pi/180.0)
+		ddec := (dec2 - dec1) * 3600.0
+		fmt.Printf("Mars clone %v: dRA %.4f\" dDec %.4f\" ddis %.2e AU\n", dt, dra, ddec, dis2-dis1)
+		th.CheckFT(t, dra, 0.0, 0.1, "Minor body RA Error")
+		th.CheckFT(t, ddec, 0.0, 0.1, "Minor body Dec Error")
+		// NOVAS gives the geometric distance, without light time
+		th.CheckFT(t, dis2, dis1, 1e-4, "Minor body distance Error")
+	}
+
+	rd, dis, err := mb.Astrometric(ti)
+	if err != nil || dis < 0.5 || dis > 3.0 {
+		fmt.Println("Astrometric error: ", err, dis)
+		t.Fail()
+	}
+	pos, _, err := mb.Heliocentric(ti)
+	if err != nil {
+		fmt.Println("Heliocentric error: ", err)
+		t.Fail()
+	}
+	th.CheckFT(t, math.Sqrt(pos[0]*pos[0]+pos[1]*pos[1]+pos[2]*pos[2]),
+		mb.Elements.SemiMajorAxis(), 0.15*mb.Elements.SemiMajorAxis(), "Heliocentric distance Error")
+	fmt.Println("Mars clone astrometric: ", rd.Ra().Hour().Value, rd.Dec().Degree().Value, dis)
+}
+
+func TestMinorBodyRegistry(t *testing.T) {
+	defer ClearMinorBodies()
+	ti := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	el := marsElements(t, ti)
+	_, ok := GetMinorBody("mars clone")
+	if ok {
+		fmt.Println("GetMinorBody found a body before it was added")
+		t.Fail()
+	}
+	_, err := NewEphemeris("Mars Clone", Location{}, nil)
+	th.CheckErrorNil(t, err, "NewEphemeris expected error for unknown minor body")
+
+	mb, err := NewMinorBody(el, true)
+	if err != nil {
+		fmt.Println("NewMinorBody error: ", err)
+		t.FailNow()
+	}
+	mb.Self = 4
+	AddMinorBody(mb)
+	for _, name := range []string{"Mars Clone", "MARS CLONE", "m4"} {
+		got, ok := GetMinorBody(name)
+		if !ok || got != mb {
+			fmt.Println("GetMinorBody did not find: ", name)
+			t.Fail()
+		}
+	}
+
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	var si nov.OnSurface
+	nov.MakeOnSurface(37.2339, -118.282, 1222., 0.0, 0.0, &si)
+	for _, src := range []string{"Mars Clone", "M4"} {
+		e, err := NewEphemeris(src, loc, nil)
+		if err != nil {
+			fmt.Println("NewEphemeris error: ", err)
+			t.Fail()
+			continue
+		}
+		e.SetTime(ti)
+		azel, err := e.GetAzEl()
+		if err != nil {
+			fmt.Println("GetAzEl error: ", err)
+			t.Fail()
+		}
+		track, err := SimpleTrack(si, src, nil)
+		if err != nil {
+			fmt.Println("SimpleTrack error: ", err)
+			t.Fail()
+			continue
+		}
+		az, el, _ := track(ti)
+		th.CheckFT(t, azel.Az().Degree().Value, az, 1e-9, src+" Az Error")
+		th.CheckFT(t, azel.El().Degree().Value, el, 1e-9, src+" El Error")
+
+		// and close to Mars itself
+		mtrack, _ := SimpleTrack(si, "Mars", nil)
+		maz, mel, _ := mtrack(ti)
+		th.CheckFT(t, az, maz, 1e-4, src+" Mars Az Error")
+		th.CheckFT(t, el, mel, 1e-4, src+" Mars El Error")
+	}
+
+	// planets are never minor bodies
+	el.Name = "Mars"
+	el.Designation = ""
+	shadow, _ := NewMinorBody(el, false)
+	AddMinorBody(shadow)
+	e, err := NewEphemeris("Mars", loc, nil)
+	if err != nil || e.target.minor != nil {
+		fmt.Println("Mars resolved to a minor body: ", err)
+		t.Fail()
+	}
+
+	ClearMinorBodies()
+	_, ok = GetMinorBody("m4")
+	if ok {
+		fmt.Println("GetMinorBody found a body after ClearMinorBodies")
+		t.Fail()
+	}
+
+	el.Eccentricity = -1.0
+	_, err = NewMinorBody(el, false)
+	th.CheckErrorNil(t, err, "NewMinorBody expected error for invalid elements")
+	el.Eccentricity = 1.5
+	el.Epoch = 0.0
+	el.PerihelionTime = 2460700.5
+	_, err = NewMinorBody(el, true)
+	th.CheckErrorNil(t, err, "NewMinorBody expected error for perturbed orbit without epoch")
+}
@@ -0,0 +1,224 @@
+// Minor Planet Center orbital element files
+package ephemeris
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+// calendarJD returns the Julian date of a calendar date, with day the
+// fractional day of the month, on the date's own time scale.
+func calendarJD(year, month int, day float64) float64 {
+	t := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	j2000 := time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)
+	return 2451545.0 + t.Sub(j2000).Hours()/24.0 + day - 1.0
+}
+
+// unpackDigit returns the value of an MPC packed digit: 0-9, then A-Z for
+// 10-35 and a-z for 36-61.
+func unpackDigit(c byte) (int, error) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), nil
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, nil
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 36, nil
+	}
+	emsg := fmt.Sprintf("Invalid MPC packed digit: %c", c)
+	return 0, errors.New(emsg)
+}
+
+// unpackEpoch returns the Julian date, TT, of an MPC packed date such as
+// K24AH for 2024 October 17.
+func unpackEpoch(s string) (float64, error) {
+	if len(s) != 5 {
+		emsg := fmt.Sprintf("Invalid MPC packed epoch: %s", s)
+		return 0.0, errors.New(emsg)
+	}
+	var v [3]int
+	for idx, c := range []byte{s[0], s[3], s[4]} {
+		d, err := unpackDigit(c)
+		if err != nil {
+			return 0.0, err
+		}
+		v[idx] = d
+	}
+	yy, err := strconv.Atoi(s[1:3])
+	if err != nil {
+		return 0.0, err
+	}
+	return calendarJD(v[0]*100+yy, v[1], float64(v[2])), nil
+}
+
+// unpackNumber returns the number of a packed minor planet number, as
+// 00001 or A0001 for 100001, and false for a provisional designation.
+func unpackNumber(s string) (int, bool) {
+	if len(s) != 5 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[1:])
+	if err != nil {
+		return 0, false
+	}
+	d, err := unpackDigit(s[0])
+	if err != nil {
+		return 0, false
+	}
+	return d*10000 + n, true
+}
+
+// parseFloats parses the fields of line at the 1 based column ranges
+// cols.
+func parseFloats(line string, cols ...[2]int) ([]float64, error) {
+	vs := make([]float64, len(cols))
+	for idx, c := range cols {
+		v, err := strconv.ParseFloat(field(line, c[0], c[1]), 64)
+		if err != nil {
+			emsg := fmt.Sprintf("columns %d-%d: %v", c[0], c[1], err)
+			return nil, errors.New(emsg)
+		}
+		vs[idx] = v
+	}
+	return vs, nil
+}
+
+// parseMPCORBLine parses a minor planet record of MPCORB.DAT format.
+func parseMPCORBLine(line string) (OrbitalElements, error) {
+	var el OrbitalElements
+	if len(line) < 103 {
+		emsg := fmt.Sprintf("MPCORB record is %d characters, not at least 103", len(line))
+		return el, errors.New(emsg)
+	}
+	vs, err := parseFloats(line, [2]int{27, 35}, [2]int{38, 46}, [2]int{49, 57}, [2]int{60, 68},
+		[2]int{71, 79}, [2]int{93, 103})
+	if err != nil {
+		return el, err
+	}
+	el.Epoch, err = unpackEpoch(field(line, 21, 25))
+	if err != nil {
+		return el, err
+	}
+	el.MeanAnomaly = au.NewAngle(au.Degree, vs[0])
+	el.ArgPerihelion = au.NewAngle(au.Degree, vs[1])
+	el.Node = au.NewAngle(au.Degree, vs[2])
+	el.Inclination = au.NewAngle(au.Degree, vs[3])
+	el.Eccentricity = vs[4]
+	el.PerihelionDistance = vs[5] * (1.0 - vs[4])
+	el.H, _ = parseFloat(field(line, 9, 13))
+	el.G, _ = parseFloat(field(line, 15, 19))
+
+	packed := field(line, 1, 7)
+	readable := field(line, 167, 194)
+	if strings.HasPrefix(readable, "(") {
+		// numbered, as "(1) Ceres"
+		if end := strings.Index(readable, ")"); end > 0 {
+			el.Designation = readable[1:end]
+			el.Name = strings.TrimSpace(readable[end+1:])
+		}
+	} else if readable != "" {
+		el.Designation = readable
+	} else if n, ok := unpackNumber(packed); ok {
+		el.Designation = strconv.Itoa(n)
+	} else {
+		el.Designation = packed
+	}
+	if el.Name == "" {
+		el.Name = el.Designation
+	}
+	return el, nil
+}
+
+// parseCometLine parses a record of the MPC comet element format, as
+// CometEls.txt.
+func parseCometLine(line string) (OrbitalElements, error) {
+	var el OrbitalElements
+	if len(line) < 103 {
+		emsg := fmt.Sprintf("Comet record is %d characters, not at least 103", len(line))
+		return el, errors.New(emsg)
+	}
+	vs, err := parseFloats(line, [2]int{15, 18}, [2]int{20, 21}, [2]int{23, 29}, [2]int{31, 39},
+		[2]int{42, 49}, [2]int{52, 59}, [2]int{62, 69}, [2]int{72, 79})
+	if err != nil {
+		return el, err
+	}
+	el.PerihelionTime = calendarJD(int(vs[0]), int(vs[1]), vs[2])
+	el.PerihelionDistance = vs[3]
+	el.Eccentricity = vs[4]
+	el.ArgPerihelion = au.NewAngle(au.Degree, vs[5])
+	el.Node = au.NewAngle(au.Degree, vs[6])
+	el.Inclination = au.NewAngle(au.Degree, vs[7])
+	if ep := field(line, 82, 89); len(ep) == 8 {
+		y, err1 := strconv.Atoi(ep[:4])
+		m, err2 := strconv.Atoi(ep[4:6])
+		d, err3 := strconv.Atoi(ep[6:])
+		if err1 == nil && err2 == nil && err3 == nil {
+			el.Epoch = calendarJD(y, m, float64(d))
+		}
+	}
+	el.H, _ = parseFloat(field(line, 92, 95))
+	el.G, _ = parseFloat(field(line, 97, 100))
+
+	// "1P/Halley" or "C/1995 O1 (Hale-Bopp)"
+	name := field(line, 103, 158)
+	if open := strings.Index(name, " ("); open > 0 && strings.HasSuffix(name, ")") {
+		el.Designation = name[:open]
+		el.Name = name[open+2 : len(name)-1]
+	} else if slash := strings.Index(name, "/"); slash > 0 && strings.HasSuffix(name[:slash], "P") {
+		el.Designation = name[:slash]
+		el.Name = name
+	} else {
+		el.Designation = name
+		el.Name = name
+	}
+	return el, nil
+}
+
+// isCometLine reports whether line is in the comet format, whose columns
+// 15-18 hold the year of perihelion where MPCORB has the slope parameter.
+func isCometLine(line string) bool {
+	y := field(line, 15, 18)
+	_, err := strconv.Atoi(y)
+	return len(y) == 4 && err == nil
+}
+
+// ReadMPC reads the orbital elements of an MPC minor planet (MPCORB.DAT)
+// or comet (CometEls.txt) file, which may be gzipped. Lines before the
+// first record, such as the MPCORB header, are skipped.
+func ReadMPC(fn string) ([]OrbitalElements, error) {
+	var els []OrbitalElements
+	err := readFile(fn, func(r io.Reader) error {
+		sc := bufio.NewScanner(r)
+		for ln := 1; sc.Scan(); ln++ {
+			line := strings.TrimRight(sc.Text(), " \r")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			var el OrbitalElements
+			var err error
+			if isCometLine(line) {
+				el, err = parseCometLine(line)
+			} else {
+				el, err = parseMPCORBLine(line)
+			}
+			if err != nil {
+				if len(els) == 0 {
+					continue
+				}
+				emsg := fmt.Sprintf("line %d: %v", ln, err)
+				return errors.New(emsg)
+			}
+			el.File, el.Line = fn, ln
+			els = append(els, el)
+		}
+		return sc.Err()
+	})
+	return els, err
+}
@@ -0,0 +1,163 @@
+package ephemeris
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// columns builds a fixed format record with each value starting at its 1
+// based column.
+func columns(fields map[int]string) string {
+	line := []byte(strings.Repeat(" ", 200))
+	for col, v := range fields {
+		copy(line[col-1:], v)
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+var mpcCeres = columns(map[int]string{
+	1: "00001", 9: " 3.33", 15: " 0.15", 21: "K24AH", 27: "145.84128", 38: " 73.29890",
+	49: " 80.25491", 60: " 10.58680", 71: "0.0797000", 81: "0.21424651", 93: "  2.7670000",
+	167: "(1) Ceres",
+})
+
+var mpcProvisional = columns(map[int]string{
+	1: "K24A01A", 9: "18.20", 15: " 0.15", 21: "K24AH", 27: " 12.34560", 38: "200.00000",
+	49: " 30.00000", 60: "  5.00000", 71: "0.2500000", 81: "0.30000000", 93: "  2.0000000",
+	167: "2024 AA1",
+})
+
+var mpcHalley = columns(map[int]string{
+	1: "0001P", 15: "2061", 20: "07", 23: "28.8852", 31: " 0.593362", 42: "0.967142",
+	52: "112.2414", 62: " 59.4169", 72: "162.1904", 82: "20240101", 92: " 4.0", 97: " 6.0",
+	103: "1P/Halley",
+})
+
+var mpcHaleBopp = columns(map[int]string{
+	5: "C", 6: "1995O1", 15: "1997", 20: "03", 23: "31.8000", 31: " 0.914000", 42: "0.995000",
+	52: "130.5900", 62: "282.4700", 72: " 89.4300", 92: "-2.0", 97: " 4.0",
+	103: "C/1995 O1 (Hale-Bopp)",
+})
+
+func TestUnpackEpoch(t *testing.T) {
+	jd, err := unpackEpoch("K24AH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckF(t, jd, calendarJD(2024, 10, 17), "K24AH Error")
+	th.CheckF(t, calendarJD(2000, 1, 1.5), 2451545.0, "J2000 Error")
+	jd, _ = unpackEpoch("J9611")
+	th.CheckF(t, jd, calendarJD(1996, 1, 1), "J9611 Error")
+	_, err = unpackEpoch("K24A")
+	th.CheckErrorNil(t, err, "unpackEpoch expected error for short epoch")
+	_, err = unpackEpoch("K24A#")
+	th.CheckErrorNil(t, err, "unpackEpoch expected error for bad digit")
+
+	n, ok := unpackNumber("A0001")
+	if !ok {
+		fmt.Println("unpackNumber failed for A0001")
+		t.Fail()
+	}
+	th.CheckI(t, n, 100001, "Packed number Error")
+	_, ok = unpackNumber("K24A01A")
+	if ok {
+		fmt.Println("unpackNumber accepted a provisional designation")
+		t.Fail()
+	}
+}
+
+func TestParseMPC(t *testing.T) {
+	el, err := parseMPCORBLine(mpcCeres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckS(t, el.Name, "Ceres", "Ceres name Error")
+	th.CheckS(t, el.Designation, "1", "Ceres designation Error")
+	th.CheckF(t, el.Epoch, calendarJD(2024, 10, 17), "Ceres epoch Error")
+	th.CheckFT(t, el.MeanAnomaly.Degree().Value, 145.84128, 1e-12, "Ceres mean anomaly Error")
+	th.CheckFT(t, el.ArgPerihelion.Degree().Value, 73.2989, 1e-12, "Ceres argument of perihelion Error")
+	th.CheckFT(t, el.Node.Degree().Value, 80.25491, 1e-12, "Ceres node Error")
+	th.CheckFT(t, el.Inclination.Degree().Value, 10.5868, 1e-12, "Ceres inclination Error")
+	th.CheckFT(t, el.SemiMajorAxis(), 2.767, 1e-12, "Ceres semi-major axis Error")
+	th.CheckF(t, el.H, 3.33, "Ceres H Error")
+	th.CheckF(t, el.G, 0.15, "Ceres G Error")
+
+	el, _ = parseMPCORBLine(mpcProvisional)
+	th.CheckS(t, el.Name, "2024 AA1", "Provisional name Error")
+
+	if isCometLine(mpcCeres) || !isCometLine(mpcHalley) {
+		fmt.Println("isCometLine Error")
+		t.Fail()
+	}
+	el, err = parseCometLine(mpcHalley)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckS(t, el.Name, "1P/Halley", "Halley name Error")
+	th.CheckS(t, el.Designation, "1P", "Halley designation Error")
+	th.CheckF(t, el.PerihelionTime, calendarJD(2061, 7, 28.8852), "Halley perihelion time Error")
+	th.CheckF(t, el.PerihelionDistance, 0.593362, "Halley q Error")
+	th.CheckF(t, el.Eccentricity, 0.967142, "Halley e Error")
+	th.CheckF(t, el.Epoch, calendarJD(2024, 1, 1), "Halley epoch Error")
+	th.CheckFT(t, el.Inclination.Degree().Value, 162.1904, 1e-12, "Halley inclination Error")
+
+	el, err = parseCometLine(mpcHaleBopp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckS(t, el.Name, "Hale-Bopp", "Hale-Bopp name Error")
+	th.CheckS(t, el.Designation, "C/1995 O1", "Hale-Bopp designation Error")
+	th.CheckF(t, el.Epoch, 0.0, "Hale-Bopp epoch Error")
+	th.CheckF(t, el.H, -2.0, "Hale-Bopp H Error")
+
+	_, err = parseMPCORBLine(mpcCeres[:80])
+	th.CheckErrorNil(t, err, "parseMPCORBLine expected error for short record")
+	_, err = parseCometLine(strings.Replace(mpcHalley, "0.967142", "0.96x142", 1))
+	th.CheckErrorNil(t, err, "parseCometLine expected error for bad eccentricity")
+}
+
+func TestReadMPC(t *testing.T) {
+	dir := t.TempDir()
+	header := "MINOR PLANET CENTER ORBIT DATABASE (MPCORB)\n\nDes'n     H     G   Epoch     M\n" +
+		strings.Repeat("-", 160) + "\n"
+	fn := writeFile(t, dir, "MPCORB.DAT.gz", header+mpcCeres+"\n\n"+mpcProvisional+"\n")
+	els, err := ReadMPC(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, len(els), 2, "MPCORB record count Error")
+	th.CheckS(t, els[0].Name, "Ceres", "MPCORB name Error")
+	th.CheckS(t, els[0].File, fn, "MPCORB file Error")
+	th.CheckI(t, els[0].Line, 5, "MPCORB line Error")
+	th.CheckI(t, els[1].Line, 7, "MPCORB second line Error")
+
+	fn = writeFile(t, dir, "CometEls.txt", mpcHalley+"\n"+mpcHaleBopp+"\n")
+	els, err = ReadMPC(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, len(els), 2, "Comet record count Error")
+	th.CheckS(t, els[1].Designation, "C/1995 O1", "Comet designation Error")
+
+	err = LoadMinorBodies(fn, false)
+	defer ClearMinorBodies()
+	if err != nil {
+		fmt.Println("LoadMinorBodies error: ", err)
+		t.Fail()
+	}
+	for _, name := range []string{"1P", "1p/halley", "Hale-Bopp", "C/1995 O1"} {
+		if _, ok := GetMinorBody(name); !ok {
+			fmt.Println("GetMinorBody did not find: ", name)
+			t.Fail()
+		}
+	}
+
+	fn = writeFile(t, dir, "bad.txt", mpcCeres+"\n"+mpcCeres[:80]+"\n")
+	_, err = ReadMPC(fn)
+	th.CheckErrorNil(t, err, "ReadMPC expected error for bad record")
+	_, err = ReadMPC(dir + "/missing.txt")
+	th.CheckErrorNil(t, err, "ReadMPC expected error for missing file")
+}
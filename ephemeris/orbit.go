@@ -0,0 +1,347 @@
+// Heliocentric orbits from osculating elements
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+const (
+	gaussK    = 0.01720209895 // Gaussian gravitational constant, AU^1.5/day
+	gmSun     = gaussK * gaussK
+	cAUPerDay = 173.1446326846693 // speed of light
+	// obliquity of the ecliptic at J2000, the plane of MPC elements
+	obliquityJ2000 = 84381.448 / 3600.0 * math.Pi / 180.0
+	// eccentricities this close to 1 are treated as parabolic
+	parabolicTol = 1e-8
+)
+
+// perturbers are the NOVAS body numbers and GM, AU^3/day^2, of the planets
+// perturbing a minor body orbit (DE405 masses).
+var perturbers = []struct {
+	body int16
+	gm   float64
+}{
+	{1, gmSun / 6023600.0},
+	{2, gmSun / 408523.71},
+	{3, gmSun / 332946.050895},
+	{11, gmSun / (332946.050895 * 81.30056)},
+	{4, gmSun / 3098708.0},
+	{5, gmSun / 1047.3486},
+	{6, gmSun / 3497.898},
+	{7, gmSun / 22902.98},
+	{8, gmSun / 19412.24},
+}
+
+// OrbitalElements are the heliocentric osculating elements of a comet or
+// minor planet, referred to the J2000 ecliptic and equinox as in MPC
+// files. The orbit is fixed by PerihelionTime or, for an elliptic orbit
+// when it is zero, by MeanAnomaly at Epoch.
+type OrbitalElements struct {
+	Name           string
+	Designation    string
+	Epoch          float64 // Julian date, TT, of osculation
+	PerihelionTime float64 // Julian date, TT
+	// perihelion distance, AU
+	PerihelionDistance float64
+	Eccentricity       float64
+	Inclination        au.Angle
+	Node               au.Angle // longitude of the ascending node
+	ArgPerihelion      au.Angle
+	MeanAnomaly        au.Angle // at Epoch
+	// absolute magnitude and slope parameter; K for comets
+	H, G float64
+	Provenance
+}
+
+// SemiMajorAxis returns the semi-major axis in AU, negative for a
+// hyperbolic orbit and infinite for a parabolic one.
+func (el OrbitalElements) SemiMajorAxis() float64 {
+	if math.Abs(el.Eccentricity-1.0) < parabolicTol {
+		return math.Inf(1)
+	}
+	return el.PerihelionDistance / (1.0 - el.Eccentricity)
+}
+
+// validate checks that the elements describe an orbit.
+func (el OrbitalElements) validate() error {
+	if el.PerihelionDistance <= 0.0 || el.Eccentricity < 0.0 || math.IsNaN(el.Eccentricity) {
+		emsg := fmt.Sprintf("Invalid orbit for %s: q %g e %g", el.Name, el.PerihelionDistance, el.Eccentricity)
+		return errors.New(emsg)
+	}
+	if el.PerihelionTime == 0.0 && (el.Epoch == 0.0 || el.Eccentricity >= 1.0-parabolicTol) {
+		emsg := fmt.Sprintf("Orbit for %s needs a perihelion time, or an epoch and mean anomaly", el.Name)
+		return errors.New(emsg)
+	}
+	return nil
+}
+
+// perihelionTime returns the time of perihelion passage, JD TT.
+func (el OrbitalElements) perihelionTime() float64 {
+	if el.PerihelionTime != 0.0 {
+		return el.PerihelionTime
+	}
+	a := el.SemiMajorAxis()
+	n := gaussK / math.Pow(a, 1.5)
+	return el.Epoch - el.MeanAnomaly.Radian().Value/n
+}
+
+// eclipticToICRS rotates a J2000 ecliptic vector to the equator.
+func eclipticToICRS(v [3]float64) [3]float64 {
+	ce, se := math.Cos(obliquityJ2000), math.Sin(obliquityJ2000)
+	return [3]float64{v[0], ce*v[1] - se*v[2], se*v[1] + ce*v[2]}
+}
+
+// icrsToEcliptic rotates an equatorial vector to the J2000 ecliptic.
+func icrsToEcliptic(v [3]float64) [3]float64 {
+	ce, se := math.Cos(obliquityJ2000), math.Sin(obliquityJ2000)
+	return [3]float64{v[0], ce*v[1] + se*v[2], -se*v[1] + ce*v[2]}
+}
+
+// kepler solves Kepler's equation M = E - e sin E for E.
+func kepler(m, e float64) float64 {
+	m = math.Remainder(m, 2.0*math.Pi)
+	ea := m
+	if e >= 0.8 {
+		ea = math.Copysign(math.Pi, m)
+	}
+	for idx := 0; idx < 50; idx++ {
+		d := (ea - e*math.Sin(ea) - m) / (1.0 - e*math.Cos(ea))
+		ea -= d
+		if math.Abs(d) < 1e-15 {
+			break
+		}
+	}
+	return ea
+}
+
+// keplerHyperbolic solves M = e sinh H - H for H.
+func keplerHyperbolic(m, e float64) float64 {
+	h := math.Asinh(m / e)
+	for idx := 0; idx < 50; idx++ {
+		d := (e*math.Sinh(h) - h - m) / (e*math.Cosh(h) - 1.0)
+		h -= d
+		if math.Abs(d) < 1e-15*math.Max(1.0, math.Abs(h)) {
+			break
+		}
+	}
+	return h
+}
+
+// twoBody returns the heliocentric position, AU, and velocity, AU/day, in
+// the ICRS at jd, TT, of the unperturbed orbit.
+func (el OrbitalElements) twoBody(jd float64) (pos, vel [3]float64) {
+	q, e := el.PerihelionDistance, el.Eccentricity
+	dt := jd - el.perihelionTime()
+	var x, y, vx, vy float64
+	switch {
+	case math.Abs(e-1.0) < parabolicTol:
+		// Barker's equation for s = tan(v/2)
+		w := 3.0 * gaussK / math.Sqrt(2.0*q*q*q) * dt
+		yb := math.Cbrt(w/2.0 + math.Sqrt(w*w/4.0+1.0))
+		s := yb - 1.0/yb
+		sdot := gaussK / (math.Sqrt(2.0*q*q*q) * (1.0 + s*s))
+		x, y = q*(1.0-s*s), 2.0*q*s
+		vx, vy = -2.0*q*s*sdot, 2.0*q*sdot
+	case e < 1.0:
+		a := q / (1.0 - e)
+		n := gaussK / math.Pow(a, 1.5)
+		ea := kepler(n*dt, e)
+		ce, se := math.Cos(ea), math.Sin(ea)
+		b := a * math.Sqrt(1.0-e*e)
+		edot := n / (1.0 - e*ce)
+		x, y = a*(ce-e), b*se
+		vx, vy = -a*se*edot, b*ce*edot
+	default:
+		a := q / (e - 1.0)
+		n := gaussK / math.Pow(a, 1.5)
+		h := keplerHyperbolic(n*dt, e)
+		ch, sh := math.Cosh(h), math.Sinh(h)
+		b := a * math.Sqrt(e*e-1.0)
+		hdot := n / (e*ch - 1.0)
+		x, y = a*(e-ch), b*sh
+		vx, vy = -a*sh*hdot, b*ch*hdot
+	}
+
+	// orbital plane to ecliptic
+	w := el.ArgPerihelion.Radian().Value
+	node := el.Node.Radian().Value
+	inc := el.Inclination.Radian().Value
+	cw, sw := math.Cos(w), math.Sin(w)
+	cn, sn := math.Cos(node), math.Sin(node)
+	ci, si := math.Cos(inc), math.Sin(inc)
+	p := [3]float64{cw*cn - sw*sn*ci, cw*sn + sw*cn*ci, sw * si}
+	qv := [3]float64{-sw*cn - cw*sn*ci, -sw*sn + cw*cn*ci, cw * si}
+	for idx := 0; idx < 3; idx++ {
+		pos[idx] = x*p[idx] + y*qv[idx]
+		vel[idx] = vx*p[idx] + vy*qv[idx]
+	}
+	return eclipticToICRS(pos), eclipticToICRS(vel)
+}
+
+// ElementsFromState returns the osculating elements at jd, TT, of the
+// heliocentric ICRS position, AU, and velocity, AU/day.
+func ElementsFromState(name string, jd float64, pos, vel [3]float64) OrbitalElements {
+	r := icrsToEcliptic(pos)
+	v := icrsToEcliptic(vel)
+	rm := math.Sqrt(r[0]*r[0] + r[1]*r[1] + r[2]*r[2])
+	v2 := v[0]*v[0] + v[1]*v[1] + v[2]*v[2]
+	rv := r[0]*v[0] + r[1]*v[1] + r[2]*v[2]
+	h := [3]float64{r[1]*v[2] - r[2]*v[1], r[2]*v[0] - r[0]*v[2], r[0]*v[1] - r[1]*v[0]}
+	hm := math.Sqrt(h[0]*h[0] + h[1]*h[1] + h[2]*h[2])
+	var ev [3]float64
+	for idx := range ev {
+		ev[idx] = ((v2-gmSun/rm)*r[idx] - rv*v[idx]) / gmSun
+	}
+	e := math.Sqrt(ev[0]*ev[0] + ev[1]*ev[1] + ev[2]*ev[2])
+	inc := math.Acos(h[2] / hm)
+	node := math.Atan2(h[0], -h[1])
+	// argument of perihelion from the node line
+	nv := [3]float64{math.Cos(node), math.Sin(node), 0.0}
+	m := [3]float64{h[1]*nv[2] - h[2]*nv[1], h[2]*nv[0] - h[0]*nv[2], h[0]*nv[1] - h[1]*nv[0]}
+	w := math.Atan2((ev[0]*m[0]+ev[1]*m[1]+ev[2]*m[2])/hm, ev[0]*nv[0]+ev[1]*nv[1]+ev[2]*nv[2])
+	p := hm * hm / gmSun
+	q := p / (1.0 + e)
+	nu := math.Atan2(rv*math.Sqrt(p/gmSun), p-rm)
+
+	el := OrbitalElements{
+		Name:               name,
+		Epoch:              jd,
+		PerihelionDistance: q,
+		Eccentricity:       e,
+		Inclination:        au.NewAngle(au.Radian, inc),
+		Node:               au.NewAngle(au.Radian, math.Mod(node+2.0*math.Pi, 2.0*math.Pi)),
+		ArgPerihelion:      au.NewAngle(au.Radian, math.Mod(w+2.0*math.Pi, 2.0*math.Pi)),
+	}
+	switch {
+	case math.Abs(e-1.0) < parabolicTol:
+		s := math.Tan(nu / 2.0)
+		el.PerihelionTime = jd - (s*s*s+3.0*s)*math.Sqrt(2.0*q*q*q)/(3.0*gaussK)
+	case e < 1.0:
+		ea := 2.0 * math.Atan(math.Sqrt((1.0-e)/(1.0+e))*math.Tan(nu/2.0))
+		ma := ea - e*math.Sin(ea)
+		el.MeanAnomaly = au.NewAngle(au.Radian, math.Mod(ma+2.0*math.Pi, 2.0*math.Pi))
+		el.PerihelionTime = jd - ma/(gaussK/math.Pow(q/(1.0-e), 1.5))
+	default:
+		hh := 2.0 * math.Atanh(math.Sqrt((e-1.0)/(e+1.0))*math.Tan(nu/2.0))
+		ma := e*math.Sinh(hh) - hh
+		el.PerihelionTime = jd - ma/(gaussK/math.Pow(q/(e-1.0), 1.5))
+	}
+	return el
+}
+
+// solarSystem returns the position, AU, and velocity, AU/day, in the ICRS
+// of the NOVAS body at jd, TDB, from the barycenter (origin 0) or the Sun
+// (origin 1), from the ephemeris set with SetPlanetaryEphemeris or else
+// NOVAS. The caller must hold novasMu.
+func solarSystem(jd float64, body, origin int16) (pos, vel [3]float64, err error) {
+	if e := PlanetaryEphemeris(); e != nil {
+		return e.State(jd, body, origin)
+	}
+	p := make([]float64, 3)
+	v := make([]float64, 3)
+	if rc := nov.Solarsystem(jd, body, origin, p, v); rc != 0 {
+		emsg := fmt.Sprintf("No ephemeris for body %d at JD %.5f: error %d", body, jd, rc)
+		return pos, vel, errors.New(emsg)
+	}
+	copy(pos[:], p)
+	copy(vel[:], v)
+	return pos, vel, nil
+}
+
+// acceleration returns the heliocentric acceleration, AU/day^2, at r at
+// jd, TT, from the Sun and the planets other than the NOVAS body skip.
+func acceleration(jd float64, r [3]float64, skip int16) ([3]float64, error) {
+	rm := math.Sqrt(r[0]*r[0] + r[1]*r[1] + r[2]*r[2])
+	var a [3]float64
+	for idx := range a {
+		a[idx] = -gmSun * r[idx] / (rm * rm * rm)
+	}
+	novasMu.Lock()
+	defer novasMu.Unlock()
+	for _, p := range perturbers {
+		if p.body == skip {
+			continue
+		}
+		pj, _, err := solarSystem(jd, p.body, 1)
+		if err != nil {
+			return a, err
+		}
+		var d [3]float64
+		for idx := range d {
+			d[idx] = pj[idx] - r[idx]
+		}
+		dm := math.Sqrt(d[0]*d[0] + d[1]*d[1] + d[2]*d[2])
+		pm := math.Sqrt(pj[0]*pj[0] + pj[1]*pj[1] + pj[2]*pj[2])
+		for idx := range a {
+			a[idx] += p.gm * (d[idx]/(dm*dm*dm) - pj[idx]/(pm*pm*pm))
+		}
+	}
+	return a, nil
+}
+
+// integrate carries the heliocentric state pos, vel at jd0 to jd1 with
+// fourth order Runge-Kutta steps shortened near the Sun, perturbed by the
+// planets other than skip.
+func integrate(jd0 float64, pos, vel [3]float64, jd1 float64, skip int16) ([3]float64, [3]float64, error) {
+	const (
+		stepAt1AU = 0.5 // days
+		minStep   = 1e-3
+	)
+	type state struct{ r, v [3]float64 }
+	deriv := func(jd float64, s state) (state, error) {
+		a, err := acceleration(jd, s.r, skip)
+		return state{s.v, a}, err
+	}
+	add := func(s, d state, h float64) state {
+		for idx := 0; idx < 3; idx++ {
+			s.r[idx] += h * d.r[idx]
+			s.v[idx] += h * d.v[idx]
+		}
+		return s
+	}
+	s := state{pos, vel}
+	jd := jd0
+	for jd != jd1 {
+		rm := math.Sqrt(s.r[0]*s.r[0] + s.r[1]*s.r[1] + s.r[2]*s.r[2])
+		if math.IsNaN(rm) || rm == 0.0 {
+			emsg := fmt.Sprintf("Orbit integration failed at JD %.5f", jd)
+			return s.r, s.v, errors.New(emsg)
+		}
+		h := math.Max(stepAt1AU*math.Pow(rm, 1.5), minStep)
+		if math.Abs(jd1-jd) <= h {
+			h = math.Abs(jd1 - jd)
+		}
+		h = math.Copysign(h, jd1-jd)
+		k1, err := deriv(jd, s)
+		if err != nil {
+			return s.r, s.v, err
+		}
+		k2, err := deriv(jd+h/2.0, add(s, k1, h/2.0))
+		if err != nil {
+			return s.r, s.v, err
+		}
+		k3, err := deriv(jd+h/2.0, add(s, k2, h/2.0))
+		if err != nil {
+			return s.r, s.v, err
+		}
+		k4, err := deriv(jd+h, add(s, k3, h))
+		if err != nil {
+			return s.r, s.v, err
+		}
+		s = add(s, k1, h/6.0)
+		s = add(s, k2, h/3.0)
+		s = add(s, k3, h/3.0)
+		s = add(s, k4, h/6.0)
+		if math.Abs(jd1-jd) <= math.Abs(h) {
+			jd = jd1
+		} else {
+			jd += h
+		}
+	}
+	return s.r, s.v, nil
+}
@@ -0,0 +1,181 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// separation returns the distance between a and b.
+func separation(a, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
+
+func TestKepler(t *testing.T) {
+	for _, e := range []float64{0.0, 0.1, 0.5, 0.9, 0.999} {
+		for _, m := range []float64{0.001, 0.5, 2.0, 3.1, -1.0} {
+			ea := kepler(m, e)
+			th.CheckFT(t, ea-e*math.Sin(ea), m, 1e-12, fmt.Sprintf("Kepler e %g M %g Error", e, m))
+		}
+	}
+	for _, e := range []float64{1.001, 1.5, 5.0} {
+		for _, m := range []float64{0.001, 1.0, 20.0, -3.0} {
+			h := keplerHyperbolic(m, e)
+			th.CheckFT(t, e*math.Sinh(h)-h, m, 1e-10*math.Max(1.0, math.Abs(m)),
+				fmt.Sprintf("Hyperbolic Kepler e %g M %g Error", e, m))
+		}
+	}
+}
+
+func TestTwoBody(t *testing.T) {
+	ceres := OrbitalElements{
+		Name:               "Ceres",
+		Epoch:              2460600.5,
+		PerihelionDistance: 2.7670 * (1.0 - 0.0797),
+		Eccentricity:       0.0797,
+		Inclination:        au.NewAngle(au.Degree, 10.5868),
+		Node:               au.NewAngle(au.Degree, 80.2549),
+		ArgPerihelion:      au.NewAngle(au.Degree, 73.2989),
+		MeanAnomaly:        au.NewAngle(au.Degree, 145.8413),
+	}
+	th.CheckFT(t, ceres.SemiMajorAxis(), 2.7670, 1e-12, "Ceres semi-major axis Error")
+	// period, days
+	period := 2.0 * math.Pi / gaussK * math.Pow(ceres.SemiMajorAxis(), 1.5)
+	p0, v0 := ceres.twoBody(ceres.Epoch)
+	p1, v1 := ceres.twoBody(ceres.Epoch + period)
+	th.CheckFT(t, separation(p0, p1), 0.0, 1e-10, "Ceres period position Error")
+	th.CheckFT(t, separation(v0, v1), 0.0, 1e-12, "Ceres period velocity Error")
+
+	// the energy of the orbit
+	r := math.Sqrt(p0[0]*p0[0] + p0[1]*p0[1] + p0[2]*p0[2])
+	v2 := v0[0]*v0[0] + v0[1]*v0[1] + v0[2]*v0[2]
+	th.CheckFT(t, v2/2.0-gmSun/r, -gmSun/(2.0*ceres.SemiMajorAxis()), 1e-14, "Ceres energy Error")
+
+	hyperbolic := OrbitalElements{
+		Name:               "C/hyperbolic",
+		PerihelionTime:     2460700.25,
+		PerihelionDistance: 0.8,
+		Eccentricity:       1.2,
+		Inclination:        au.NewAngle(au.Degree, 122.0),
+		Node:               au.NewAngle(au.Degree, 15.0),
+		ArgPerihelion:      au.NewAngle(au.Degree, 300.0),
+	}
+	parabolic := hyperbolic
+	parabolic.Name = "C/parabolic"
+	parabolic.Eccentricity = 1.0
+	for _, el := range []OrbitalElements{ceres, hyperbolic, parabolic} {
+		err := el.validate()
+		if err != nil {
+			fmt.Println("validate error: ", err)
+			t.Fail()
+		}
+		p, _ := el.twoBody(el.perihelionTime())
+		th.CheckFT(t, math.Sqrt(p[0]*p[0]+p[1]*p[1]+p[2]*p[2]), el.PerihelionDistance, 1e-12,
+			el.Name+" perihelion distance Error")
+
+		// the elements of a state give back the orbit
+		jd := el.perihelionTime() + 123.4
+		pos, vel := el.twoBody(jd)
+		osc := ElementsFromState(el.Name, jd, pos, vel)
+		th.CheckFT(t, osc.Eccentricity, el.Eccentricity, 1e-9, el.Name+" eccentricity Error")
+		th.CheckFT(t, osc.PerihelionDistance, el.PerihelionDistance, 1e-9, el.Name+" q Error")
+		th.CheckFT(t, osc.Inclination.Degree().Value, el.Inclination.Degree().Value, 1e-8, el.Name+" inclination Error")
+		th.CheckFT(t, osc.Node.Degree().Value, el.Node.Degree().Value, 1e-8, el.Name+" node Error")
+		th.CheckFT(t, osc.PerihelionTime, el.perihelionTime(), 1e-6, el.Name+" perihelion time Error")
+		if math.Abs(el.Eccentricity-1.0) > parabolicTol {
+			th.CheckFT(t, osc.ArgPerihelion.Degree().Value, el.ArgPerihelion.Degree().Value, 1e-8,
+				el.Name+" argument of perihelion Error")
+		}
+		p2, _ := osc.twoBody(jd + 200.0)
+		p3, _ := el.twoBody(jd + 200.0)
+		th.CheckFT(t, separation(p2, p3), 0.0, 1e-8, el.Name+" propagated position Error")
+	}
+
+	bad := []OrbitalElements{
+		{Name: "no q", Eccentricity: 0.5, PerihelionTime: 2460000.5},
+		{Name: "no time", PerihelionDistance: 1.0, Eccentricity: 0.5},
+		{Name: "hyperbolic mean anomaly", PerihelionDistance: 1.0, Eccentricity: 1.5, Epoch: 2460000.5},
+	}
+	for _, el := range bad {
+		th.CheckErrorNil(t, el.validate(), el.Name+" validate expected error")
+	}
+}
+
+func TestPerturbedOrbit(t *testing.T) {
+	// Mars followed as a minor body from its osculating elements
+	jd0 := 2460389.5
+	novasMu.Lock()
+	pos, vel, err := solarSystem(jd0, 4, 1)
+	novasMu.Unlock()
+	if err != nil {
+		fmt.Println("solarSystem error: ", err)
+		t.FailNow()
+	}
+	el := ElementsFromState("Mars", jd0, pos, vel)
+	th.CheckFT(t, el.SemiMajorAxis(), 1.5237, 1e-3, "Mars semi-major axis Error")
+	unperturbed, _ := NewMinorBody(el, false)
+	perturbed, err := NewMinorBody(el, true)
+	if err != nil {
+		fmt.Println("NewMinorBody error: ", err)
+		t.FailNow()
+	}
+	perturbed.Self = 4
+
+	for _, dt := range []float64{0.0, 200.0, 400.0, -100.0} {
+		novasMu.Lock()
+		mars, _, err := solarSystem(jd0+dt, 4, 1)
+		novasMu.Unlock()
+		if err != nil {
+			fmt.Println("solarSystem error: ", err)
+			t.Fail()
+			continue
+		}
+		p1, _, _ := unperturbed.heliocentric(jd0 + dt)
+		p2, _, err := perturbed.heliocentric(jd0 + dt)
+		if err != nil {
+			fmt.Println("heliocentric error: ", err)
+			t.Fail()
+			continue
+		}
+		d1 := au.NewLength(au.AstronomicalUnit, separation(p1, mars)).Kilometer().Value
+		d2 := au.NewLength(au.AstronomicalUnit, separation(p2, mars)).Kilometer().Value
+		fmt.Printf("Mars %+6.0f d: two body %8.1f km, perturbed %8.1f km\n", dt, d1, d2)
+		if d2 > 1000.0 || (dt != 0.0 && d2 > d1/10.0) {
+			fmt.Println("Perturbed Mars orbit Error: ", d1, d2)
+			t.Fail()
+		}
+	}
+}
+
+func TestPerturbedOrbitOrder(t *testing.T) {
+	// the state does not depend on the times asked for before
+	jd0 := 2460389.5
+	novasMu.Lock()
+	pos, vel, err := solarSystem(jd0, 4, 1)
+	novasMu.Unlock()
+	if err != nil {
+		fmt.Println("solarSystem error: ", err)
+		t.FailNow()
+	}
+	el := ElementsFromState("Mars", jd0, pos, vel)
+	jds := []float64{jd0 + 123.4, jd0 - 57.3, jd0 + 31.0, jd0 + 250.0}
+	var fwd, rev [][3]float64
+	mb, _ := NewMinorBody(el, true)
+	for _, jd := range jds {
+		p, _, _ := mb.heliocentric(jd)
+		fwd = append(fwd, p)
+	}
+	mb, _ = NewMinorBody(el, true)
+	for idx := len(jds) - 1; idx >= 0; idx-- {
+		p, _, _ := mb.heliocentric(jds[idx])
+		rev = append([][3]float64{p}, rev...)
+	}
+	for idx := range jds {
+		for k := 0; k < 3; k++ {
+			th.CheckFT(t, fwd[idx][k], rev[idx][k], 0.0, fmt.Sprint("Order dependent state at ", jds[idx]))
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Approximate positions of the Sun, Moon and planets
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+// earthMoonRatio is the Earth mass over the Moon mass (DE405).
+const earthMoonRatio = 81.30056
+
+// meanElements are the heliocentric elements of a planet on the J2000
+// ecliptic and equinox at J2000 and their rates per Julian century:
+// semi-major axis, AU, eccentricity, inclination, mean longitude,
+// longitude of perihelion and longitude of the ascending node, degrees.
+type meanElements struct {
+	a, e, i, l, peri, node       float64
+	da, de, di, dl, dperi, dnode float64
+	rmass                        float64 // Sun mass over planet mass
+}
+
+// planetElements are the elements of Mercury to Pluto, by NOVAS number,
+// fit to DE405 from 1800 to 2050 (E M Standish, Keplerian Elements for
+// Approximate Positions of the Major Planets). The third is the
+// Earth-Moon barycenter.
+var planetElements = [9]meanElements{
+	{0.38709927, 0.20563593, 7.00497902, 252.25032350, 77.45779628, 48.33076593,
+		0.00000037, 0.00001906, -0.00594749, 149472.67411175, 0.16047689, -0.12534081, 6023600.0},
+	{0.72333566, 0.00677672, 3.39467605, 181.97909950, 131.60246718, 76.67984255,
+		0.00000390, -0.00004107, -0.00078890, 58517.81538729, 0.00268329, -0.27769418, 408523.71},
+	{1.00000261, 0.01671123, -0.00001531, 100.46457166, 102.93768193, 0.0,
+		0.00000562, -0.00004392, -0.01294668, 35999.37244981, 0.32327364, 0.0, 328900.56},
+	{1.52371034, 0.09339410, 1.84969142, -4.55343205, -23.94362959, 49.55953891,
+		0.00001847, 0.00007882, -0.00813131, 19140.30268499, 0.44441088, -0.29257343, 3098708.0},
+	{5.20288700, 0.04838624, 1.30439695, 34.39644051, 14.72847983, 100.47390909,
+		-0.00011607, -0.00013253, -0.00183714, 3034.74612775, 0.21252668, 0.20469106, 1047.3486},
+	{9.53667594, 0.05386179, 2.48599187, 49.95424423, 92.59887831, 113.66242448,
+		-0.00125060, -0.00050991, 0.00193609, 1222.49362201, -0.41897216, -0.28867794, 3497.898},
+	{19.18916464, 0.04725744, 0.77263783, 313.23810451, 170.95427630, 74.01692503,
+		-0.00196176, -0.00004397, -0.00242939, 428.48202785, 0.40805281, 0.04240589, 22902.98},
+	{30.06992276, 0.00859048, 1.77004347, -55.12002969, 44.96476227, 131.78422574,
+		0.00026291, 0.00005105, 0.00035372, 218.45945325, -0.32241464, -0.00508664, 19412.24},
+	{39.48211675, 0.24882730, 17.14001206, 238.92903833, 224.06891629, 110.30393684,
+		-0.00031596, 0.00005170, 0.00004818, 145.20780515, -0.04062942, -0.01183482, 1.352e8},
+}
+
+// position returns the heliocentric ICRS position, AU, of the planet at
+// jd, TDB.
+func (el meanElements) position(jd float64) [3]float64 {
+	t := (jd - at.J2000) / at.JulianCentury
+	rad := math.Pi / 180.0
+	a := el.a + el.da*t
+	e := el.e + el.de*t
+	i := (el.i + el.di*t) * rad
+	l := (el.l + el.dl*t) * rad
+	peri := (el.peri + el.dperi*t) * rad
+	node := (el.node + el.dnode*t) * rad
+	ea := kepler(l-peri, e)
+	xp := a * (math.Cos(ea) - e)
+	yp := a * math.Sqrt(1.0-e*e) * math.Sin(ea)
+	sw, cw := math.Sincos(peri - node)
+	sn, cn := math.Sincos(node)
+	si, ci := math.Sincos(i)
+	return eclipticToICRS([3]float64{
+		(cw*cn-sw*sn*ci)*xp + (-sw*cn-cw*sn*ci)*yp,
+		(cw*sn+sw*cn*ci)*xp + (-sw*sn+cw*cn*ci)*yp,
+		sw*si*xp + cw*si*yp,
+	})
+}
+
+// moonTerm is a periodic term of the lunar theory: the multiples of D, M,
+// M' and F, and the coefficients of longitude, 1e-6 degree, and distance,
+// m, or of latitude, 1e-6 degree.
+type moonTerm struct {
+	d, m, mp, f int8
+	c1, c2      float64
+}
+
+// moonLonDist are the principal terms in longitude and distance of the
+// ELP-2000/82 theory as given by Meeus, Astronomical Algorithms, ch. 47.
+var moonLonDist = []moonTerm{
+	{0, 0, 1, 0, 6288774, -20905355},
+	{2, 0, -1, 0, 1274027, -3699111},
+	{2, 0, 0, 0, 658314, -2955968},
+	{0, 0, 2, 0, 213618, -569925},
+	{0, 1, 0, 0, -185116, 48888},
+	{0, 0, 0, 2, -114332, -3149},
+	{2, 0, -2, 0, 58793, 246158},
+	{2, -1, -1, 0, 57066, -152138},
+	{2, 0, 1, 0, 53322, -170733},
+	{2, -1, 0, 0, 45758, -204586},
+	{0, 1, -1, 0, -40923, -129620},
+	{1, 0, 0, 0, -34720, 108743},
+	{0, 1, 1, 0, -30383, 104755},
+	{2, 0, 0, -2, 15327, 10321},
+	{0, 0, 1, 2, -12528, 0},
+	{0, 0, 1, -2, 10980, 79661},
+	{4, 0, -1, 0, 10675, -34782},
+	{0, 0, 3, 0, 10034, -23210},
+	{4, 0, -2, 0, 8548, -21636},
+	{2, 1, -1, 0, -7888, 24208},
+	{2, 1, 0, 0, -6766, 30824},
+	{1, 0, -1, 0, -5163, -8379},
+	{1, 1, 0, 0, 4987, -16675},
+	{2, -1, 1, 0, 4036, -12831},
+	{2, 0, 2, 0, 3994, -10445},
+	{4, 0, 0, 0, 3861, -11650},
+	{2, 0, -3, 0, 3665, 14403},
+	{0, 1, -2, 0, -2689, -7003},
+	{2, 0, -1, 2, -2602, 0},
+	{2, -1, -2, 0, 2390, 10056},
+	{1, 0, 1, 0, -2348, 6322},
+	{2, -2, 0, 0, 2236, -9884},
+}
+
+// moonLat are the principal terms in latitude.
+var moonLat = []moonTerm{
+	{0, 0, 0, 1, 5128122, 0},
+	{0, 0, 1, 1, 280602, 0},
+	{0, 0, 1, -1, 277693, 0},
+	{2, 0, 0, -1, 173237, 0},
+	{2, 0, -1, 1, 55413, 0},
+	{2, 0, -1, -1, 46271, 0},
+	{2, 0, 0, 1, 32573, 0},
+	{0, 0, 2, 1, 17198, 0},
+	{2, 0, 1, -1, 9266, 0},
+	{0, 0, 2, -1, 8822, 0},
+	{2, -1, 0, -1, 8216, 0},
+	{2, 0, -2, -1, 4324, 0},
+	{2, 0, 1, 1, 4200, 0},
+	{2, 1, 0, -1, -3359, 0},
+	{2, -1, -1, 1, 2463, 0},
+	{2, -1, 0, 1, 2211, 0},
+	{2, -1, -1, -1, 2065, 0},
+	{0, 1, -1, -1, -1870, 0},
+	{4, 0, -1, -1, 1828, 0},
+	{0, 1, 0, 1, -1794, 0},
+	{0, 0, 0, 3, -1749, 0},
+	{0, 1, -1, 1, -1565, 0},
+	{1, 0, 0, 1, -1491, 0},
+	{0, 1, 1, 1, -1475, 0},
+	{0, 1, 1, -1, -1410, 0},
+	{0, 1, 0, -1, -1344, 0},
+	{1, 0, 0, -1, -1335, 0},
+	{0, 0, 3, 1, 1107, 0},
+	{4, 0, 0, -1, 1021, 0},
+	{4, 0, -1, 1, 833, 0},
+}
+
+// moonPosition returns the geocentric ICRS position, AU, of the Moon at
+// jd, TDB, from the principal terms of the lunar theory, good to about 10
+// arcsec.
+func moonPosition(jd float64) [3]float64 {
+	t := (jd - at.J2000) / at.JulianCentury
+	rad := math.Pi / 180.0
+	// mean longitude, elongation, anomalies of the Sun and Moon and
+	// argument of latitude, degrees
+	lp := 218.3164477 + (481267.88123421+(-0.0015786+(1.0/538841.0-t/65194000.0)*t)*t)*t
+	d := 297.8501921 + (445267.1114034+(-0.0018819+(1.0/545868.0-t/113065000.0)*t)*t)*t
+	m := 357.5291092 + (35999.0502909+(-0.0001536+t/24490000.0)*t)*t
+	mp := 134.9633964 + (477198.8675055+(0.0087414+(1.0/69699.0-t/14712000.0)*t)*t)*t
+	f := 93.2720950 + (483202.0175233+(-0.0036539+(-1.0/3526000.0+t/863310000.0)*t)*t)*t
+	a1 := 119.75 + 131.849*t
+	a2 := 53.09 + 479264.290*t
+	a3 := 313.45 + 481266.484*t
+	// the decreasing eccentricity of the Earth's orbit
+	ecc := 1.0 - (0.002516+0.0000074*t)*t
+	arg := func(tm moonTerm) (float64, float64) {
+		x := (float64(tm.d)*d + float64(tm.m)*m + float64(tm.mp)*mp + float64(tm.f)*f) * rad
+		scale := math.Pow(ecc, math.Abs(float64(tm.m)))
+		return x, scale
+	}
+	var sl, sr, sb float64
+	for _, tm := range moonLonDist {
+		x, scale := arg(tm)
+		sl += scale * tm.c1 * math.Sin(x)
+		sr += scale * tm.c2 * math.Cos(x)
+	}
+	for _, tm := range moonLat {
+		x, scale := arg(tm)
+		sb += scale * tm.c1 * math.Sin(x)
+	}
+	sl += 3958.0*math.Sin(a1*rad) + 1962.0*math.Sin((lp-f)*rad) + 318.0*math.Sin(a2*rad)
+	sb += -2235.0*math.Sin(lp*rad) + 382.0*math.Sin(a3*rad) + 175.0*math.Sin((a1-f)*rad) +
+		175.0*math.Sin((a1+f)*rad) + 127.0*math.Sin((lp-mp)*rad) - 115.0*math.Sin((lp+mp)*rad)
+	lon := (lp + sl/1e6) * rad
+	lat := sb / 1e6 * rad
+	dist := au.NewLength(au.Kilometer, 385000.56+sr/1000.0).AstronomicalUnit().Value
+
+	// from the ecliptic of date to the mean equator of date and to the
+	// ICRS
+	so, co := math.Sincos(lon)
+	sa, ca := math.Sincos(lat)
+	v := [3]float64{dist * ca * co, dist * ca * so, dist * sa}
+	tt := at.NewJD(jd, 0.0)
+	se, ce := math.Sincos(at.MeanObliquity(tt))
+	v = [3]float64{v[0], ce*v[1] - se*v[2], se*v[1] + ce*v[2]}
+	return mat3(at.BiasPrecessionMatrix(tt)).applyT(v)
+}
+
+// approxPosition returns the barycentric ICRS position, AU, of the NOVAS
+// body at jd, TDB: the Sun moves about the barycenter opposite the
+// planets.
+func approxPosition(jd float64, body int16) [3]float64 {
+	var helio [9][3]float64
+	var sun [3]float64
+	mass := 1.0
+	for idx, el := range planetElements {
+		helio[idx] = el.position(jd)
+		for k := range sun {
+			sun[k] -= helio[idx][k] / el.rmass
+		}
+		mass += 1.0 / el.rmass
+	}
+	for k := range sun {
+		sun[k] /= mass
+	}
+	var pos [3]float64
+	switch body {
+	case 10:
+		return sun
+	case 3, 11:
+		moon := moonPosition(jd)
+		f := -1.0 / (1.0 + earthMoonRatio)
+		if body == 11 {
+			f = earthMoonRatio / (1.0 + earthMoonRatio)
+		}
+		for k := range pos {
+			pos[k] = sun[k] + helio[2][k] + f*moon[k]
+		}
+	default:
+		for k := range pos {
+			pos[k] = sun[k] + helio[body-1][k]
+		}
+	}
+	return pos
+}
+
+// approxState returns the barycentric ICRS position, AU, and velocity,
+// AU/day, of the NOVAS body at jd, TDB, from the mean elements and the
+// lunar theory, the velocity by central differences.
+func approxState(jd float64, body int16) (pos, vel [3]float64, err error) {
+	if body < 1 || body > 11 {
+		emsg := fmt.Sprintf("Invalid body %d", body)
+		return pos, vel, errors.New(emsg)
+	}
+	const h = 0.01 // days
+	pos = approxPosition(jd, body)
+	p0 := approxPosition(jd-h, body)
+	p1 := approxPosition(jd+h, body)
+	for k := range vel {
+		vel[k] = (p1[k] - p0[k]) / (2.0 * h)
+	}
+	return pos, vel, nil
+}
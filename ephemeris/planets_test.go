@@ -0,0 +1,100 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestApproxPlanets(t *testing.T) {
+	de, err := OpenJPLEphemeris("JPLEPH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer de.Close()
+
+	// geocentric error limits, arcsec, and relative distance error
+	limits := []struct {
+		name    string
+		body    int16
+		sep, dr float64
+	}{
+		{Mercury, 1, 90.0, 1e-3},
+		{Venus, 2, 90.0, 1e-3},
+		{Mars, 4, 90.0, 1e-3},
+		{Jupiter, 5, 400.0, 2e-3},
+		{Saturn, 6, 600.0, 2e-3},
+		{Uranus, 7, 150.0, 1e-3},
+		{Neptune, 8, 150.0, 1e-3},
+		{Pluto, 9, 150.0, 1e-3},
+		{Sun, 10, 60.0, 1e-4},
+		{Moon, 11, 30.0, 1e-4},
+	}
+	for _, ti := range []time.Time{
+		time.Date(1990, 7, 1, 18, 0, 0, 0, time.UTC),
+		time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC),
+		time.Date(2045, 1, 10, 0, 0, 0, 0, time.UTC),
+	} {
+		jd := NewInstant(ti).JDTT
+		e1, _, err := de.State(jd, 3, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e2, _, _ := approxState(jd, 3)
+		for _, l := range limits {
+			p1, _, err := de.State(jd, l.body, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p2, _, err := approxState(jd, l.body)
+			if err != nil {
+				fmt.Println("approxState error: ", err)
+				t.Fail()
+				continue
+			}
+			var g1, g2 [3]float64
+			for k := range g1 {
+				g1[k], g2[k] = p1[k]-e1[k], p2[k]-e2[k]
+			}
+			sep := math.Acos(math.Min(1.0, dot(g1, g2)/(norm(g1)*norm(g2)))) * 180.0 / math.Pi * 3600.0
+			msg := fmt.Sprintf("%v %s", ti, l.name)
+			th.CheckFT(t, sep, 0.0, l.sep, msg+" position Error")
+			th.CheckFT(t, (norm(g2)-norm(g1))/norm(g1), 0.0, l.dr, msg+" distance Error")
+		}
+	}
+
+	// the velocity of the Earth and the Moon
+	jd := 2460766.75
+	for _, body := range []int16{3, 11} {
+		_, v1, _ := de.State(jd, body, 0)
+		_, v2, err := approxState(jd, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		th.CheckFT(t, separation(v1, v2)/separation(v1, [3]float64{}), 0.0, 2e-3, "Velocity Error")
+	}
+	_, _, err = approxState(jd, 0)
+	th.CheckErrorNil(t, err, "approxState expected error for a bad body")
+
+	// the Go backend uses them when no ephemeris is set, independently of
+	// NOVAS
+	gb, nb := NewGoBackend(), NOVASBackend()
+	in := NewInstant(time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC))
+	for _, l := range limits {
+		b := Body{Name: l.name, Number: l.body}
+		ra1, dec1, _, err := nb.Apparent(in, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ra2, dec2, _, err := gb.Apparent(in, b)
+		if err != nil {
+			fmt.Println("Apparent error: ", err)
+			t.Fail()
+			continue
+		}
+		checkPlace(t, ra2, dec2, ra1, dec1, l.sep, l.name+" Go backend")
+	}
+}
@@ -0,0 +1,243 @@
+// Optical pointing run planning
+package ephemeris
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+const (
+	// default slew rates, degrees per second
+	defaultAzRate = 2.0
+	defaultElRate = 1.0
+)
+
+// PointingConfig describes an optical pointing run: the time window, the
+// magnitude range (inclusive) and elevation limits of the stars, how many
+// to observe, the time spent on each and the telescope's slew rates, which
+// default to 2 deg/s in azimuth and 1 deg/s in elevation. Backend, if set,
+// computes the star positions in place of NOVASBackend().
+type PointingConfig struct {
+	Start            time.Time     `yaml:"start" json:"start"`
+	End              time.Time     `yaml:"end" json:"end"`
+	MinMag           float64       `yaml:"minMag" json:"minMag"`
+	MaxMag           float64       `yaml:"maxMag" json:"maxMag"`
+	MinEl            au.Angle      `yaml:"minEl" json:"minEl"`
+	MaxEl            au.Angle      `yaml:"maxEl" json:"maxEl"`
+	Count            int           `yaml:"count" json:"count"`
+	Dwell            time.Duration `yaml:"dwell" json:"dwell"`
+	AzRate_degPerSec float64       `yaml:"azRate" json:"azRate"`
+	ElRate_degPerSec float64       `yaml:"elRate" json:"elRate"`
+	Backend          Backend       `yaml:"-" json:"-"`
+}
+
+// PointingTarget is one star of a pointing run. Time is when the star is
+// acquired, after Slew from the previous one, and AzEl its predicted
+// unrefracted position then, in degrees.
+type PointingTarget struct {
+	Key    string        `yaml:"key" json:"key"`
+	Source BSCdata       `yaml:"source" json:"source"`
+	Time   time.Time     `yaml:"time" json:"time"`
+	AzEl   au.AngleCoord `yaml:"azel" json:"azel"`
+	Slew   time.Duration `yaml:"slew" json:"slew"`
+}
+
+// validate checks cfg and fills in the default slew rates.
+func (cfg *PointingConfig) validate() error {
+	if cfg.Count <= 0 {
+		return errors.New("Pointing run needs at least one star")
+	}
+	if !cfg.End.After(cfg.Start) {
+		emsg := fmt.Sprintf("Pointing run ends %v before it starts %v", cfg.End, cfg.Start)
+		return errors.New(emsg)
+	}
+	e0, e1 := cfg.MinEl.Degree().Value, cfg.MaxEl.Degree().Value
+	if e0 < 0.0 || e1 > 90.0 || e0 >= e1 {
+		emsg := fmt.Sprintf("Invalid pointing elevation limits %.2f to %.2f", e0, e1)
+		return errors.New(emsg)
+	}
+	if cfg.MinMag > cfg.MaxMag {
+		emsg := fmt.Sprintf("Invalid pointing magnitude range %.2f to %.2f", cfg.MinMag, cfg.MaxMag)
+		return errors.New(emsg)
+	}
+	if cfg.AzRate_degPerSec <= 0.0 {
+		cfg.AzRate_degPerSec = defaultAzRate
+	}
+	if cfg.ElRate_degPerSec <= 0.0 {
+		cfg.ElRate_degPerSec = defaultElRate
+	}
+	return nil
+}
+
+// skyGrid returns n az/el points (degrees) spread evenly over the sky
+// between el0 and el1: the centres of rings of roughly square cells of
+// equal area.
+func skyGrid(n int, el0, el1 float64) []au.AngleCoord {
+	rad := math.Pi / 180.0
+	s0, s1 := math.Sin(el0*rad), math.Sin(el1*rad)
+	side := math.Sqrt(2.0*math.Pi*(s1-s0)/float64(n)) / rad
+	rings := int(math.Max(1.0, math.Round((el1-el0)/side)))
+	if rings > n {
+		rings = n
+	}
+	// share the points among the rings by area, largest remainder first
+	counts := make([]int, rings)
+	rem := make([]float64, rings)
+	edges := make([]float64, rings+1)
+	total := 0
+	for idx := range edges {
+		edges[idx] = el0 + (el1-el0)*float64(idx)/float64(rings)
+	}
+	for idx := range counts {
+		share := float64(n) * (math.Sin(edges[idx+1]*rad) - math.Sin(edges[idx]*rad)) / (s1 - s0)
+		counts[idx] = int(share)
+		rem[idx] = share - float64(counts[idx])
+		total += counts[idx]
+	}
+	order := make([]int, rings)
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool { return rem[order[i]] > rem[order[j]] })
+	for idx := 0; total < n; idx++ {
+		counts[order[idx%rings]]++
+		total++
+	}
+	var pts []au.AngleCoord
+	for idx, c := range counts {
+		el := (edges[idx] + edges[idx+1]) / 2.0
+		for j := 0; j < c; j++ {
+			pts = append(pts, au.NewAzElCoord(au.Degree, (float64(j)+0.5)*360.0/float64(c), el))
+		}
+	}
+	return pts
+}
+
+// slewTime returns the time to slew between az/el positions, the axes
+// moving together, taking the short way round in azimuth.
+func slewTime(from, to au.AngleCoord, azRate, elRate float64) time.Duration {
+	daz := math.Abs(math.Mod(to.Az().Degree().Value-from.Az().Degree().Value, 360.0))
+	if daz > 180.0 {
+		daz = 360.0 - daz
+	}
+	del := math.Abs(to.El().Degree().Value - from.El().Degree().Value)
+	s := math.Max(daz/azRate, del/elRate)
+	return time.Duration(s * float64(time.Second))
+}
+
+// PlanPointingRun selects stars from bsc for a pointing run from loc. The
+// sky between the elevation limits is divided into cfg.Count cells of
+// equal area and the star in the magnitude range nearest each cell's
+// centre at the start is chosen. The stars are then observed in greedy
+// order, each time slewing to the one reached soonest, and a star that
+// would be outside the elevation limits when the telescope gets there is
+// dropped. The run stops early if the window ends or no star is left.
+// Azimuth cable wraps are not modelled.
+func PlanPointingRun(loc Location, bsc *BSC, cfg PointingConfig) ([]PointingTarget, error) {
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	if bsc == nil {
+		return nil, errors.New("Pointing run needs a catalog")
+	}
+	ci := NewCatalogIndex(bsc)
+	if cfg.Backend != nil {
+		ci.SetBackend(cfg.Backend)
+	}
+	inMag := func(d BSCdata) bool {
+		return d.Magnitude >= cfg.MinMag && d.Magnitude <= cfg.MaxMag
+	}
+	az0, az1 := au.NewAngle(au.Degree, 0.0), au.NewAngle(au.Degree, 360.0)
+	stars, err := ci.BoxAzEl(loc, cfg.Start, az0, az1, cfg.MinEl, cfg.MaxEl, inMag)
+	if err != nil {
+		return nil, err
+	}
+
+	// the star nearest each cell, by catalog key as names need not be
+	// unique across catalogs
+	used := make(map[string]bool)
+	var targets []SourceMatch
+	for _, cell := range skyGrid(cfg.Count, cfg.MinEl.Degree().Value, cfg.MaxEl.Degree().Value) {
+		best := -1
+		for idx, s := range stars {
+			if used[s.Key] {
+				continue
+			}
+			if best < 0 || cell.Separation(s.Position).Radian().Value < cell.Separation(stars[best].Position).Radian().Value {
+				best = idx
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[stars[best].Key] = true
+		targets = append(targets, stars[best])
+	}
+
+	e0, e1 := cfg.MinEl.Degree().Value, cfg.MaxEl.Degree().Value
+	var run []PointingTarget
+	t := cfg.Start
+	var pos au.AngleCoord
+	for len(targets) > 0 {
+		// the star reached soonest from pos, the first at the start
+		next := 0
+		var slew time.Duration
+		if len(run) > 0 {
+			for idx, s := range targets {
+				azel, err := sourceAzEl(ci.backend, s.Key, s.Source, NewInstant(t), loc)
+				if err != nil {
+					return nil, err
+				}
+				d := slewTime(pos, azel, cfg.AzRate_degPerSec, cfg.ElRate_degPerSec)
+				if idx == 0 || d < slew {
+					next, slew = idx, d
+				}
+			}
+		}
+		best := targets[next]
+		targets = append(targets[:next], targets[next+1:]...)
+		// the star moves during the slew, so aim at where it will be
+		azel, err := sourceAzEl(ci.backend, best.Key, best.Source, NewInstant(t.Add(slew)), loc)
+		if err != nil {
+			return nil, err
+		}
+		obs := t
+		if len(run) > 0 {
+			slew = slewTime(pos, azel, cfg.AzRate_degPerSec, cfg.ElRate_degPerSec)
+			obs = t.Add(slew)
+		}
+		if obs.After(cfg.End) {
+			break
+		}
+		if el := azel.El().Degree().Value; el < e0 || el > e1 {
+			continue
+		}
+		run = append(run, PointingTarget{Key: best.Key, Source: best.Source, Time: obs, AzEl: azel, Slew: slew})
+		pos = azel
+		t = obs.Add(cfg.Dwell)
+	}
+	return run, nil
+}
+
+// WritePointingRun writes a pointing run as a run list, one star per line
+// with its observation time, name, J2000 position, magnitude and predicted
+// az/el.
+func WritePointingRun(w io.Writer, run []PointingTarget) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Time                  Name          RA              DEC               Mag     Az       El")
+	for _, p := range run {
+		ra, dec := p.Source.RA.Hour(), p.Source.DEC.Degree()
+		fmt.Fprintf(bw, "%-23s %-13s %-15s %-16s %6.2f %8.3f %8.3f\n", p.Time.UTC().Format(time.RFC3339),
+			p.Source.Name, ra.SexagesimalHMS(), signedDMS(dec), p.Source.Magnitude,
+			p.AzEl.Az().Degree().Value, p.AzEl.El().Degree().Value)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,123 @@
+package ephemeris
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestSkyGrid(t *testing.T) {
+	for _, n := range []int{1, 5, 12, 40} {
+		pts := skyGrid(n, 20.0, 80.0)
+		th.CheckI(t, len(pts), n, "Grid size Error")
+		for _, p := range pts {
+			el := p.El().Degree().Value
+			if el < 20.0 || el > 80.0 {
+				fmt.Println("Grid point outside the elevation limits: ", p)
+				t.Fail()
+			}
+		}
+	}
+	// every quadrant of azimuth is covered
+	quads := make(map[int]int)
+	for _, p := range skyGrid(24, 20.0, 80.0) {
+		quads[int(p.Az().Degree().Value/90.0)]++
+	}
+	th.CheckI(t, len(quads), 4, "Grid azimuth coverage Error")
+}
+
+func TestPlanPointingRun(t *testing.T) {
+	bsc := DefaultBSC()
+	loc, _ := Site("OVRO")
+	cfg := PointingConfig{
+		Start:  time.Date(2024, 3, 20, 5, 0, 0, 0, time.UTC),
+		End:    time.Date(2024, 3, 20, 7, 0, 0, 0, time.UTC),
+		MinMag: 1.0,
+		MaxMag: 4.0,
+		MinEl:  au.NewAngle(au.Degree, 20.0),
+		MaxEl:  au.NewAngle(au.Degree, 80.0),
+		Count:  12,
+		Dwell:  2 * time.Minute,
+	}
+	run, err := PlanPointingRun(loc, &bsc, cfg)
+	if err != nil {
+		fmt.Println("PlanPointingRun error: ", err)
+		t.Fail()
+		return
+	}
+	th.CheckI(t, len(run), 12, "Run length Error")
+	si := loc.onSurface()
+	seen := make(map[string]bool)
+	quads := make(map[int]int)
+	prev := cfg.Start.Add(-time.Second)
+	for _, p := range run {
+		if seen[p.Key] {
+			fmt.Println("Star observed twice: ", p.Key)
+			t.Fail()
+		}
+		seen[p.Key] = true
+		if !p.Time.After(prev) || p.Time.After(cfg.End) {
+			fmt.Println("Observation time out of order or window: ", p.Time)
+			t.Fail()
+		}
+		prev = p.Time.Add(cfg.Dwell)
+		if p.Source.Magnitude < 1.0 || p.Source.Magnitude > 4.0 {
+			fmt.Println("Star outside the magnitude range: ", p.Key, p.Source.Magnitude)
+			t.Fail()
+		}
+		// the predicted position is where the star is at the time
+		track, _ := SimpleTrack(si, p.Key, &bsc)
+		az, el, _ := track(p.Time)
+		sep := p.AzEl.Separation(au.NewAzElCoord(au.Degree, az, el)).Degree().Value
+		th.CheckFT(t, sep, 0.0, 0.01, p.Key+" predicted position Error")
+		if el < 20.0-0.01 || el > 80.0+0.01 {
+			fmt.Println("Star outside the elevation limits: ", p.Key, el)
+			t.Fail()
+		}
+		quads[int(az/90.0)]++
+	}
+	th.CheckI(t, len(quads), 4, "Run azimuth coverage Error")
+
+	// each star is the quickest to reach of those still to be observed
+	for idx := 1; idx < len(run); idx++ {
+		from := run[idx-1]
+		ti := from.Time.Add(cfg.Dwell)
+		for _, p := range run[idx+1:] {
+			track, _ := SimpleTrack(si, p.Key, &bsc)
+			az, el, _ := track(ti)
+			d := slewTime(from.AzEl, au.NewAzElCoord(au.Degree, az, el), defaultAzRate, defaultElRate)
+			if d < run[idx].Slew-2*time.Second {
+				fmt.Println("Greedy order Error: ", run[idx].Key, run[idx].Slew, p.Key, d)
+				t.Fail()
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	WritePointingRun(&buf, run)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	th.CheckI(t, len(lines), len(run)+1, "Run list length Error")
+
+	// a short window ends the run early
+	short := cfg
+	short.End = cfg.Start.Add(5 * time.Minute)
+	run, _ = PlanPointingRun(loc, &bsc, short)
+	if len(run) == 0 || len(run) > 3 {
+		fmt.Println("Short window run length: ", len(run))
+		t.Fail()
+	}
+
+	bad := cfg
+	bad.MinEl = au.NewAngle(au.Degree, 85.0)
+	_, err = PlanPointingRun(loc, &bsc, bad)
+	th.CheckErrorNil(t, err, "Expected elevation limit error")
+	bad = cfg
+	bad.Count = 0
+	_, err = PlanPointingRun(loc, &bsc, bad)
+	th.CheckErrorNil(t, err, "Expected star count error")
+}
@@ -0,0 +1,297 @@
+// Rise, transit and set times
+package ephemeris
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	au "github.com/rh-codebase/astrogo/astrounit"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+const (
+	// Enums to identify rise/transit/set events
+	_ Event = iota
+	Rise
+	Transit
+	Set
+
+	// Event strings
+	RiseStr    = "rise"
+	TransitStr = "transit"
+	SetStr     = "set"
+
+	// scan step when looking for events, and the resolution they are
+	// refined to
+	riseSetStep       = 10 * time.Minute
+	riseSetResolution = time.Second
+	// a little more than half a lunar day, so every transit in the
+	// requested range has a lower culmination on either side
+	riseSetPad = 14 * time.Hour
+)
+
+type Event int
+
+// String returns the name of the event.
+func (ev Event) String() string {
+	var s string
+	switch ev {
+	case Rise:
+		s = RiseStr
+	case Transit:
+		s = TransitStr
+	case Set:
+		s = SetStr
+	}
+	return s
+}
+
+// RiseSet holds one upper transit of a source, its highest elevation and
+// the rise and set around it. Rise is zero if the source is already above
+// the limit at the preceding lower culmination, Set if it is still above
+// at the following one. Rise and Set may fall outside the requested range.
+type RiseSet struct {
+	Rise        time.Time `yaml:"rise" json:"rise"`
+	Transit     time.Time `yaml:"transit" json:"transit"`
+	Set         time.Time `yaml:"set" json:"set"`
+	MaxEl       au.Angle  `yaml:"maxEl" json:"maxEl"`
+	Circumpolar bool      `yaml:"circumpolar" json:"circumpolar"`
+	NeverRises  bool      `yaml:"neverRises" json:"neverRises"`
+}
+
+// sample is the elevation (degrees) and hour angle (hours) of a target,
+// or its separation (degrees) from another body.
+type sample struct {
+	t   time.Time
+	el  float64
+	ha  float64
+	sep float64
+}
+
+// sampler returns a function giving the elevation, with refraction if
+// refract is not nil, and hour angle of tg at loc, computed by be.
+func sampler(be Backend, tg target, loc Location, refract func(au.Angle) (au.Angle, error)) func(time.Time) (sample, error) {
+	return func(ti time.Time) (sample, error) {
+		s := sample{t: ti}
+		ep := NewInstant(ti)
+		ra, dec, _, err := tg.topo(be, ep, loc)
+		if err != nil {
+			return s, err
+		}
+		_, zd := be.Horizon(ep, loc, ra, dec)
+		s.el = 90.0 - zd
+		if refract != nil {
+			el, err := refract(au.NewAngle(au.Degree, s.el))
+			if err != nil {
+				return s, err
+			}
+			s.el = el.Degree().Value
+		}
+		s.ha = at.HourAngle(ti, loc.Longitude.Degree().Value, ra)
+		return s, nil
+	}
+}
+
+// bisect narrows [a, b] to riseSetResolution around the point where
+// side changes from side(a) and returns the sample there.
+func bisect(f func(time.Time) (sample, error), a, b sample, side func(sample) bool) (sample, error) {
+	sa := side(a)
+	for b.t.Sub(a.t) > riseSetResolution {
+		m, err := f(a.t.Add(b.t.Sub(a.t) / 2))
+		if err != nil {
+			return m, err
+		}
+		if side(m) == sa {
+			a = m
+		} else {
+			b = m
+		}
+	}
+	return b, nil
+}
+
+// maxElevation returns the highest point within an hour of the transit
+// up. For the Moon and planets the declination changes enough that this
+// is not quite on the meridian.
+func maxElevation(f func(time.Time) (sample, error), up sample) (sample, error) {
+	// golden section search
+	const g = 0.6180339887498949
+	a, b := up.t.Add(-time.Hour), up.t.Add(time.Hour)
+	c := b.Add(-time.Duration(g * float64(b.Sub(a))))
+	d := a.Add(time.Duration(g * float64(b.Sub(a))))
+	sc, err := f(c)
+	if err != nil {
+		return sc, err
+	}
+	sd, err := f(d)
+	if err != nil {
+		return sd, err
+	}
+	for b.Sub(a) > riseSetResolution {
+		if sc.el > sd.el {
+			b, d, sd = d, c, sc
+			c = b.Add(-time.Duration(g * float64(b.Sub(a))))
+			sc, err = f(c)
+		} else {
+			a, c, sc = c, d, sd
+			d = a.Add(time.Duration(g * float64(b.Sub(a))))
+			sd, err = f(d)
+		}
+		if err != nil {
+			return up, err
+		}
+	}
+	best := up
+	for _, s := range []sample{sc, sd} {
+		if s.el > best.el {
+			best = s
+		}
+	}
+	return best, nil
+}
+
+// riseTransitSet finds every upper transit in [start, end] and the rise
+// and set of the source through elLimit (degrees) around it.
+func riseTransitSet(f func(time.Time) (sample, error), start, end time.Time, elLimit float64) ([]RiseSet, error) {
+	if end.Before(start) {
+		emsg := fmt.Sprintf("End %v is before start %v", end, start)
+		return nil, errors.New(emsg)
+	}
+	var uppers, lowers []sample
+	prev, err := f(start.Add(-riseSetPad))
+	if err != nil {
+		return nil, err
+	}
+	stop := end.Add(riseSetPad)
+	for ti := prev.t.Add(riseSetStep); !ti.After(stop.Add(riseSetStep)); ti = ti.Add(riseSetStep) {
+		cur, err := f(ti)
+		if err != nil {
+			return nil, err
+		}
+		if prev.ha < 0.0 && cur.ha >= 0.0 && cur.ha-prev.ha < 6.0 {
+			// upper culmination, the hour angle goes through 0
+			up, err := bisect(f, prev, cur, func(s sample) bool { return s.ha < 0.0 })
+			if err != nil {
+				return nil, err
+			}
+			uppers = append(uppers, up)
+		} else if prev.ha-cur.ha > 12.0 {
+			// lower culmination, the hour angle wraps from +12 to -12
+			low, err := bisect(f, prev, cur, func(s sample) bool { return s.ha > 0.0 })
+			if err != nil {
+				return nil, err
+			}
+			lowers = append(lowers, low)
+		}
+		prev = cur
+	}
+
+	var rs []RiseSet
+	above := func(s sample) bool { return s.el >= elLimit }
+	for _, up := range uppers {
+		if up.t.Before(start) || up.t.After(end) {
+			continue
+		}
+		// the lower culminations either side of the transit
+		idx := sort.Search(len(lowers), func(i int) bool { return lowers[i].t.After(up.t) })
+		if idx == 0 || idx == len(lowers) {
+			emsg := fmt.Sprintf("No lower culmination found around transit at %v", up.t)
+			return nil, errors.New(emsg)
+		}
+		before, after := lowers[idx-1], lowers[idx]
+		maxEl, err := maxElevation(f, up)
+		if err != nil {
+			return nil, err
+		}
+		r := RiseSet{Transit: up.t, MaxEl: au.NewAngle(au.Degree, maxEl.el)}
+		if !above(up) {
+			r.NeverRises = true
+			rs = append(rs, r)
+			continue
+		}
+		if above(before) && above(after) {
+			r.Circumpolar = true
+		}
+		// elevation increases monotonically from the lower to the upper
+		// culmination, and decreases after it
+		if !above(before) {
+			rise, err := bisect(f, before, up, above)
+			if err != nil {
+				return nil, err
+			}
+			r.Rise = rise.t
+		}
+		if !above(after) {
+			set, err := bisect(f, up, after, above)
+			if err != nil {
+				return nil, err
+			}
+			r.Set = set.t
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// RiseTransitSet returns the rise, transit and set through elLimit for
+// every upper transit of the source between start and end, as seen from
+// the Ephemeris location. Refraction is applied if enabled.
+func (e *Ephemeris) RiseTransitSet(start, end time.Time, elLimit au.Angle) ([]RiseSet, error) {
+	e.mu.Lock()
+	if !e.hasTarget {
+		e.mu.Unlock()
+		return nil, errors.New("Ephemeris has no source")
+	}
+	f := sampler(e.backend, e.target, e.location, e.refractor())
+	e.mu.Unlock()
+	return riseTransitSet(f, start, end, elLimit.Degree().Value)
+}
+
+// RiseTransitSet returns the rise, transit and set through elLimit for
+// every upper transit of sourceName between start and end as seen from si.
+// No refraction is applied. sourceName and opts are as in SimpleTrack.
+func RiseTransitSet(si nov.OnSurface, sourceName string, bsc *BSC, start, end time.Time,
+	elLimit au.Angle, opts ...Option) ([]RiseSet, error) {
+	tg, err := resolveTarget(sourceName, bsc)
+	if err != nil {
+		return nil, err
+	}
+	be := applyOptions(opts).backend
+	return riseTransitSet(sampler(be, tg, siteLocation(si), nil), start, end, elLimit.Degree().Value)
+}
+
+// EventTrigger returns an astrotime.Trigger firing at each rise, transit
+// or set of the source through elLimit, for use with astrotime.Scheduler.
+func (e *Ephemeris) EventTrigger(ev Event, elLimit au.Angle) at.Trigger {
+	return func(after time.Time) (time.Time, error) {
+		// two days always holds the next transit, and the rise and set
+		// that go with it unless the source is circumpolar. Start a day
+		// early to catch the set after a transit just before 'after'.
+		for span := 0; span < 3; span++ {
+			start := after.Add(time.Duration(2*span-1) * 24 * time.Hour)
+			rs, err := e.RiseTransitSet(start, start.Add(48*time.Hour), elLimit)
+			if err != nil {
+				return time.Time{}, err
+			}
+			for _, r := range rs {
+				var t time.Time
+				switch ev {
+				case Rise:
+					t = r.Rise
+				case Transit:
+					t = r.Transit
+				case Set:
+					t = r.Set
+				}
+				if !t.IsZero() && t.After(after) {
+					return t, nil
+				}
+			}
+		}
+		emsg := fmt.Sprintf("No %s of %s within 5 days of %v", ev, e.GetSource(), after)
+		return time.Time{}, errors.New(emsg)
+	}
+}
@@ -0,0 +1,121 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+func TestRiseTransitSet(t *testing.T) {
+	bsc := DefaultBSC()
+	var si nov.OnSurface
+	nov.MakeOnSurface(37.2339, -118.282, 1222., 0.0, 0.0, &si)
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+	limit := au.NewAngle(au.Degree, 10.0)
+
+	for _, src := range []string{"alpboo", "Sun", "Moon", "Jupiter"} {
+		rs, err := RiseTransitSet(si, src, &bsc, start, end, limit)
+		if err != nil {
+			fmt.Println("RiseTransitSet error: ", err)
+			t.Fail()
+			continue
+		}
+		// a transit a day, two for the Moon only if it is early
+		if len(rs) < 1 || len(rs) > 2 {
+			fmt.Println(src, " transits: ", len(rs))
+			t.Fail()
+		}
+		track, _ := SimpleTrack(si, src, &bsc)
+		for _, r := range rs {
+			if r.Transit.Before(start) || r.Transit.After(end) {
+				fmt.Println(src, " transit outside range: ", r.Transit)
+				t.Fail()
+			}
+			if !r.Rise.Before(r.Transit) || !r.Set.After(r.Transit) {
+				fmt.Println(src, " out of order: ", r)
+				t.Fail()
+			}
+			_, el, _ := track(r.Rise)
+			th.CheckFT(t, el, 10.0, 0.01, src+" rise elevation Error")
+			_, el, _ = track(r.Set)
+			th.CheckFT(t, el, 10.0, 0.01, src+" set elevation Error")
+			// the highest point is at or near the transit
+			for _, dt := range []time.Duration{-10 * time.Minute, 0, 10 * time.Minute} {
+				_, el, _ = track(r.Transit.Add(dt))
+				if el > r.MaxEl.Degree().Value+1e-6 {
+					fmt.Println(src, " MaxEl not a maximum: ", el, r.MaxEl.Degree().Value)
+					t.Fail()
+				}
+			}
+		}
+	}
+
+	// circumpolar and never rises
+	rs, err := RiseTransitSet(si, "ra: 2.5\ndec: 85.0", nil, start, end, limit)
+	if err != nil || len(rs) != 2 || !rs[0].Circumpolar || !rs[0].Rise.IsZero() || !rs[0].Set.IsZero() {
+		fmt.Println("Circumpolar Error: ", rs, err)
+		t.Fail()
+	}
+	rs, err = RiseTransitSet(si, "ra: 2.5\ndec: -60.0", nil, start, end, limit)
+	if err != nil || len(rs) != 2 || !rs[0].NeverRises {
+		fmt.Println("NeverRises Error: ", rs, err)
+		t.Fail()
+	}
+	// precession moves the J2000 declination by about 0.1 degrees
+	th.CheckFT(t, rs[0].MaxEl.Degree().Value, 90.0-37.2339-60.0, 0.2, "NeverRises MaxEl Error")
+}
+
+func TestEphemerisRiseSet(t *testing.T) {
+	var loc Location
+	loc.Latitude = au.NewAngle(au.Degree, 37.2339)
+	loc.Longitude = au.NewAngle(au.Degree, -118.282)
+	loc.Height = au.NewLength(au.Meter, 1222.0)
+	e, err := NewEphemeris("ra: 14.261\ndec: 19.18", loc, nil)
+	if err != nil {
+		fmt.Println("NewEphemeris error: ", err)
+		t.Fail()
+		return
+	}
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	limit := au.NewAngle(au.Degree, 0.0)
+	rs, err := e.RiseTransitSet(start, start.Add(24*time.Hour), limit)
+	if err != nil || len(rs) != 1 {
+		fmt.Println("RiseTransitSet Error: ", rs, err)
+		t.Fail()
+		return
+	}
+
+	// refraction lifts the source so it rises earlier and sets later
+	airT, _ := au.NewTemperature(au.Celsius, 10.0)
+	e.SetWeather(au.NewPressure(au.Millibar, 1013.0), airT, 50.0)
+	e.SetFreq(1.e9)
+	e.SetRefraction(true)
+	rsr, err := e.RiseTransitSet(start, start.Add(24*time.Hour), limit)
+	if err != nil || len(rsr) != 1 {
+		fmt.Println("RiseTransitSet Error: ", rsr, err)
+		t.Fail()
+		return
+	}
+	if !rsr[0].Rise.Before(rs[0].Rise) || !rsr[0].Set.After(rs[0].Set) {
+		fmt.Println("Refraction Error: ", rs[0], rsr[0])
+		t.Fail()
+	}
+	if math.Abs(rsr[0].Transit.Sub(rs[0].Transit).Seconds()) > 1.0 {
+		fmt.Println("Transit should not move: ", rs[0].Transit, rsr[0].Transit)
+		t.Fail()
+	}
+
+	// the trigger finds the next set after a rise
+	set, err := e.EventTrigger(Set, limit)(rsr[0].Rise)
+	if err != nil || math.Abs(set.Sub(rsr[0].Set).Seconds()) > 1.0 {
+		fmt.Println("EventTrigger Error: ", set, rsr[0].Set, err)
+		t.Fail()
+	}
+	th.CheckS(t, Transit.String(), TransitStr, "Event String Error")
+}
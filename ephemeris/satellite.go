@@ -0,0 +1,132 @@
+// Topocentric satellite positions
+package ephemeris
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+const (
+	wgs84Radius     = 6378.137 // km
+	wgs84Flattening = 1.0 / 298.257223563
+	earthRotation   = 7.292115146706979e-5 // rad/s
+)
+
+// SatelliteLook is the topocentric position of a satellite, with no
+// refraction applied.
+type SatelliteLook struct {
+	AzEl      au.AngleCoord
+	Range     au.Length
+	RangeRate float64 // km/s, positive when receding
+}
+
+// LoadSatellite reads the element file fn and returns the named satellite,
+// by name or catalog number, initialised for propagation.
+func LoadSatellite(fn, name string) (*Satellite, error) {
+	tles, err := ReadTLE(fn)
+	if err != nil {
+		return nil, err
+	}
+	tle, err := FindTLE(tles, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewSatellite(tle)
+}
+
+// siteECEF returns the Earth fixed position, in km, of loc on the WGS-84
+// ellipsoid.
+func siteECEF(loc Location) [3]float64 {
+	lat := loc.Latitude.Radian().Value
+	lon := loc.Longitude.Radian().Value
+	h := loc.Height.Kilometer().Value
+	e2 := wgs84Flattening * (2.0 - wgs84Flattening)
+	n := wgs84Radius / math.Sqrt(1.0-e2*math.Sin(lat)*math.Sin(lat))
+	return [3]float64{
+		(n + h) * math.Cos(lat) * math.Cos(lon),
+		(n + h) * math.Cos(lat) * math.Sin(lon),
+		(n*(1.0-e2) + h) * math.Sin(lat),
+	}
+}
+
+// temeToECEF rotates the TEME position, km, and velocity, km/s, at ep to
+// the Earth fixed frame, using the mean sidereal time TEME is defined with
+// and polar motion.
+func temeToECEF(ep Instant, r, v [3]float64) (re, ve [3]float64) {
+	gmst := gstime(ep.JDUT1)
+	cg, sg := math.Cos(gmst), math.Sin(gmst)
+	rp := [3]float64{cg*r[0] + sg*r[1], -sg*r[0] + cg*r[1], r[2]}
+	vp := [3]float64{cg*v[0] + sg*v[1], -sg*v[0] + cg*v[1], v[2]}
+	// the velocity relative to the rotating Earth
+	vp[0] += earthRotation * rp[1]
+	vp[1] -= earthRotation * rp[0]
+
+	xp := ep.EOP.Xp / 3600.0 * math.Pi / 180.0
+	yp := ep.EOP.Yp / 3600.0 * math.Pi / 180.0
+	cx, sx := math.Cos(xp), math.Sin(xp)
+	cy, sy := math.Cos(yp), math.Sin(yp)
+	pm := func(a [3]float64) [3]float64 {
+		return [3]float64{
+			cx*a[0] + sx*sy*a[1] + sx*cy*a[2],
+			cy*a[1] - sy*a[2],
+			-sx*a[0] + cx*sy*a[1] + cx*cy*a[2],
+		}
+	}
+	return pm(rp), pm(vp)
+}
+
+// Look returns the topocentric position of the satellite at t seen from
+// si.
+func (s *Satellite) Look(si nov.OnSurface, t time.Time) (SatelliteLook, error) {
+	var look SatelliteLook
+	r, v, err := s.Propagate(t)
+	if err != nil {
+		return look, err
+	}
+	re, ve := temeToECEF(NewInstant(t), r, v)
+	site := siteECEF(siteLocation(si))
+	var rho [3]float64
+	for idx := range rho {
+		rho[idx] = re[idx] - site[idx]
+	}
+
+	// to east, north, up
+	lat := si.Latitude * math.Pi / 180.0
+	lon := si.Longitude * math.Pi / 180.0
+	sl, cl := math.Sin(lat), math.Cos(lat)
+	so, co := math.Sin(lon), math.Cos(lon)
+	e := -so*rho[0] + co*rho[1]
+	n := -sl*co*rho[0] - sl*so*rho[1] + cl*rho[2]
+	u := cl*co*rho[0] + cl*so*rho[1] + sl*rho[2]
+	rng := math.Sqrt(e*e + n*n + u*u)
+	az := math.Atan2(e, n) * 180.0 / math.Pi
+	if az < 0.0 {
+		az += 360.0
+	}
+	el := math.Asin(u/rng) * 180.0 / math.Pi
+
+	look.AzEl = au.NewAzElCoord(au.Degree, az, el)
+	look.Range = au.NewLength(au.Kilometer, rng)
+	look.RangeRate = (rho[0]*ve[0] + rho[1]*ve[1] + rho[2]*ve[2]) / rng
+	return look, nil
+}
+
+// SatelliteTrack returns a function giving the azimuth and elevation, in
+// degrees, of the satellite from si at a time, as SimpleTrack does for
+// other sources.
+func SatelliteTrack(si nov.OnSurface, sat *Satellite) (func(time.Time) (float64, float64, error), error) {
+	if sat == nil {
+		return nil, errors.New("No satellite to track")
+	}
+	return func(ti time.Time) (az float64, el float64, err error) {
+		look, err := sat.Look(si, ti)
+		if err != nil {
+			return az, el, err
+		}
+		return look.AzEl.Az().Degree().Value, look.AzEl.El().Degree().Value, nil
+	}, nil
+}
@@ -0,0 +1,107 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+	nov "github.com/rh-codebase/novasgo/novas"
+)
+
+var testGEO = [2]string{
+	"1 28884U 05041A   24079.50000000 -.00000101  00000-0  00000+0 0  9999",
+	"2 28884   0.0210  85.4390 0002385 302.4940 163.5990  1.00270000 67000",
+}
+
+// geodetic returns the point on the WGS-84 ellipsoid below the Earth fixed
+// position r, in km, and the height of r above it.
+func geodetic(r [3]float64) (nov.OnSurface, float64) {
+	e2 := wgs84Flattening * (2.0 - wgs84Flattening)
+	p := math.Hypot(r[0], r[1])
+	lat := math.Atan2(r[2], p*(1.0-e2))
+	var n float64
+	for idx := 0; idx < 10; idx++ {
+		n = wgs84Radius / math.Sqrt(1.0-e2*math.Sin(lat)*math.Sin(lat))
+		lat = math.Atan2(r[2]+e2*n*math.Sin(lat), p)
+	}
+	si := nov.OnSurface{
+		Latitude:  lat * 180.0 / math.Pi,
+		Longitude: math.Atan2(r[1], r[0]) * 180.0 / math.Pi,
+	}
+	return si, p/math.Cos(lat) - n
+}
+
+func TestSatelliteLook(t *testing.T) {
+	tle, _ := ParseTLE("ISS", testISS[0], testISS[1])
+	sat, err := NewSatellite(tle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := tle.Epoch.Add(40 * time.Minute)
+
+	// seen from directly below it is at the zenith
+	r, v, _ := sat.Propagate(ti)
+	re, _ := temeToECEF(NewInstant(ti), r, v)
+	below, height := geodetic(re)
+	look, err := sat.Look(below, ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckFT(t, look.AzEl.El().Degree().Value, 90.0, 1e-6, "Zenith from below Error")
+	th.CheckFT(t, look.Range.Kilometer().Value, height, 1e-6, "Range from below Error")
+
+	// the range rate is the derivative of the range
+	loc, _ := Site("OVRO")
+	si := loc.onSurface()
+	for _, m := range []float64{0.0, 17.0, 33.0, 71.0} {
+		tm := tle.Epoch.Add(time.Duration(m * float64(time.Minute)))
+		look, _ := sat.Look(si, tm)
+		before, _ := sat.Look(si, tm.Add(-time.Second))
+		after, _ := sat.Look(si, tm.Add(time.Second))
+		rate := (after.Range.Kilometer().Value - before.Range.Kilometer().Value) / 2.0
+		th.CheckFT(t, look.RangeRate, rate, 1e-4, "Range rate Error")
+	}
+
+	// SatelliteTrack gives the look angles
+	track, err := SatelliteTrack(si, sat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	az, el, _ := track(ti)
+	look, _ = sat.Look(si, ti)
+	th.CheckF(t, az, look.AzEl.Az().Degree().Value, "Track az Error")
+	th.CheckF(t, el, look.AzEl.El().Degree().Value, "Track el Error")
+	_, err = SatelliteTrack(si, nil)
+	th.CheckErrorNil(t, err, "Expected no satellite error")
+}
+
+func TestSatelliteGeostationary(t *testing.T) {
+	tle, _ := ParseTLE("GEO", testGEO[0], testGEO[1])
+	sat, err := NewSatellite(tle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, sat.irez, 1, "Synchronous resonance Error")
+	loc, _ := Site("OVRO")
+	track, _ := SatelliteTrack(loc.onSurface(), sat)
+	az0, el0, err := track(tle.Epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a geostationary satellite stays put in the sky
+	for h := 1; h <= 48; h += 6 {
+		az, el, err := track(tle.Epoch.Add(time.Duration(h) * time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		th.CheckFT(t, az, az0, 0.1, "Geostationary az Error")
+		th.CheckFT(t, el, el0, 0.1, "Geostationary el Error")
+	}
+	look, _ := sat.Look(loc.onSurface(), tle.Epoch)
+	if rng := look.Range.Kilometer().Value; rng < 35786.0 || rng > 41700.0 {
+		fmt.Println("Geostationary range: ", rng)
+		t.Fail()
+	}
+}
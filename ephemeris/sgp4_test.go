@@ -0,0 +1,121 @@
+package ephemeris
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// Vallado's verification element sets: a near earth orbit, a deep space
+// orbit from Spacetrack Report #3 and a 12 hour resonant Molniya orbit.
+var sgp4TLEs = [][3]string{
+	{"00005",
+		"1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753",
+		"2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667"},
+	{"11801",
+		"1 11801U          80230.29629788  .01431103  00000-0  14311-1      13",
+		"2 11801  46.7916 230.4354 7318036  47.4722  10.4117  2.28537848    13"},
+	{"08195",
+		"1 08195U 75081A   06176.33215444  .00000099  00000-0  11873-3 0   813",
+		"2 08195  64.1586 279.0717 6877146 264.7651  20.2257  2.00491383225656"},
+}
+
+var testISS = [2]string{
+	"1 25544U 98067A   24079.50000000  .00016717  00000-0  30000-3 0  9995",
+	"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.49815361443452",
+}
+
+func newTestSatellite(t *testing.T, idx int) *Satellite {
+	tle, err := ParseTLE(sgp4TLEs[idx][0], sgp4TLEs[idx][1], sgp4TLEs[idx][2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sat, err := NewSatellite(tle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sat
+}
+
+func checkRV(t *testing.T, r, v [3]float64, exp [6]float64, msg string) {
+	for idx := 0; idx < 3; idx++ {
+		th.CheckFT(t, r[idx], exp[idx], 1e-6, msg+" position Error")
+		th.CheckFT(t, v[idx], exp[idx+3], 1e-9, msg+" velocity Error")
+	}
+}
+
+func TestSGP4(t *testing.T) {
+	for _, c := range []struct {
+		idx     int
+		minutes float64
+		exp     [6]float64
+	}{
+		{0, 0.0, [6]float64{7022.46529266, -1400.08296755, 0.03995155, 1.893841015, 6.405893759, 4.534807250}},
+		{0, 360.0, [6]float64{-7154.03120202, -3783.17682504, -3536.19412294, 4.741887409, -4.151817765, -2.093935425}},
+		{1, 0.0, [6]float64{7473.37102491, 428.94748312, 5828.74846783, 5.107155391, 6.444680305, -0.186133297}},
+		{1, 720.0, [6]float64{14271.29083858, 24110.44309009, -4725.76320143, -0.320504528, 2.679841539, -2.084054355}},
+		{1, 1440.0, [6]float64{9787.87836256, 33753.32249667, -15030.79874625, -1.094251553, 0.923589906, -1.522311008}},
+		{2, 0.0, [6]float64{2349.89483350, -14785.93811562, 0.02119378, 2.721488096, -3.256811655, 4.498416672}},
+		// the 12 hour resonance integration, over one and several steps
+		{2, 720.0, [6]float64{2622.13222207, -15125.15464924, 474.51048398, 2.688287199, -3.078426664, 4.494979530}},
+		{2, 1440.0, [6]float64{2890.80638268, -15446.43952300, 948.77010176, 2.654407490, -2.909344895, 4.486437362}},
+		{2, 2880.0, [6]float64{3417.20931586, -16038.79510665, 1894.74934058, 2.585515864, -2.596818146, 4.456882556}},
+	} {
+		sat := newTestSatellite(t, c.idx)
+		r, v, err := sat.propagate(c.minutes)
+		if err != nil {
+			fmt.Println("propagate error: ", err)
+			t.Fail()
+			continue
+		}
+		checkRV(t, r, v, c.exp, fmt.Sprint(sgp4TLEs[c.idx][0], " at ", c.minutes))
+	}
+
+	// Propagate takes times
+	sat := newTestSatellite(t, 0)
+	r, v, _ := sat.Propagate(sat.TLE.Epoch.Add(6 * time.Hour))
+	checkRV(t, r, v, [6]float64{-7154.03120202, -3783.17682504, -3536.19412294, 4.741887409, -4.151817765, -2.093935425}, "Propagate")
+}
+
+func TestSDP4(t *testing.T) {
+	sat := newTestSatellite(t, 2)
+	th.CheckI(t, sat.irez, 2, "Molniya resonance Error")
+	if !sat.deep {
+		fmt.Println("Molniya orbit not propagated with SDP4")
+		t.Fail()
+	}
+	// the orbit keeps its size, by vis-viva, through the resonance
+	// integration, forwards and backwards
+	a0 := math.Pow(sgpMu*math.Pow(86400.0/(2.0*math.Pi*sat.TLE.MeanMotion), 2), 1.0/3.0)
+	for _, m := range []float64{-4320.0, -1000.0, 725.0, 1440.0, 4320.0, 10000.0} {
+		r, v, err := sat.propagate(m)
+		if err != nil {
+			fmt.Println("propagate error: ", err)
+			t.Fail()
+			continue
+		}
+		rr := math.Sqrt(r[0]*r[0] + r[1]*r[1] + r[2]*r[2])
+		vv := v[0]*v[0] + v[1]*v[1] + v[2]*v[2]
+		a := 1.0 / (2.0/rr - vv/sgpMu)
+		th.CheckFT(t, a/a0, 1.0, 0.01, fmt.Sprint("Semi-major axis at ", m, " Error"))
+	}
+
+}
+
+func TestSGP4Decay(t *testing.T) {
+	// a low orbit with heavy drag decays
+	tle, err := ParseTLE("ISS", testISS[0], testISS[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tle.BStar = 0.01
+	sat, err := NewSatellite(tle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = sat.propagate(60.0 * minPerDay)
+	th.CheckErrorNil(t, err, "Expected decay error")
+}
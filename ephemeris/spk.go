@@ -0,0 +1,228 @@
+// JPL SPK kernels
+package ephemeris
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+)
+
+const dafRecordLen = 1024 // bytes
+
+// spkSegment is a type 2 SPK segment: Chebyshev coefficients of position
+// in fixed length intervals, for a NAIF target relative to a center.
+type spkSegment struct {
+	target int
+	center int
+	start  float64 // seconds, TDB, past J2000
+	end    float64
+	init   float64 // start of the first interval
+	intlen float64 // interval length, seconds
+	rsize  int     // doubles per record
+	n      int     // number of records
+	addr   int     // first double of the data, 1 based
+}
+
+// spkFile reads the type 2 segments of an SPK kernel in the NAIF double
+// precision array file (DAF) format.
+type spkFile struct {
+	f     *os.File
+	order binary.ByteOrder
+	segs  []spkSegment
+
+	// last record read of each segment
+	mu    sync.Mutex
+	cache map[int]spkRecord
+}
+
+// spkRecord is a record of a segment: its midpoint and radius, seconds,
+// and the coefficients of x, y and z.
+type spkRecord struct {
+	idx  int
+	data []float64
+}
+
+// spkBodies are the NAIF ids of the NOVAS bodies 1 to 11. The planets
+// beyond the Earth are their system barycenters, as in the JPL binary
+// files.
+var spkBodies = []int{1, 2, 399, 4, 5, 6, 7, 8, 9, 10, 301}
+
+// openSPK sets e to read the SPK kernel format.
+func openSPK(e *JPLEphemeris) error {
+	fr := make([]byte, dafRecordLen)
+	_, err := e.f.ReadAt(fr, 0)
+	if err != nil {
+		return err
+	}
+	s := &spkFile{f: e.f, cache: make(map[int]spkRecord)}
+	switch string(fr[88:96]) {
+	case "LTL-IEEE":
+		s.order = binary.LittleEndian
+	case "BIG-IEEE":
+		s.order = binary.BigEndian
+	default:
+		emsg := fmt.Sprintf("Unsupported SPK number format: %q", fr[88:96])
+		return errors.New(emsg)
+	}
+	nd := int(s.order.Uint32(fr[8:]))
+	ni := int(s.order.Uint32(fr[12:]))
+	if nd != 2 || ni != 6 {
+		emsg := fmt.Sprintf("Not an SPK kernel: ND %d NI %d", nd, ni)
+		return errors.New(emsg)
+	}
+	e.Name = strings.TrimSpace(string(fr[16:76]))
+
+	// the summary records are a linked list
+	ss := nd + (ni+1)/2
+	for next := int(s.order.Uint32(fr[76:])); next != 0; {
+		rec := make([]byte, dafRecordLen)
+		_, err = e.f.ReadAt(rec, int64(next-1)*dafRecordLen)
+		if err != nil {
+			return err
+		}
+		f64 := func(off int) float64 {
+			return math.Float64frombits(s.order.Uint64(rec[off:]))
+		}
+		nsum := int(f64(16))
+		for idx := 0; idx < nsum; idx++ {
+			off := 24 + 8*ss*idx
+			i32 := func(k int) int {
+				return int(int32(s.order.Uint32(rec[off+8*nd+4*k:])))
+			}
+			if i32(3) != 2 {
+				// only the Chebyshev position type of the DE kernels
+				continue
+			}
+			seg := spkSegment{target: i32(0), center: i32(1), start: f64(off), end: f64(off + 8),
+				addr: i32(4)}
+			trailer, err := readFloats(e.f, s.order, int64(8*(i32(5)-4)), 4)
+			if err != nil {
+				return err
+			}
+			seg.init, seg.intlen = trailer[0], trailer[1]
+			seg.rsize, seg.n = int(trailer[2]), int(trailer[3])
+			if seg.intlen <= 0.0 || seg.rsize < 5 || (seg.rsize-2)%3 != 0 || seg.n < 1 {
+				emsg := fmt.Sprintf("Invalid segment for body %d", seg.target)
+				return errors.New(emsg)
+			}
+			s.segs = append(s.segs, seg)
+		}
+		next = int(f64(0))
+	}
+
+	// the coverage common to all the bodies
+	e.StartJD, e.EndJD = math.Inf(-1), math.Inf(1)
+	for _, id := range spkBodies {
+		start, end, err := s.coverage(id)
+		if err != nil {
+			return err
+		}
+		e.StartJD = math.Max(e.StartJD, at.J2000+start/at.SecondPerDay)
+		e.EndJD = math.Min(e.EndJD, at.J2000+end/at.SecondPerDay)
+	}
+	if e.EndJD < e.StartJD {
+		return errors.New("SPK segments have no common coverage")
+	}
+	e.reader = s
+	return nil
+}
+
+// segment returns the index of the segment of target covering et,
+// preferring the last in the file as SPICE does.
+func (s *spkFile) segment(target int, et float64) (int, error) {
+	for idx := len(s.segs) - 1; idx >= 0; idx-- {
+		seg := s.segs[idx]
+		if seg.target == target && et >= seg.start && et <= seg.end {
+			return idx, nil
+		}
+	}
+	emsg := fmt.Sprintf("No SPK segment for body %d at JD %.5f", target, at.J2000+et/at.SecondPerDay)
+	return 0, errors.New(emsg)
+}
+
+// coverage returns the span, seconds past J2000, over which target can be
+// followed to the barycenter.
+func (s *spkFile) coverage(target int) (start, end float64, err error) {
+	start, end = math.Inf(-1), math.Inf(1)
+	for depth := 0; target != 0; depth++ {
+		found := false
+		center := 0
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, seg := range s.segs {
+			if seg.target == target {
+				found = true
+				center = seg.center
+				lo, hi = math.Min(lo, seg.start), math.Max(hi, seg.end)
+			}
+		}
+		if !found || depth > 10 {
+			emsg := fmt.Sprintf("No SPK segment for body %d", target)
+			return start, end, errors.New(emsg)
+		}
+		start, end = math.Max(start, lo), math.Min(end, hi)
+		target = center
+	}
+	return start, end, nil
+}
+
+// state returns the position, km, and velocity, km/s, of target relative
+// to the barycenter at et, seconds past J2000. The caller must hold s.mu.
+func (s *spkFile) state(target int, et float64) (pos, vel [3]float64, err error) {
+	for depth := 0; target != 0; depth++ {
+		if depth > 10 {
+			emsg := fmt.Sprintf("SPK segments for body %d do not reach the barycenter", target)
+			return pos, vel, errors.New(emsg)
+		}
+		sidx, err := s.segment(target, et)
+		if err != nil {
+			return pos, vel, err
+		}
+		seg := s.segs[sidx]
+		idx := int((et - seg.init) / seg.intlen)
+		if idx >= seg.n {
+			idx = seg.n - 1
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		rec, ok := s.cache[sidx]
+		if !ok || rec.idx != idx {
+			data, err := readFloats(s.f, s.order, int64(8*(seg.addr-1+idx*seg.rsize)), seg.rsize)
+			if err != nil {
+				return pos, vel, err
+			}
+			rec = spkRecord{idx: idx, data: data}
+			s.cache[sidx] = rec
+		}
+		mid, radius := rec.data[0], rec.data[1]
+		ncf := (seg.rsize - 2) / 3
+		x := (et - mid) / radius
+		for k := 0; k < 3; k++ {
+			p, dp := chebyshev(rec.data[2+k*ncf:2+(k+1)*ncf], x)
+			pos[k] += p
+			vel[k] += dp / radius
+		}
+		target = seg.center
+	}
+	return pos, vel, nil
+}
+
+func (s *spkFile) barycentric(jd float64, body int16) (pos, vel [3]float64, err error) {
+	if body < 1 || int(body) > len(spkBodies) {
+		emsg := fmt.Sprintf("Invalid body %d for SPK", body)
+		return pos, vel, errors.New(emsg)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos, vel, err = s.state(spkBodies[body-1], (jd-at.J2000)*at.SecondPerDay)
+	for idx := range vel {
+		vel[idx] *= at.SecondPerDay
+	}
+	return pos, vel, err
+}
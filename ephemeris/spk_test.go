@@ -0,0 +1,167 @@
+package ephemeris
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+// testSegment is a type 2 SPK segment to write: records of midpoint,
+// radius and coefficients, with intervals of intlen seconds from init.
+type testSegment struct {
+	target, center int
+	init, intlen   float64
+	records        [][]float64
+}
+
+// writeSPK writes the segments as an SPK kernel, in a single summary
+// record, with byte order bo.
+func writeSPK(t *testing.T, fn string, bo binary.ByteOrder, segs []testSegment) {
+	f64 := func(b []byte, v float64) {
+		bo.PutUint64(b, math.Float64bits(v))
+	}
+	fr := make([]byte, dafRecordLen)
+	copy(fr, "DAF/SPK ")
+	bo.PutUint32(fr[8:], 2)
+	bo.PutUint32(fr[12:], 6)
+	copy(fr[16:76], fmt.Sprintf("%-60s", "DE405 TEST"))
+	bo.PutUint32(fr[76:], 2)
+	bo.PutUint32(fr[80:], 2)
+	if bo == binary.LittleEndian {
+		copy(fr[88:], "LTL-IEEE")
+	} else {
+		copy(fr[88:], "BIG-IEEE")
+	}
+	summary := make([]byte, dafRecordLen)
+	names := make([]byte, dafRecordLen)
+	f64(summary[16:], float64(len(segs)))
+	var data []byte
+	addr := 3*dafRecordLen/8 + 1
+	for idx, seg := range segs {
+		var words []float64
+		for _, r := range seg.records {
+			words = append(words, r...)
+		}
+		rsize := len(seg.records[0])
+		words = append(words, seg.init, seg.intlen, float64(rsize), float64(len(seg.records)))
+		off := 24 + 40*idx
+		f64(summary[off:], seg.init)
+		f64(summary[off+8:], seg.init+seg.intlen*float64(len(seg.records)))
+		for k, v := range []int{seg.target, seg.center, 1, 2, addr, addr + len(words) - 1} {
+			bo.PutUint32(summary[off+16+4*k:], uint32(v))
+		}
+		for _, w := range words {
+			b := make([]byte, 8)
+			f64(b, w)
+			data = append(data, b...)
+		}
+		addr += len(words)
+	}
+	bo.PutUint32(fr[84:], uint32(addr))
+	out := append(append(append(fr, summary...), names...), data...)
+	err := os.WriteFile(fn, out, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deSegments converts the DE records from jd for days to SPK segments
+// of the NOVAS bodies, the Earth and Moon relative to their barycenter.
+func deSegments(t *testing.T, e *JPLEphemeris, jd float64, days float64) []testSegment {
+	d := e.reader.(*deBinary)
+	items := []struct {
+		target, center, item int
+		scale                float64
+	}{
+		{1, 0, 0, 1.0}, {2, 0, 1, 1.0}, {3, 0, 2, 1.0}, {4, 0, 3, 1.0}, {5, 0, 4, 1.0},
+		{6, 0, 5, 1.0}, {7, 0, 6, 1.0}, {8, 0, 7, 1.0}, {9, 0, 8, 1.0}, {10, 0, 10, 1.0},
+		{399, 3, 9, -1.0 / (1.0 + d.emrat)}, {301, 3, 9, d.emrat / (1.0 + d.emrat)},
+	}
+	var segs []testSegment
+	for _, it := range items {
+		off, ncf, nsub := d.ipt[it.item][0]-1, d.ipt[it.item][1], d.ipt[it.item][2]
+		span := d.step / float64(nsub) * at.SecondPerDay
+		seg := testSegment{target: it.target, center: it.center, intlen: span,
+			init: (jd - at.J2000) * at.SecondPerDay}
+		for r := jd; r < jd+days; r += d.step {
+			d.mu.Lock()
+			rec, err := d.record(r)
+			rec = append([]float64(nil), rec...)
+			d.mu.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for sub := 0; sub < nsub; sub++ {
+				start := (rec[0]-at.J2000)*at.SecondPerDay + float64(sub)*span
+				words := []float64{start + span/2.0, span / 2.0}
+				for k := off + sub*ncf*3; k < off+(sub+1)*ncf*3; k++ {
+					words = append(words, it.scale*rec[k])
+				}
+				seg.records = append(seg.records, words)
+			}
+		}
+		segs = append(segs, seg)
+	}
+	return segs
+}
+
+func TestSPK(t *testing.T) {
+	de, err := OpenJPLEphemeris("JPLEPH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer de.Close()
+	start := 2460368.5 // the start of a DE405 record
+	segs := deSegments(t, de, start, 64.0)
+
+	dir := t.TempDir()
+	for _, bo := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		fn := filepath.Join(dir, fmt.Sprintf("de405-%v.bsp", bo))
+		writeSPK(t, fn, bo, segs)
+		e, err := OpenJPLEphemeris(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		th.CheckS(t, e.Name, "DE405 TEST", "SPK name Error")
+		th.CheckFT(t, e.StartJD, start, 1e-9, "SPK start Error")
+		th.CheckFT(t, e.EndJD, start+64.0, 1e-9, "SPK end Error")
+		for _, jd := range []float64{start, start + 3.7, start + 32.0, start + 50.123, start + 64.0} {
+			for body := int16(1); body <= 11; body++ {
+				for origin := int16(0); origin <= 1; origin++ {
+					p1, v1, err := de.State(jd, body, origin)
+					if err != nil {
+						t.Fatal(err)
+					}
+					p2, v2, err := e.State(jd, body, origin)
+					if err != nil {
+						fmt.Println("SPK State error: ", err)
+						t.Fail()
+						continue
+					}
+					msg := fmt.Sprintf("SPK JD %.3f body %d origin %d", jd, body, origin)
+					// the header AU of DE405 is 149597870.691 km, the SPK
+					// kernel is read with the IAU value
+					th.CheckFT(t, separation(p1, p2), 0.0, 1e-10*math.Max(separation(p1, [3]float64{}), 1.0), msg+" position Error")
+					th.CheckFT(t, separation(v1, v2), 0.0, 1e-10*math.Max(separation(v1, [3]float64{}), 1e-2), msg+" velocity Error")
+				}
+			}
+		}
+		_, _, err = e.State(start+65.0, 3, 0)
+		th.CheckErrorNil(t, err, "SPK State expected error after end")
+		_, _, err = e.reader.barycentric(start, 12)
+		th.CheckErrorNil(t, err, "SPK expected error for a bad body")
+		e.Close()
+	}
+
+	// every body must be covered
+	fn := filepath.Join(dir, "nosun.bsp")
+	writeSPK(t, fn, binary.LittleEndian, append(segs[:9:9], segs[10:]...))
+	_, err = OpenJPLEphemeris(fn)
+	th.CheckErrorNil(t, err, "OpenJPLEphemeris expected error without the Sun")
+}
@@ -0,0 +1,169 @@
+// Text source lists, as used by the OVRO and CARMA control systems
+package ephemeris
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+const (
+	// header written by WriteText
+	textHeader = "# Epoch  Name          RA              DEC              PMRA           PMDEC           VLSR"
+	// epoch written by WriteText
+	textEpoch = "J2000"
+)
+
+// parseTextLine parses one line of a text source list:
+//
+//	J2000 AlpBoo 14:15:39.70 +19:10:57.0 -0:0:00.0729 -0:0:01.998 -0.0 # comment
+//
+// The epoch is anything ParseCatalogEpoch takes that has no spaces, RA is
+// in hours and DEC in degrees, and the proper motions, in seconds of time
+// and arcseconds per year with PMRA on the sky (including the cos(DEC)
+// factor), and the velocity in km/s are optional. As in the OVRO lists the
+// velocity may also follow the '#', "... # -0.0", when it is the first
+// word of the comment and a number. ok is false for blank and comment
+// lines.
+func parseTextLine(line string) (BSCdata, bool, error) {
+	var d BSCdata
+	var comment []string
+	if idx := strings.Index(line, "#"); idx >= 0 {
+		comment = strings.Fields(line[idx+1:])
+		line = line[:idx]
+	}
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return d, false, nil
+	}
+	if len(f) != 4 && len(f) != 6 && len(f) != 7 {
+		emsg := fmt.Sprintf("Expected epoch, name, RA, DEC and optionally PMRA, PMDEC and velocity, got %d fields", len(f))
+		return d, false, errors.New(emsg)
+	}
+	d.Epoch = f[0]
+	d.Name = f[1]
+	var err error
+	d.RA, err = au.NewAngleHMS(f[2])
+	if err != nil {
+		return d, false, err
+	}
+	d.DEC, err = au.NewAngleDMS(f[3])
+	if err != nil {
+		return d, false, err
+	}
+	d.PMRA = au.NewAngle(au.Hour, 0.0)
+	d.PMDEC = au.NewAngle(au.Degree, 0.0)
+	if len(f) > 4 {
+		d.PMRA, err = au.NewAngleHMS(f[4])
+		if err != nil {
+			return d, false, err
+		}
+		d.PMDEC, err = au.NewAngleDMS(f[5])
+		if err != nil {
+			return d, false, err
+		}
+	}
+	if len(f) > 6 {
+		d.VLSR, err = strconv.ParseFloat(f[6], 64)
+		if err != nil {
+			emsg := fmt.Sprintf("Invalid velocity: %s", f[6])
+			return d, false, errors.New(emsg)
+		}
+	} else if len(comment) > 0 {
+		if v, err := strconv.ParseFloat(comment[0], 64); err == nil {
+			d.VLSR = v
+		}
+	}
+	return d, true, nil
+}
+
+// ReadText reads a whitespace delimited text source list, one source per
+// line as described for parseTextLine. Everything after a '#' is a
+// comment, apart from a leading velocity. The velocity is taken to be the
+// LSR velocity of a radio source.
+func (bsc *BSC) ReadText(fn string) error {
+	defer bsc.forgetNames()
+	return readFile(fn, func(r io.Reader) error {
+		sc := bufio.NewScanner(r)
+		for ln := 1; sc.Scan(); ln++ {
+			d, ok, err := parseTextLine(sc.Text())
+			if err != nil {
+				emsg := fmt.Sprintf("line %d: %v", ln, err)
+				return errors.New(emsg)
+			}
+			if !ok {
+				continue
+			}
+			d.File, d.Line = fn, ln
+			err = d.toICRS()
+			if err != nil {
+				emsg := fmt.Sprintf("line %d: Source %s: %v", ln, d.Name, err)
+				return errors.New(emsg)
+			}
+			(*bsc)[strings.ToLower(d.Name)] = d
+		}
+		return sc.Err()
+	})
+}
+
+// signedDMS returns the sexagesimal DMS string of a with an explicit sign.
+func signedDMS(a au.Angle) string {
+	s := a.SexagesimalDMS()
+	if !strings.HasPrefix(s, "-") {
+		s = "+" + s
+	}
+	return s
+}
+
+// WriteText writes the catalog as a text source list sorted by name, with
+// J2000 (FK5) positions and proper motions at epoch J2000.0 so that it can
+// be read by older control systems. Names containing white space are
+// written with it removed.
+func (bsc *BSC) WriteText(w io.Writer) error {
+	keys := make([]string, 0, len(*bsc))
+	for k := range *bsc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, textHeader)
+	for _, k := range keys {
+		d := (*bsc)[k]
+		name := d.Name
+		if name == "" {
+			name = k
+		}
+		name = strings.Join(strings.Fields(name), "")
+		fk5, err := d.starPosition(au.ICRS, 0.0, 2000.0).FK5()
+		if err != nil {
+			return err
+		}
+		ra, dec := fk5.Coord.Ra().Hour(), fk5.Coord.Dec().Degree()
+		pmra := fk5.PMRA.Hour()
+		pmdec := fk5.PMDec.Degree()
+		fmt.Fprintf(bw, "%-8s %-13s %-15s %-16s %-14s %-15s %.1f\n", textEpoch, name,
+			ra.SexagesimalHMS(), signedDMS(dec), pmra.SexagesimalHMS(), signedDMS(pmdec), d.VLSR)
+	}
+	return bw.Flush()
+}
+
+// WriteTextFile writes the catalog as a text source list to fn.
+func (bsc *BSC) WriteTextFile(fn string) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	err = bsc.WriteText(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,117 @@
+package ephemeris
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	au "github.com/rh-codebase/astrogo/astrounit"
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+const textCat = `# OVRO source list
+J2000 AlpBoo    14:15:39.70 +19:10:57.0 -0:0:00.0729 -0:0:01.998 # -0.0
+
+B1950 3C273     12:26:33.246 +02:19:43.53   # quasar
+J2000 W3OH      02:27:03.82 +61:52:25.2 0:0:0 0:0:0 -46.0
+J2000 OriKL     05:35:14.50 -05:22:30.0 0:0:0 0:0:0 # 9.0 Orion hot core
+`
+
+func TestReadText(t *testing.T) {
+	fn := writeFile(t, t.TempDir(), "radio.cat", textCat)
+	bsc := make(BSC)
+	err := bsc.ReadText(fn)
+	if err != nil {
+		fmt.Println("ReadText error: ", err)
+		t.Fail()
+		return
+	}
+	th.CheckI(t, len(bsc), 4, "Source count Error")
+	a, _ := bsc.GetSource("alpboo")
+	th.CheckS(t, a.Epoch, "J2000", "Catalog epoch Error")
+	th.CheckFT(t, a.PMRA.Hour().Value*3600.0, -0.0729, 1e-6, "PMRA Error")
+	th.CheckFT(t, a.PMDEC.ArcSecond().Value, -1.998, 1e-6, "PMDEC Error")
+	q, _ := bsc.GetSource("3c273")
+	th.CheckFT(t, q.RA.Hour().Value, 12.0+29.0/60.0+6.6997/3600.0, 0.05/3600.0, "B1950 RA Error")
+	w, _ := bsc.GetSource("W3OH")
+	th.CheckFT(t, w.VLSR, -46.0, 1e-9, "Velocity Error")
+	o, _ := bsc.GetSource("orikl")
+	th.CheckFT(t, o.VLSR, 9.0, 1e-9, "Comment velocity Error")
+	th.CheckFT(t, q.VLSR, 0.0, 0.0, "Comment text velocity Error")
+
+	for _, bad := range []string{
+		"J2000 AlpBoo 14:15:39.70\n",
+		"J2000 AlpBoo 14:15:39.70 +19:10:57.0 -0:0:00.0729\n",
+		"J2000 AlpBoo 14:15:39.70 +19:10:57.0 0:0:0 0:0:0 fast\n",
+		"J2000 AlpBoo 14h15m39.70 +19:10:57.0\n",
+		"B1900 AlpBoo 14:15:39.70 +19:10:57.0\n",
+	} {
+		fn = writeFile(t, t.TempDir(), "bad.cat", bad)
+		th.CheckErrorNil(t, bsc.ReadText(fn), "Expected error for "+bad)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	bsc := DefaultBSC()
+	bsc.ReadText(writeFile(t, t.TempDir(), "radio.cat", textCat))
+	var buf bytes.Buffer
+	err := bsc.WriteText(&buf)
+	if err != nil {
+		fmt.Println("WriteText error: ", err)
+		t.Fail()
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	th.CheckI(t, len(lines), len(bsc)+1, "Line count Error")
+	if !strings.HasPrefix(lines[0], "#") {
+		fmt.Println("Missing header: ", lines[0])
+		t.Fail()
+	}
+
+	// the proper motion in RA is written on the sky, as read
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) > 4 && f[1] == "AlpBoo" {
+			pmra, err := au.NewAngleHMS(f[4])
+			if err != nil {
+				fmt.Println("Invalid PMRA written: ", l)
+				t.Fail()
+				continue
+			}
+			th.CheckFT(t, pmra.Hour().Value*3600.0, -0.0729, 1e-4, "Written PMRA Error")
+		}
+	}
+
+	// the list reads back to the same sources
+	fn := filepath.Join(t.TempDir(), "out.src")
+	err = bsc.WriteTextFile(fn)
+	if err != nil {
+		fmt.Println("WriteTextFile error: ", err)
+		t.Fail()
+	}
+	ClearCatalogs()
+	defer ClearCatalogs()
+	AddCatalog(CatalogSource{"OVRO", fn})
+	back := make(BSC)
+	err = back.LoadCatalogs()
+	if err != nil {
+		fmt.Println("LoadCatalogs error: ", err)
+		t.Fail()
+	}
+	th.CheckI(t, len(back), len(bsc), "Round trip count Error")
+	for k, d := range bsc {
+		b, err := back.GetSource(k)
+		if err != nil {
+			fmt.Println("Round trip lost ", k)
+			t.Fail()
+			continue
+		}
+		sep := au.NewRaDecCoordA(b.RA, b.DEC).Separation(au.NewRaDecCoordA(d.RA, d.DEC))
+		th.CheckFT(t, sep.MilliArcSecond().Value, 0.0, 2.0, k+" round trip position Error [mas]")
+		// proper motions are written to 0.0001 s and 0.0001"
+		th.CheckFT(t, b.PMRA.Hour().Value*3600.0, d.PMRA.Hour().Value*3600.0, 5e-5, k+" round trip PMRA Error")
+		th.CheckFT(t, b.PMDEC.ArcSecond().Value, d.PMDEC.ArcSecond().Value, 1e-4, k+" round trip PMDEC Error")
+		th.CheckFT(t, b.VLSR, d.VLSR, 0.05, k+" round trip velocity Error")
+	}
+}
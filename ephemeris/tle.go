@@ -0,0 +1,282 @@
+// Two and three line element sets
+package ephemeris
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	at "github.com/rh-codebase/astrogo/astrotime"
+	au "github.com/rh-codebase/astrogo/astrounit"
+)
+
+// TLE is a NORAD two line element set, with the mean elements used by
+// SGP4.
+type TLE struct {
+	Name           string
+	CatalogNumber  int
+	Classification string
+	Designator     string // international designator
+	Epoch          time.Time
+	MeanMotionDot  float64 // first derivative of mean motion / 2, rev/day^2
+	MeanMotionDDot float64 // second derivative of mean motion / 6, rev/day^3
+	BStar          float64 // drag term, 1/Earth radii
+	ElementSet     int
+	Inclination    au.Angle
+	RAAN           au.Angle // right ascension of the ascending node
+	Eccentricity   float64
+	ArgPerigee     au.Angle
+	MeanAnomaly    au.Angle
+	MeanMotion     float64 // rev/day
+	RevNumber      int
+	Line1, Line2   string
+	Provenance
+
+	jd float64 // epoch as a Julian date, UTC
+}
+
+// tleChecksum returns the checksum of the first 68 columns of line: the
+// sum of its digits, with 1 for each minus sign, modulo 10.
+func tleChecksum(line string) int {
+	sum := 0
+	for _, c := range line[:68] {
+		if c >= '0' && c <= '9' {
+			sum += int(c - '0')
+		} else if c == '-' {
+			sum++
+		}
+	}
+	return sum % 10
+}
+
+// checkTLELine checks the line number and, when present, the checksum of
+// line n of an element set.
+func checkTLELine(line string, n, minLen int) error {
+	if len(line) < minLen || line[0] != byte('0'+n) || line[1] != ' ' {
+		emsg := fmt.Sprintf("Not TLE line %d: %s", n, line)
+		return errors.New(emsg)
+	}
+	if len(line) >= 69 && line[68] >= '0' && line[68] <= '9' {
+		if tleChecksum(line) != int(line[68]-'0') {
+			emsg := fmt.Sprintf("TLE line %d checksum is not %c: %s", n, line[68], line)
+			return errors.New(emsg)
+		}
+	}
+	return nil
+}
+
+// parseCatalogNumber parses a catalog number, including the alpha-5 form
+// in which a leading letter, skipping I and O, counts ten thousands from
+// 100000.
+func parseCatalogNumber(s string) (int, error) {
+	if s != "" && s[0] >= 'A' && s[0] <= 'Z' {
+		c := s[0]
+		if c == 'I' || c == 'O' {
+			emsg := fmt.Sprintf("Invalid alpha-5 catalog number: %s", s)
+			return 0, errors.New(emsg)
+		}
+		n := int(c-'A') + 10
+		if c > 'I' {
+			n--
+		}
+		if c > 'O' {
+			n--
+		}
+		rest, err := strconv.Atoi(s[1:])
+		return n*10000 + rest, err
+	}
+	return strconv.Atoi(s)
+}
+
+// parseImpliedDecimal parses a TLE field with an implied leading decimal
+// point and a power of ten exponent, as " 12345-4" for 0.12345e-4.
+func parseImpliedDecimal(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0.0, nil
+	}
+	sign := ""
+	if s[0] == '-' || s[0] == '+' {
+		sign, s = s[:1], s[1:]
+	}
+	if len(s) < 3 {
+		emsg := fmt.Sprintf("Invalid TLE field: %s", s)
+		return 0.0, errors.New(emsg)
+	}
+	return strconv.ParseFloat(sign+"0."+s[:len(s)-2]+"e"+s[len(s)-2:], 64)
+}
+
+// tleEpoch returns the time and Julian date of a TLE epoch given as a two
+// digit year, 1957 to 2056, and a day of the year.
+func tleEpoch(yy int, doy float64) (time.Time, float64) {
+	year := 2000 + yy
+	if yy >= 57 {
+		year = 1900 + yy
+	}
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days := jan1.Sub(time.Date(1949, time.December, 31, 0, 0, 0, 0, time.UTC)).Hours() / 24.0
+	jd := jd1950 + days + doy - 1.0
+	ns := math.Round((doy - 1.0) * at.SecondPerDay * 1e9)
+	return jan1.Add(time.Duration(ns)), jd
+}
+
+// ParseTLE parses an element set from its two lines. If name is empty the
+// catalog number is used.
+func ParseTLE(name, line1, line2 string) (TLE, error) {
+	var tle TLE
+	line1 = strings.TrimRight(line1, " \r")
+	line2 = strings.TrimRight(line2, " \r")
+	err := checkTLELine(line1, 1, 61)
+	if err != nil {
+		return tle, err
+	}
+	err = checkTLELine(line2, 2, 63)
+	if err != nil {
+		return tle, err
+	}
+	tle.Line1, tle.Line2 = line1, line2
+
+	tle.CatalogNumber, err = parseCatalogNumber(field(line1, 3, 7))
+	if err != nil {
+		return tle, err
+	}
+	n2, err := parseCatalogNumber(field(line2, 3, 7))
+	if err != nil {
+		return tle, err
+	}
+	if n2 != tle.CatalogNumber {
+		emsg := fmt.Sprintf("TLE lines are for satellites %d and %d", tle.CatalogNumber, n2)
+		return tle, errors.New(emsg)
+	}
+	tle.Name = strings.TrimSpace(name)
+	if tle.Name == "" {
+		tle.Name = field(line1, 3, 7)
+	}
+	tle.Classification = field(line1, 8, 8)
+	tle.Designator = field(line1, 10, 17)
+
+	yy, err := strconv.Atoi(field(line1, 19, 20))
+	if err != nil {
+		return tle, err
+	}
+	doy, err := parseFloat(field(line1, 21, 32))
+	if err != nil {
+		return tle, err
+	}
+	tle.Epoch, tle.jd = tleEpoch(yy, doy)
+	tle.MeanMotionDot, err = parseFloat(field(line1, 34, 43))
+	if err != nil {
+		return tle, err
+	}
+	tle.MeanMotionDDot, err = parseImpliedDecimal(field(line1, 45, 52))
+	if err != nil {
+		return tle, err
+	}
+	tle.BStar, err = parseImpliedDecimal(field(line1, 54, 61))
+	if err != nil {
+		return tle, err
+	}
+	if es := field(line1, 65, 68); es != "" {
+		tle.ElementSet, _ = strconv.Atoi(es)
+	}
+
+	var deg [4]float64
+	for idx, cols := range [4][2]int{{9, 16}, {18, 25}, {35, 42}, {44, 51}} {
+		deg[idx], err = parseFloat(field(line2, cols[0], cols[1]))
+		if err != nil {
+			return tle, err
+		}
+	}
+	tle.Inclination = au.NewAngle(au.Degree, deg[0])
+	tle.RAAN = au.NewAngle(au.Degree, deg[1])
+	tle.ArgPerigee = au.NewAngle(au.Degree, deg[2])
+	tle.MeanAnomaly = au.NewAngle(au.Degree, deg[3])
+	tle.Eccentricity, err = parseFloat("0." + field(line2, 27, 33))
+	if err != nil {
+		return tle, err
+	}
+	tle.MeanMotion, err = parseFloat(field(line2, 53, 63))
+	if err != nil {
+		return tle, err
+	}
+	if rn := field(line2, 64, 68); rn != "" {
+		tle.RevNumber, _ = strconv.Atoi(rn)
+	}
+	return tle, nil
+}
+
+// parseTLEs parses the element sets read from r, with or without name
+// lines. A name line may start with "0 ", as in 3LE files.
+func parseTLEs(r io.Reader, fn string) ([]TLE, error) {
+	var tles []TLE
+	var name, line1 string
+	var ln1 int
+	sc := bufio.NewScanner(r)
+	for ln := 1; sc.Scan(); ln++ {
+		line := strings.TrimRight(sc.Text(), " \r")
+		switch {
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "1 ") && line1 == "":
+			line1, ln1 = line, ln
+		case strings.HasPrefix(line, "2 ") && line1 != "":
+			tle, err := ParseTLE(name, line1, line)
+			if err != nil {
+				emsg := fmt.Sprintf("line %d: %v", ln, err)
+				return tles, errors.New(emsg)
+			}
+			tle.File, tle.Line = fn, ln1
+			tles = append(tles, tle)
+			name, line1 = "", ""
+		case line1 == "":
+			name = strings.TrimPrefix(line, "0 ")
+		default:
+			emsg := fmt.Sprintf("line %d: expected TLE line 2: %s", ln, line)
+			return tles, errors.New(emsg)
+		}
+	}
+	if line1 != "" {
+		emsg := fmt.Sprintf("line %d: TLE line 2 missing", ln1)
+		return tles, errors.New(emsg)
+	}
+	return tles, sc.Err()
+}
+
+// ReadTLE reads the element sets of a two line (TLE) or three line (3LE)
+// element file, which may be gzipped.
+func ReadTLE(fn string) ([]TLE, error) {
+	var tles []TLE
+	err := readFile(fn, func(r io.Reader) error {
+		var err error
+		tles, err = parseTLEs(r, fn)
+		return err
+	})
+	return tles, err
+}
+
+// FindTLE returns the element set of tles for the satellite name, ignoring
+// case, or catalog number. Of several, the one with the latest epoch is
+// returned.
+func FindTLE(tles []TLE, name string) (TLE, error) {
+	var found TLE
+	ok := false
+	num, numErr := parseCatalogNumber(strings.TrimSpace(name))
+	for _, tle := range tles {
+		if strings.EqualFold(tle.Name, strings.TrimSpace(name)) || (numErr == nil && tle.CatalogNumber == num) {
+			if !ok || tle.Epoch.After(found.Epoch) {
+				found = tle
+				ok = true
+			}
+		}
+	}
+	if !ok {
+		emsg := fmt.Sprintf("Satellite not found: %s", name)
+		return found, errors.New(emsg)
+	}
+	return found, nil
+}
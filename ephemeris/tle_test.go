@@ -0,0 +1,106 @@
+package ephemeris
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	th "github.com/rh-codebase/genutilsgo"
+)
+
+func TestParseTLE(t *testing.T) {
+	tle, err := ParseTLE("", sgp4TLEs[0][1], sgp4TLEs[0][2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckS(t, tle.Name, "00005", "Name Error")
+	th.CheckI(t, tle.CatalogNumber, 5, "Catalog number Error")
+	th.CheckS(t, tle.Designator, "58002B", "Designator Error")
+	exp := time.Date(2000, 6, 27, 18, 50, 19, 733568000, time.UTC)
+	th.CheckFT(t, tle.Epoch.Sub(exp).Seconds(), 0.0, 1e-6, "Epoch Error")
+	th.CheckFT(t, tle.jd, 2451723.28495062, 1e-8, "Epoch JD Error")
+	th.CheckF(t, tle.MeanMotionDot, 0.00000023, "Mean motion dot Error")
+	th.CheckFT(t, tle.BStar, 0.28098e-4, 1e-15, "BStar Error")
+	th.CheckFT(t, tle.Inclination.Degree().Value, 34.2682, 1e-12, "Inclination Error")
+	th.CheckFT(t, tle.RAAN.Degree().Value, 348.7242, 1e-12, "RAAN Error")
+	th.CheckFT(t, tle.Eccentricity, 0.1859667, 1e-12, "Eccentricity Error")
+	th.CheckFT(t, tle.MeanMotion, 10.82419157, 1e-12, "Mean motion Error")
+	th.CheckI(t, tle.RevNumber, 41366, "Rev number Error")
+
+	// the century of the epoch
+	tle, _ = ParseTLE("", sgp4TLEs[1][1], sgp4TLEs[1][2])
+	th.CheckI(t, tle.Epoch.Year(), 1980, "Epoch year Error")
+
+	th.CheckFT(t, mustImplied(t, "-11606-4"), -0.11606e-4, 1e-15, "Implied decimal Error")
+	th.CheckFT(t, mustImplied(t, " 00000+0"), 0.0, 0.0, "Implied decimal zero Error")
+	n, _ := parseCatalogNumber("A0001")
+	th.CheckI(t, n, 100001, "Alpha-5 Error")
+	n, _ = parseCatalogNumber("Z9999")
+	th.CheckI(t, n, 339999, "Alpha-5 Z Error")
+
+	bad := sgp4TLEs[0][1][:68] + "4"
+	_, err = ParseTLE("", bad, sgp4TLEs[0][2])
+	th.CheckErrorNil(t, err, "Expected checksum error")
+	_, err = ParseTLE("", sgp4TLEs[0][1], sgp4TLEs[1][2])
+	th.CheckErrorNil(t, err, "Expected catalog number mismatch error")
+	_, err = ParseTLE("", sgp4TLEs[0][2], sgp4TLEs[0][1])
+	th.CheckErrorNil(t, err, "Expected line number error")
+}
+
+func mustImplied(t *testing.T, s string) float64 {
+	v, err := parseImpliedDecimal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestReadTLE(t *testing.T) {
+	dir := t.TempDir()
+	// three line elements, with Space-Track's 0 name prefix
+	fn3 := filepath.Join(dir, "sats.3le")
+	content := "0 VANGUARD 1\n" + sgp4TLEs[0][1] + "\n" + sgp4TLEs[0][2] + "\n\n" +
+		"ISS (ZARYA)\n" + testISS[0] + "\n" + testISS[1] + "\n"
+	err := os.WriteFile(fn3, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tles, err := ReadTLE(fn3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, len(tles), 2, "3LE count Error")
+	th.CheckS(t, tles[0].Name, "VANGUARD 1", "3LE name Error")
+	th.CheckI(t, tles[1].Line, 6, "3LE line Error")
+	tle, err := FindTLE(tles, "iss (zarya)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, tle.CatalogNumber, 25544, "FindTLE by name Error")
+	tle, _ = FindTLE(tles, "5")
+	th.CheckS(t, tle.Name, "VANGUARD 1", "FindTLE by number Error")
+	_, err = FindTLE(tles, "HST")
+	th.CheckErrorNil(t, err, "Expected unknown satellite error")
+
+	// two line elements
+	fn2 := filepath.Join(dir, "sats.tle")
+	content = sgp4TLEs[1][1] + "\n" + sgp4TLEs[1][2] + "\n" + sgp4TLEs[2][1] + "\n" + sgp4TLEs[2][2] + "\n"
+	os.WriteFile(fn2, []byte(content), 0644)
+	tles, err = ReadTLE(fn2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, len(tles), 2, "TLE count Error")
+	th.CheckS(t, tles[1].Name, "08195", "TLE name Error")
+
+	sat, err := LoadSatellite(fn2, "8195")
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.CheckI(t, sat.TLE.CatalogNumber, 8195, "LoadSatellite Error")
+
+	os.WriteFile(fn2, []byte(sgp4TLEs[1][1]+"\n"), 0644)
+	_, err = ReadTLE(fn2)
+	th.CheckErrorNil(t, err, "Expected missing line 2 error")
+}